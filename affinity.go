@@ -0,0 +1,244 @@
+package gohl
+
+import (
+	"fmt"
+)
+
+// Set by SetAffinityCheck
+var affinityDispatcher *Dispatcher
+
+// Debugging aid.  While enabled, every Element method panics if it is called
+// from a thread other than the one that owns the dispatcher, which catches
+// goroutines that touch the dom directly instead of going through Invoke or
+// BeginInvoke.  Pass nil to disable the check.
+func SetAffinityCheck(d *Dispatcher) {
+	affinityDispatcher = d
+	installBackend()
+}
+
+// Wraps a Backend, checking the calling thread before forwarding each call
+type affinityBackend struct {
+	Backend
+	dispatcher *Dispatcher
+}
+
+func (b affinityBackend) check(method string) {
+	if thread := b.dispatcher.threadId(); thread != b.dispatcher.owner {
+		panic(fmt.Sprintf("%s called on thread %d, but the dom belongs to thread %d", method, thread, b.dispatcher.owner))
+	}
+}
+
+func (b affinityBackend) UnuseElement(he HELEMENT) HLDOM_RESULT {
+	b.check("UnuseElement")
+	return b.Backend.UnuseElement(he)
+}
+
+func (b affinityBackend) DetachEventHandler(he HELEMENT, handler *EventHandler) HLDOM_RESULT {
+	b.check("DetachEventHandler")
+	return b.Backend.DetachEventHandler(he, handler)
+}
+
+func (b affinityBackend) UseElement(he HELEMENT) HLDOM_RESULT {
+	b.check("UseElement")
+	return b.Backend.UseElement(he)
+}
+
+func (b affinityBackend) RootElement(hwnd HWND) (HELEMENT, HLDOM_RESULT) {
+	b.check("RootElement")
+	return b.Backend.RootElement(hwnd)
+}
+
+func (b affinityBackend) FocusElement(hwnd HWND) (HELEMENT, HLDOM_RESULT) {
+	b.check("FocusElement")
+	return b.Backend.FocusElement(hwnd)
+}
+
+func (b affinityBackend) CreateElement(tagName string) (HELEMENT, HLDOM_RESULT) {
+	b.check("CreateElement")
+	return b.Backend.CreateElement(tagName)
+}
+
+func (b affinityBackend) CloneElement(he HELEMENT) (HELEMENT, HLDOM_RESULT) {
+	b.check("CloneElement")
+	return b.Backend.CloneElement(he)
+}
+
+func (b affinityBackend) ChildrenCount(he HELEMENT) (uint, HLDOM_RESULT) {
+	b.check("ChildrenCount")
+	return b.Backend.ChildrenCount(he)
+}
+
+func (b affinityBackend) NthChild(he HELEMENT, index uint) (HELEMENT, HLDOM_RESULT) {
+	b.check("NthChild")
+	return b.Backend.NthChild(he, index)
+}
+
+func (b affinityBackend) ElementIndex(he HELEMENT) (uint, HLDOM_RESULT) {
+	b.check("ElementIndex")
+	return b.Backend.ElementIndex(he)
+}
+
+func (b affinityBackend) ParentElement(he HELEMENT) (HELEMENT, HLDOM_RESULT) {
+	b.check("ParentElement")
+	return b.Backend.ParentElement(he)
+}
+
+func (b affinityBackend) InsertElement(he, parent HELEMENT, index uint) HLDOM_RESULT {
+	b.check("InsertElement")
+	return b.Backend.InsertElement(he, parent, index)
+}
+
+func (b affinityBackend) DetachElement(he HELEMENT) HLDOM_RESULT {
+	b.check("DetachElement")
+	return b.Backend.DetachElement(he)
+}
+
+func (b affinityBackend) DeleteElement(he HELEMENT) HLDOM_RESULT {
+	b.check("DeleteElement")
+	return b.Backend.DeleteElement(he)
+}
+
+func (b affinityBackend) SwapElements(he1, he2 HELEMENT) HLDOM_RESULT {
+	b.check("SwapElements")
+	return b.Backend.SwapElements(he1, he2)
+}
+
+func (b affinityBackend) SortElements(he HELEMENT, start, end uint, comparator func(HELEMENT, HELEMENT) int) HLDOM_RESULT {
+	b.check("SortElements")
+	return b.Backend.SortElements(he, start, end, comparator)
+}
+
+func (b affinityBackend) SelectElements(he HELEMENT, selector string, callback func(HELEMENT) bool) HLDOM_RESULT {
+	b.check("SelectElements")
+	return b.Backend.SelectElements(he, selector, callback)
+}
+
+func (b affinityBackend) SelectParent(he HELEMENT, selector string, depth uint) (HELEMENT, HLDOM_RESULT) {
+	b.check("SelectParent")
+	return b.Backend.SelectParent(he, selector, depth)
+}
+
+func (b affinityBackend) ElementType(he HELEMENT) (string, HLDOM_RESULT) {
+	b.check("ElementType")
+	return b.Backend.ElementType(he)
+}
+
+func (b affinityBackend) ElementHtml(he HELEMENT, outer bool) (string, HLDOM_RESULT) {
+	b.check("ElementHtml")
+	return b.Backend.ElementHtml(he, outer)
+}
+
+func (b affinityBackend) SetElementHtml(he HELEMENT, html string, where uint) HLDOM_RESULT {
+	b.check("SetElementHtml")
+	return b.Backend.SetElementHtml(he, html, where)
+}
+
+func (b affinityBackend) ElementInnerText(he HELEMENT) (string, HLDOM_RESULT) {
+	b.check("ElementInnerText")
+	return b.Backend.ElementInnerText(he)
+}
+
+func (b affinityBackend) SetElementInnerText(he HELEMENT, text string) HLDOM_RESULT {
+	b.check("SetElementInnerText")
+	return b.Backend.SetElementInnerText(he, text)
+}
+
+func (b affinityBackend) AttributeCount(he HELEMENT) (uint, HLDOM_RESULT) {
+	b.check("AttributeCount")
+	return b.Backend.AttributeCount(he)
+}
+
+func (b affinityBackend) NthAttribute(he HELEMENT, index uint) (name, value string, ret HLDOM_RESULT) {
+	b.check("NthAttribute")
+	return b.Backend.NthAttribute(he, index)
+}
+
+func (b affinityBackend) AttributeByName(he HELEMENT, name string) (value string, exists bool, ret HLDOM_RESULT) {
+	b.check("AttributeByName")
+	return b.Backend.AttributeByName(he, name)
+}
+
+func (b affinityBackend) SetAttributeByName(he HELEMENT, name string, value *string) HLDOM_RESULT {
+	b.check("SetAttributeByName")
+	return b.Backend.SetAttributeByName(he, name, value)
+}
+
+func (b affinityBackend) StyleAttribute(he HELEMENT, name string) (value string, exists bool, ret HLDOM_RESULT) {
+	b.check("StyleAttribute")
+	return b.Backend.StyleAttribute(he, name)
+}
+
+func (b affinityBackend) SetStyleAttribute(he HELEMENT, name string, value *string) HLDOM_RESULT {
+	b.check("SetStyleAttribute")
+	return b.Backend.SetStyleAttribute(he, name, value)
+}
+
+func (b affinityBackend) ElementState(he HELEMENT) (uint32, HLDOM_RESULT) {
+	b.check("ElementState")
+	return b.Backend.ElementState(he)
+}
+
+func (b affinityBackend) SetElementState(he HELEMENT, bitsToSet, bitsToClear uint32, update bool) HLDOM_RESULT {
+	b.check("SetElementState")
+	return b.Backend.SetElementState(he, bitsToSet, bitsToClear, update)
+}
+
+func (b affinityBackend) ElementLocation(he HELEMENT, areas uint32) (Rect, HLDOM_RESULT) {
+	b.check("ElementLocation")
+	return b.Backend.ElementLocation(he, areas)
+}
+
+func (b affinityBackend) MoveElement(he HELEMENT, x, y int) HLDOM_RESULT {
+	b.check("MoveElement")
+	return b.Backend.MoveElement(he, x, y)
+}
+
+func (b affinityBackend) MoveElementEx(he HELEMENT, x, y, w, h int) HLDOM_RESULT {
+	b.check("MoveElementEx")
+	return b.Backend.MoveElementEx(he, x, y, w, h)
+}
+
+func (b affinityBackend) UpdateElement(he HELEMENT, flags uint32) HLDOM_RESULT {
+	b.check("UpdateElement")
+	return b.Backend.UpdateElement(he, flags)
+}
+
+func (b affinityBackend) ElementHwnd(he HELEMENT, root bool) (HWND, HLDOM_RESULT) {
+	b.check("ElementHwnd")
+	return b.Backend.ElementHwnd(he, root)
+}
+
+func (b affinityBackend) SetCapture(he HELEMENT) HLDOM_RESULT {
+	b.check("SetCapture")
+	return b.Backend.SetCapture(he)
+}
+
+func (b affinityBackend) ReleaseCapture() bool {
+	b.check("ReleaseCapture")
+	return b.Backend.ReleaseCapture()
+}
+
+func (b affinityBackend) SetTimer(he HELEMENT, ms uint) HLDOM_RESULT {
+	b.check("SetTimer")
+	return b.Backend.SetTimer(he, ms)
+}
+
+func (b affinityBackend) SendEvent(he HELEMENT, eventCode uint, source HELEMENT, reason uintptr) (handled bool, ret HLDOM_RESULT) {
+	b.check("SendEvent")
+	return b.Backend.SendEvent(he, eventCode, source, reason)
+}
+
+func (b affinityBackend) PostEvent(he HELEMENT, eventCode uint, source HELEMENT, reason uint32) HLDOM_RESULT {
+	b.check("PostEvent")
+	return b.Backend.PostEvent(he, eventCode, source, reason)
+}
+
+func (b affinityBackend) CallBehaviorMethod(he HELEMENT, params *MethodParams) HLDOM_RESULT {
+	b.check("CallBehaviorMethod")
+	return b.Backend.CallBehaviorMethod(he, params)
+}
+
+func (b affinityBackend) AttachEventHandler(he HELEMENT, handler *EventHandler, subscription uint32) HLDOM_RESULT {
+	b.check("AttachEventHandler")
+	return b.Backend.AttachEventHandler(he, handler, subscription)
+}
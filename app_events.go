@@ -0,0 +1,256 @@
+package gohl
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Application event codes handed out by RegisterAppEvent
+var (
+	appEventMutex    sync.Mutex
+	appEventCodes    = make(map[string]uint, 16)
+	appEventNames    = make(map[uint]string, 16)
+	nextAppEventCode = uint(FIRST_APPLICATION_EVENT_CODE + 1)
+)
+
+// Returns the event code for the named application event, allocating one above
+// FIRST_APPLICATION_EVENT_CODE the first time a name is seen.  Registering the
+// same name again returns the same code, so packages can register the events
+// they use at init time without coordinating.
+func RegisterAppEvent(name string) uint {
+	appEventMutex.Lock()
+	defer appEventMutex.Unlock()
+	if code, exists := appEventCodes[name]; exists {
+		return code
+	}
+	code := nextAppEventCode
+	nextAppEventCode++
+	appEventCodes[name] = code
+	appEventNames[code] = name
+	return code
+}
+
+// Returns the code of a registered application event
+func AppEventCode(name string) (uint, bool) {
+	appEventMutex.Lock()
+	defer appEventMutex.Unlock()
+	code, exists := appEventCodes[name]
+	return code, exists
+}
+
+// Returns the name an application event code was registered with.  The phase
+// flags are ignored.
+func AppEventName(code uint32) (string, bool) {
+	appEventMutex.Lock()
+	defer appEventMutex.Unlock()
+	name, exists := appEventNames[uint(code&^phaseFlags)]
+	return name, exists
+}
+
+// A Go value travelling with an application event.  The token identifying it
+// is passed in the event's Reason.
+type appPayload struct {
+	code  uint
+	value interface{}
+	hwnd  HWND
+
+	// Drain generation in which a posted payload was first delivered, or 0
+	deliveredIn uint64
+}
+
+var (
+	payloadMutex      sync.Mutex
+	payloads          = make(map[uint32]*appPayload, 16)
+	nextPayloadToken  uint32
+	payloadGeneration uint64 = 1
+	deliveredPayloads int
+)
+
+func storePayload(code uint, value interface{}, hwnd HWND) uint32 {
+	payloadMutex.Lock()
+	defer payloadMutex.Unlock()
+	// Token 0 means no payload
+	for nextPayloadToken++; nextPayloadToken == 0 || payloads[nextPayloadToken] != nil; nextPayloadToken++ {
+	}
+	payloads[nextPayloadToken] = &appPayload{code: code, value: value, hwnd: hwnd}
+	return nextPayloadToken
+}
+
+func dropPayload(token uint32) {
+	payloadMutex.Lock()
+	if p, exists := payloads[token]; exists {
+		if p.deliveredIn != 0 {
+			deliveredPayloads--
+		}
+		delete(payloads, token)
+	}
+	payloadMutex.Unlock()
+}
+
+// Returns the payload the params carry, if the event is an application event
+// sent with one
+func lookupPayload(params *BehaviorEventParams) *appPayload {
+	if params.Cmd&^phaseFlags <= FIRST_APPLICATION_EVENT_CODE || params.Reason == 0 {
+		return nil
+	}
+	payloadMutex.Lock()
+	defer payloadMutex.Unlock()
+	if p := payloads[params.Reason]; p != nil && p.code == uint(params.Cmd&^phaseFlags) {
+		return p
+	}
+	return nil
+}
+
+// Called for every behavior event handed to a handler.  A posted payload is
+// dropped once it has been delivered and the message loop has moved on; see
+// releaseDeliveredPayloads.
+func notePayloadDelivered(params *BehaviorEventParams) {
+	if p := lookupPayload(params); p != nil {
+		payloadMutex.Lock()
+		if p.deliveredIn == 0 {
+			p.deliveredIn = payloadGeneration
+			deliveredPayloads++
+		}
+		payloadMutex.Unlock()
+	}
+}
+
+// Drops the posted payloads delivered before the previous call.  Waiting a
+// generation means a drain that happens in the middle of dispatching an event
+// doesn't pull the payload from under the handlers still to see it.
+func releaseDeliveredPayloads() {
+	payloadMutex.Lock()
+	defer payloadMutex.Unlock()
+	if deliveredPayloads == 0 {
+		return
+	}
+	for token, p := range payloads {
+		if p.deliveredIn != 0 && p.deliveredIn < payloadGeneration {
+			delete(payloads, token)
+			deliveredPayloads--
+		}
+	}
+	payloadGeneration++
+}
+
+func hasDeliveredPayloads() bool {
+	payloadMutex.Lock()
+	defer payloadMutex.Unlock()
+	return deliveredPayloads > 0
+}
+
+// Drops the payloads of events posted to a window that is going away, since
+// they will never be delivered
+func dropWindowPayloads(hwnd HWND) {
+	payloadMutex.Lock()
+	defer payloadMutex.Unlock()
+	for token, p := range payloads {
+		if p.hwnd == hwnd {
+			if p.deliveredIn != 0 {
+				deliveredPayloads--
+			}
+			delete(payloads, token)
+		}
+	}
+}
+
+// Returns the number of payloads being kept for events that have been sent or
+// posted but not released yet
+func PendingPayloads() int {
+	payloadMutex.Lock()
+	defer payloadMutex.Unlock()
+	return len(payloads)
+}
+
+// Returns the Go value sent along with an application event, or nil if there
+// is none.  The value is only valid while the event is being handled; hold on
+// to the value itself, not to the params.
+func (p *BehaviorEventParams) Payload() interface{} {
+	if payload := lookupPayload(p); payload != nil {
+		return payload.value
+	}
+	return nil
+}
+
+// Stores the payload in the value out points to, if there is a payload and
+// it is assignable to that type.  Returns true if it was stored.
+//
+//	var msg ChatMessage
+//	if params.LoadPayload(&msg) { ... }
+func (p *BehaviorEventParams) LoadPayload(out interface{}) bool {
+	value := p.Payload()
+	if value == nil {
+		return false
+	}
+	target := reflect.ValueOf(out)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		panic("LoadPayload requires a non-nil pointer")
+	}
+	v := reflect.ValueOf(value)
+	if !v.Type().AssignableTo(target.Elem().Type()) {
+		return false
+	}
+	target.Elem().Set(v)
+	return true
+}
+
+// Payloads are only looked up for application event codes, so one attached to
+// any other event would never reach a handler
+func checkAppEventCode(eventCode uint) error {
+	if eventCode&^uint(phaseFlags) <= FIRST_APPLICATION_EVENT_CODE {
+		return domError(HLDOM_INVALID_PARAMETER, "Event code ", eventCode, " is not an application event code")
+	}
+	return nil
+}
+
+// Sends an application event with a Go value attached, synchronously.  The
+// payload is dropped again when SendAppEvent returns.  Returns true if the
+// event was handled.  The code must be above FIRST_APPLICATION_EVENT_CODE.
+func (e *Element) TrySendAppEvent(eventCode uint, source *Element, payload interface{}) (bool, error) {
+	if err := e.checkReleased(); err != nil {
+		return false, err
+	}
+	if err := checkAppEventCode(eventCode); err != nil {
+		return false, err
+	}
+	token := storePayload(eventCode, payload, 0)
+	defer dropPayload(token)
+	return e.TrySendEvent(eventCode, source, token)
+}
+
+func (e *Element) SendAppEvent(eventCode uint, source *Element, payload interface{}) bool {
+	handled, err := e.TrySendAppEvent(eventCode, source, payload)
+	mustSucceed(err)
+	return handled
+}
+
+// Posts an application event with a Go value attached.  The payload is kept
+// until the event has been delivered and the message loop drains again (see
+// DrainReleaseQueue), or until the element's window is destroyed.  Only
+// delivery to a Go handler counts, so an event that no handler sees keeps its
+// payload until then.  The code must be above FIRST_APPLICATION_EVENT_CODE, and
+// the element must be in a window, since nothing else would collect the payload.
+func (e *Element) TryPostAppEvent(eventCode uint, source *Element, payload interface{}) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	if err := checkAppEventCode(eventCode); err != nil {
+		return err
+	}
+	hwnd, ret := dom.ElementHwnd(e.handle, true)
+	if ret != HLDOM_OK {
+		return domError(ret, "Failed to get the window of the element to post to")
+	} else if hwnd == 0 {
+		return domError(HLDOM_INVALID_HWND, "Cannot post an application event to an element that is not in a window")
+	}
+	token := storePayload(eventCode, payload, hwnd)
+	if err := e.TryPostEvent(eventCode, source, token); err != nil {
+		dropPayload(token)
+		return err
+	}
+	return nil
+}
+
+func (e *Element) PostAppEvent(eventCode uint, source *Element, payload interface{}) {
+	mustSucceed(e.TryPostAppEvent(eventCode, source, payload))
+}
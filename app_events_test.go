@@ -0,0 +1,169 @@
+package gohl
+
+import (
+	"errors"
+	"testing"
+)
+
+type chatMessage struct {
+	From, Text string
+}
+
+// Gives the test an empty application event registry, and puts the previous
+// one back when it finishes, so registrations don't leak between tests
+func isolateAppEvents(t *testing.T) {
+	appEventMutex.Lock()
+	codes, names, next := appEventCodes, appEventNames, nextAppEventCode
+	appEventCodes = make(map[string]uint, 16)
+	appEventNames = make(map[uint]string, 16)
+	nextAppEventCode = uint(FIRST_APPLICATION_EVENT_CODE + 1)
+	appEventMutex.Unlock()
+	t.Cleanup(func() {
+		appEventMutex.Lock()
+		appEventCodes, appEventNames, nextAppEventCode = codes, names, next
+		appEventMutex.Unlock()
+	})
+}
+
+func TestRegisterAppEvent(t *testing.T) {
+	isolateAppEvents(t)
+	a := RegisterAppEvent("test.registered-a")
+	b := RegisterAppEvent("test.registered-b")
+	if a <= FIRST_APPLICATION_EVENT_CODE || b <= FIRST_APPLICATION_EVENT_CODE || a == b {
+		t.Fatal("Expected distinct codes above FIRST_APPLICATION_EVENT_CODE, got ", a, b)
+	}
+	if RegisterAppEvent("test.registered-a") != a {
+		t.Fatal("Registering a name again should return the same code")
+	}
+	if code, ok := AppEventCode("test.registered-b"); !ok || code != b {
+		t.Fatal("Failed to look up the code by name")
+	}
+	if name, ok := AppEventName(uint32(a) | SINKING); !ok || name != "test.registered-a" {
+		t.Fatal("Failed to look up the name by code")
+	}
+	if s := BehaviorEvent(uint32(b) | SINKING).String(); s != "test.registered-b|SINKING" {
+		t.Fatal("Registered events should print with their name, got ", s)
+	}
+	if a != FIRST_APPLICATION_EVENT_CODE+1 {
+		t.Fatal("Expected codes to be allocated from an empty registry, got ", a)
+	}
+}
+
+func TestSendAppEvent(t *testing.T) {
+	isolateAppEvents(t)
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		chat := RegisterAppEvent("test.chat")
+		root := RootElement(hwnd)
+		inner := root.Select("#b")[0]
+		var received []chatMessage
+		root.AttachHandler(&EventHandler{
+			OnBehaviorEvent: func(he HELEMENT, params *BehaviorEventParams) bool {
+				if params.Event() != BehaviorEvent(chat) || params.Phase() != Phase(BUBBLING) {
+					return false
+				}
+				var msg chatMessage
+				if !params.LoadPayload(&msg) {
+					t.Fatal("Failed to load the payload")
+				}
+				var wrongType int
+				if params.LoadPayload(&wrongType) {
+					t.Fatal("Payload should not load into a different type")
+				}
+				received = append(received, msg)
+				return true
+			},
+		})
+
+		if !inner.SendAppEvent(chat, inner, chatMessage{"a", "hello"}) {
+			t.Fatal("Event should have been handled")
+		}
+		if len(received) != 1 || received[0].Text != "hello" {
+			t.Fatal("Expected the payload to reach the handler, got ", received)
+		}
+		if PendingPayloads() != 0 {
+			t.Fatal("Payload of a sent event should be dropped when SendAppEvent returns")
+		}
+
+		// Plain events don't carry a payload, even with a reason that was once a token
+		if (&BehaviorEventParams{Cmd: BUTTON_CLICK, Reason: 1}).Payload() != nil {
+			t.Fatal("Only application events carry payloads")
+		}
+	})
+}
+
+func TestPostAppEvent(t *testing.T) {
+	isolateAppEvents(t)
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		update := RegisterAppEvent("test.update")
+		d := RootElement(hwnd).Child(0)
+		var received interface{}
+		d.AttachHandler(&EventHandler{
+			OnBehaviorEvent: func(he HELEMENT, params *BehaviorEventParams) bool {
+				if params.Event() == BehaviorEvent(update) {
+					received = params.Payload()
+				}
+				return false
+			},
+		})
+
+		payload := &chatMessage{"b", "posted"}
+		d.PostAppEvent(update, d, payload)
+		if PendingPayloads() != 1 || GlobalStats().PendingPayloads != 1 {
+			t.Fatal("Payload should be kept until the event is delivered")
+		}
+		mem.ProcessPostedEvents()
+		if received != payload {
+			t.Fatal("Expected the posted payload to be delivered, got ", received)
+		}
+
+		// Released once the message loop has drained again after delivery
+		DrainReleaseQueue()
+		DrainReleaseQueue()
+		if PendingPayloads() != 0 {
+			t.Fatal("Payload should be released after delivery")
+		}
+	})
+}
+
+func TestPostAppEventDestroyedWindow(t *testing.T) {
+	isolateAppEvents(t)
+	mem := NewMemoryBackend()
+	const hwnd = 4
+	if err := mem.LoadHtml(hwnd, pages["one-div"]); err != nil {
+		t.Fatal(err)
+	}
+	SetBackend(mem)
+	defer SetBackend(nil)
+
+	d := RootElement(hwnd).Child(0)
+	d.PostAppEvent(RegisterAppEvent("test.never-delivered"), d, "lost")
+	if PendingPayloads() != 1 {
+		t.Fatal("Payload should be kept until the event is delivered")
+	}
+	mem.DestroyWindow(hwnd)
+	if PendingPayloads() != 0 {
+		t.Fatal("Payloads for a destroyed window should be dropped")
+	}
+}
+
+func TestAppEventRejectsUncollectedPayloads(t *testing.T) {
+	isolateAppEvents(t)
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		if err := d.TryPostAppEvent(uint(BUTTON_CLICK), d, "click"); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatal("Expected an invalid parameter error for a standard event code, got: ", err)
+		}
+		if _, err := d.TrySendAppEvent(FIRST_APPLICATION_EVENT_CODE, d, "first"); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatal("Expected an invalid parameter error for FIRST_APPLICATION_EVENT_CODE, got: ", err)
+		}
+
+		detached := NewElement("div")
+		defer detached.Release()
+		if err := detached.TryPostAppEvent(RegisterAppEvent("test.detached"), detached, "lost"); !errors.Is(err, ErrInvalidHwnd) {
+			t.Fatal("Expected an invalid hwnd error for an element outside a window, got: ", err)
+		}
+		if PendingPayloads() != 0 {
+			t.Fatal("Rejected payloads should not be kept, pending: ", PendingPayloads())
+		}
+	})
+}
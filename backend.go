@@ -0,0 +1,116 @@
+package gohl
+
+/*
+Backend
+
+The set of DOM primitives that Element is built on.  Each method mirrors one
+of the HTMLayout DOM functions and reports failure the same way they do, with
+an HLDOM_RESULT.  The default backend calls straight into the HTMLayout dll,
+or, where there is no dll, is an empty MemoryBackend.  An alternative backend
+can be installed with SetBackend so that the Element logic can be exercised
+without a window.
+*/
+type Backend interface {
+	// Handle lifetime
+	UseElement(he HELEMENT) HLDOM_RESULT
+	UnuseElement(he HELEMENT) HLDOM_RESULT
+
+	// Element lookup and creation
+	RootElement(hwnd HWND) (HELEMENT, HLDOM_RESULT)
+	FocusElement(hwnd HWND) (HELEMENT, HLDOM_RESULT)
+	CreateElement(tagName string) (HELEMENT, HLDOM_RESULT)
+	CloneElement(he HELEMENT) (HELEMENT, HLDOM_RESULT)
+
+	// DOM structure
+	ChildrenCount(he HELEMENT) (uint, HLDOM_RESULT)
+	NthChild(he HELEMENT, index uint) (HELEMENT, HLDOM_RESULT)
+	ElementIndex(he HELEMENT) (uint, HLDOM_RESULT)
+	ParentElement(he HELEMENT) (HELEMENT, HLDOM_RESULT)
+	InsertElement(he, parent HELEMENT, index uint) HLDOM_RESULT
+	DetachElement(he HELEMENT) HLDOM_RESULT
+	DeleteElement(he HELEMENT) HLDOM_RESULT
+	SwapElements(he1, he2 HELEMENT) HLDOM_RESULT
+
+	// Sorts the children in the range [start, end).  The comparator returns
+	// -1, 0 or 1 to indicate less, equal or greater.
+	SortElements(he HELEMENT, start, end uint, comparator func(HELEMENT, HELEMENT) int) HLDOM_RESULT
+
+	// Calls the callback for each element under he that matches the selector.
+	// Returning true from the callback stops the enumeration.
+	SelectElements(he HELEMENT, selector string, callback func(HELEMENT) bool) HLDOM_RESULT
+
+	// Depth has the same meaning as in Element.SelectParentLimit.  A nil
+	// handle is returned when nothing matches.
+	SelectParent(he HELEMENT, selector string, depth uint) (HELEMENT, HLDOM_RESULT)
+
+	// Content
+	ElementType(he HELEMENT) (string, HLDOM_RESULT)
+	ElementHtml(he HELEMENT, outer bool) (string, HLDOM_RESULT)
+	SetElementHtml(he HELEMENT, html string, where uint) HLDOM_RESULT
+	ElementInnerText(he HELEMENT) (string, HLDOM_RESULT)
+	SetElementInnerText(he HELEMENT, text string) HLDOM_RESULT
+
+	// Attributes.  A nil value removes the attribute.
+	AttributeCount(he HELEMENT) (uint, HLDOM_RESULT)
+	NthAttribute(he HELEMENT, index uint) (name, value string, ret HLDOM_RESULT)
+	AttributeByName(he HELEMENT, name string) (value string, exists bool, ret HLDOM_RESULT)
+	SetAttributeByName(he HELEMENT, name string, value *string) HLDOM_RESULT
+
+	// Inline styles.  A nil value removes the style, an empty name together
+	// with a nil value clears all of the inline styles.
+	StyleAttribute(he HELEMENT, name string) (value string, exists bool, ret HLDOM_RESULT)
+	SetStyleAttribute(he HELEMENT, name string, value *string) HLDOM_RESULT
+
+	// State flags
+	ElementState(he HELEMENT) (uint32, HLDOM_RESULT)
+	SetElementState(he HELEMENT, bitsToSet, bitsToClear uint32, update bool) HLDOM_RESULT
+
+	// Geometry and rendering
+	ElementLocation(he HELEMENT, areas uint32) (Rect, HLDOM_RESULT)
+	MoveElement(he HELEMENT, x, y int) HLDOM_RESULT
+	MoveElementEx(he HELEMENT, x, y, w, h int) HLDOM_RESULT
+	UpdateElement(he HELEMENT, flags uint32) HLDOM_RESULT
+	ElementHwnd(he HELEMENT, root bool) (HWND, HLDOM_RESULT)
+	SetCapture(he HELEMENT) HLDOM_RESULT
+	ReleaseCapture() bool
+	SetTimer(he HELEMENT, ms uint) HLDOM_RESULT
+
+	// Events and behaviors
+	SendEvent(he HELEMENT, eventCode uint, source HELEMENT, reason uintptr) (handled bool, ret HLDOM_RESULT)
+	PostEvent(he HELEMENT, eventCode uint, source HELEMENT, reason uint32) HLDOM_RESULT
+	CallBehaviorMethod(he HELEMENT, params *MethodParams) HLDOM_RESULT
+	AttachEventHandler(he HELEMENT, handler *EventHandler, subscription uint32) HLDOM_RESULT
+	DetachEventHandler(he HELEMENT, handler *EventHandler) HLDOM_RESULT
+}
+
+var (
+	// The backend chosen with SetBackend
+	backend Backend = defaultBackend()
+
+	// The backend used by all Element methods.  This is the chosen backend,
+	// wrapped with a thread check when one is enabled.
+	dom Backend = backend
+)
+
+// Replaces the backend used by all Element methods, or restores the default
+// if b is nil.  Elements created with the previous backend must not be used
+// after switching.
+func SetBackend(b Backend) {
+	if b == nil {
+		b = defaultBackend()
+	}
+	backend = b
+	installBackend()
+}
+
+func CurrentBackend() Backend {
+	return backend
+}
+
+func installBackend() {
+	if affinityDispatcher != nil {
+		dom = affinityBackend{backend, affinityDispatcher}
+	} else {
+		dom = backend
+	}
+}
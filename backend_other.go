@@ -0,0 +1,7 @@
+//go:build !windows
+
+package gohl
+
+func defaultBackend() Backend {
+	return NewMemoryBackend()
+}
@@ -1 +1,178 @@
 package gohl
+
+import (
+	"log"
+	"sync"
+)
+
+/*
+Behavior
+
+A behavior with its own state for each element it is attached to, created by
+a BehaviorFactory whenever an element with a matching CSS "behavior" property
+is attached.  OnAttached and OnDetached bracket the element's use of the
+instance; after OnDetached the instance and its element are released.
+EventHandler returns the callbacks for the element's events.  The handler is
+attached as it is, so it must belong to the instance alone; its OnAttached and
+OnDetached fields are overwritten.
+*/
+type Behavior interface {
+	OnAttached(e *Element)
+	OnDetached(e *Element)
+	EventHandler() *EventHandler
+}
+
+// Creates the behavior instance for an element
+type BehaviorFactory func(e *Element) Behavior
+
+// Creates the behavior instance for an element, given the arguments that
+// followed the behavior's name in css.  See ParseBehavior.
+type BehaviorArgsFactory func(e *Element, args BehaviorArgs) Behavior
+
+func (factory BehaviorFactory) withArgs() BehaviorArgsFactory {
+	if factory == nil {
+		return nil
+	}
+	return func(e *Element, args BehaviorArgs) Behavior {
+		return factory(e)
+	}
+}
+
+var (
+	behaviorFactoriesMutex sync.Mutex
+	behaviorFactories      = make(map[string]BehaviorArgsFactory, 8)
+)
+
+// Registers a behavior factory for every window.  A behavior registered for
+// a window with RegisterWindowBehavior, or a shared handler in the window's
+// NotifyHandler.Behaviors, takes precedence over one registered here.  Like
+// other behaviors, they are only attached in windows that have a
+// NotifyHandler.  Passing a nil factory removes the registration.
+func RegisterBehavior(name string, factory BehaviorFactory) {
+	RegisterBehaviorWithArgs(name, factory.withArgs())
+}
+
+// Like RegisterBehavior, for a factory that takes the behavior's arguments
+func RegisterBehaviorWithArgs(name string, factory BehaviorArgsFactory) {
+	behaviorFactoriesMutex.Lock()
+	defer behaviorFactoriesMutex.Unlock()
+	if factory == nil {
+		delete(behaviorFactories, name)
+	} else {
+		behaviorFactories[name] = factory
+	}
+}
+
+// Registers a behavior factory for a single window.  The registration lasts
+// until the window is destroyed; passing a nil factory removes it sooner.
+func RegisterWindowBehavior(hwnd HWND, name string, factory BehaviorFactory) {
+	RegisterWindowBehaviorWithArgs(hwnd, name, factory.withArgs())
+}
+
+// Like RegisterWindowBehavior, for a factory that takes the behavior's arguments
+func RegisterWindowBehaviorWithArgs(hwnd HWND, name string, factory BehaviorArgsFactory) {
+	r := registryFor(hwnd, factory != nil)
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if factory == nil {
+		delete(r.behaviorFactories, name)
+	} else {
+		r.behaviorFactories[name] = factory
+	}
+}
+
+func (r *windowRegistry) getBehaviorFactory(name string) BehaviorArgsFactory {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.behaviorFactories[name]
+}
+
+func globalBehaviorFactory(name string) BehaviorArgsFactory {
+	behaviorFactoriesMutex.Lock()
+	defer behaviorFactoriesMutex.Unlock()
+	return behaviorFactories[name]
+}
+
+// Reported when an element asks for a behavior that can't be attached
+type UnknownBehaviorError struct {
+	Element HELEMENT
+	Spec    string // The behavior as written in css
+	Err     error  // Why Spec couldn't be parsed, or nil if no behavior has its name
+}
+
+func (e *UnknownBehaviorError) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return "No such behavior: " + e.Spec
+}
+
+// The default handling of unknown behaviors
+func LogUnknownBehavior(err *UnknownBehaviorError) {
+	log.Print(err)
+}
+
+func IgnoreUnknownBehavior(err *UnknownBehaviorError) {}
+
+var unknownBehaviorHandler = LogUnknownBehavior
+
+// Sets the function that is called when an element asks for a behavior that
+// isn't registered, or whose arguments don't parse.  It is called on the
+// thread that runs the window's message loop.  Passing nil restores the
+// default, LogUnknownBehavior.
+func SetUnknownBehaviorHandler(handler func(err *UnknownBehaviorError)) {
+	if handler == nil {
+		handler = LogUnknownBehavior
+	}
+	unknownBehaviorHandler = handler
+}
+
+// Creates a behavior instance for the element and attaches it.  The instance's
+// handler is attached as it is, so changes the instance makes to it later are
+// seen, and is counted in the registry's behaviors like a shared one, so it is
+// kept alive until BEHAVIOR_DETACH.  Detaching only drops the references the
+// behavior itself holds; other handlers on the element stay attached.
+func (e *Element) attachBehaviorInstance(r *windowRegistry, factory BehaviorArgsFactory, args BehaviorArgs) {
+	instance := factory(e, args)
+	handler := instance.EventHandler()
+	handler.OnAttached = func(he HELEMENT) {
+		instance.OnAttached(e)
+	}
+	handler.OnDetached = func(he HELEMENT) {
+		instance.OnDetached(e)
+		e.releaseReference()
+	}
+	r.addBehavior(handler)
+	e.attachBehavior(handler)
+}
+
+// Attaches the behavior the engine asked for in HLN_ATTACH_BEHAVIOR, looking
+// its name up in the window's factories, then the notify handler's shared
+// behaviors, then the global factories.  Returns false if the behavior can't
+// be attached, after reporting it to the unknown behavior handler.
+func attachNamedBehavior(r *windowRegistry, notifyHandler *NotifyHandler, he HELEMENT, spec string) bool {
+	name, args, err := ParseBehavior(spec)
+	if err != nil {
+		unknownBehaviorHandler(&UnknownBehaviorError{he, spec, err})
+		return false
+	}
+	if factory := r.getBehaviorFactory(name); factory != nil {
+		NewElementFromHandle(he).attachBehaviorInstance(r, factory, args)
+		return true
+	}
+	if behavior, exists := notifyHandler.Behaviors[name]; exists {
+		// Increment the reference count for this behavior
+		r.addBehavior(behavior)
+		NewElementFromHandle(he).attachBehavior(behavior)
+		return true
+	}
+	if factory := globalBehaviorFactory(name); factory != nil {
+		NewElementFromHandle(he).attachBehaviorInstance(r, factory, args)
+		return true
+	}
+	unknownBehaviorHandler(&UnknownBehaviorError{he, spec, nil})
+	return false
+}
@@ -0,0 +1,209 @@
+package gohl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+/*
+BehaviorArgs
+
+The arguments written after a behavior name in css, as in
+"behavior: sortable(column=2, numeric)".  Each argument is either name=value
+or a bare name, which is stored as true.  Values are stored as an int, a
+float64, a bool for true and false, or a string; strings may be quoted with
+single or double quotes to include commas, parentheses or spaces.
+*/
+type BehaviorArgs map[string]interface{}
+
+func (a BehaviorArgs) Has(name string) bool {
+	_, exists := a[name]
+	return exists
+}
+
+// Returns the argument if it is a string
+func (a BehaviorArgs) String(name string) (string, bool) {
+	s, ok := a[name].(string)
+	return s, ok
+}
+
+// Returns the argument if it is an integer
+func (a BehaviorArgs) Int(name string) (int, bool) {
+	i, ok := a[name].(int)
+	return i, ok
+}
+
+// Returns the argument if it is a number, converting integers
+func (a BehaviorArgs) Float(name string) (float64, bool) {
+	switch v := a[name].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// Returns the argument if it is a bool.  A bare name is true.
+func (a BehaviorArgs) Bool(name string) (bool, bool) {
+	b, ok := a[name].(bool)
+	return b, ok
+}
+
+func isBehaviorArgName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, c := range s {
+		switch {
+		case c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+		case i > 0 && (c == '-' || c >= '0' && c <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Converts an unquoted value to the narrowest type that holds it
+func parseBehaviorArgValue(s string) interface{} {
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	if i, err := strconv.Atoi(s); err == nil {
+		return i
+	}
+	// ParseFloat also accepts words like "inf", which are meant as strings here
+	isWord := strings.IndexFunc(s, func(c rune) bool {
+		return c != 'e' && c != 'E' && unicode.IsLetter(c)
+	}) >= 0
+	if f, err := strconv.ParseFloat(s, 64); err == nil && !isWord {
+		return f
+	}
+	return s
+}
+
+// Splits the argument list on commas outside of quotes
+func splitBehaviorArgs(s string) ([]string, error) {
+	parts := make([]string, 0, 4)
+	var quote rune
+	escaped := false
+	start := 0
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && c == '\\':
+			escaped = true
+		case quote != 0 && c == quote:
+			quote = 0
+		case quote != 0:
+		case c == '\'' || c == '"':
+			quote = c
+		case c == ',':
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated quote in %q", s)
+	}
+	return append(parts, strings.TrimSpace(s[start:])), nil
+}
+
+// Removes the quotes around a value and the backslashes that escape
+// characters within it
+func unquoteBehaviorArg(s string) (string, error) {
+	quote := s[0]
+	if len(s) < 2 || s[len(s)-1] != quote {
+		return "", fmt.Errorf("unexpected text after the quoted value %s", s)
+	}
+	var unquoted strings.Builder
+	escaped := false
+	for _, c := range s[1 : len(s)-1] {
+		switch {
+		case escaped:
+			unquoted.WriteRune(c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == rune(quote):
+			return "", fmt.Errorf("unexpected text after the quoted value %s", s)
+		default:
+			unquoted.WriteRune(c)
+		}
+	}
+	return unquoted.String(), nil
+}
+
+/*
+ParseBehavior
+
+Splits a behavior as named in css into its name and arguments:
+
+	sortable(column=2, numeric, label='a, b')
+
+gives "sortable" and {"column": 2, "numeric": true, "label": "a, b"}.  A name
+without parentheses has no arguments.
+*/
+func ParseBehavior(spec string) (name string, args BehaviorArgs, err error) {
+	spec = strings.TrimSpace(spec)
+	args = BehaviorArgs{}
+	open := strings.IndexByte(spec, '(')
+	if open < 0 {
+		name = spec
+	} else {
+		if !strings.HasSuffix(spec, ")") {
+			return "", nil, fmt.Errorf("invalid behavior %q: missing closing parenthesis", spec)
+		}
+		name = strings.TrimSpace(spec[:open])
+	}
+	if name == "" || strings.ContainsAny(name, " \t(),='\"") {
+		return "", nil, fmt.Errorf("invalid behavior %q: bad name %q", spec, name)
+	}
+	if open < 0 {
+		return name, args, nil
+	}
+
+	inner := spec[open+1 : len(spec)-1]
+	if strings.TrimSpace(inner) == "" {
+		return name, args, nil
+	}
+	parts, err := splitBehaviorArgs(inner)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid behavior %q: %s", spec, err)
+	}
+	for _, part := range parts {
+		argName, value := part, ""
+		eq := strings.IndexByte(part, '=')
+		if eq >= 0 {
+			argName, value = strings.TrimSpace(part[:eq]), strings.TrimSpace(part[eq+1:])
+		}
+		if !isBehaviorArgName(argName) {
+			return "", nil, fmt.Errorf("invalid behavior %q: bad argument %q", spec, part)
+		}
+		if args.Has(argName) {
+			return "", nil, fmt.Errorf("invalid behavior %q: %s appears twice", spec, argName)
+		}
+		switch {
+		case eq < 0:
+			args[argName] = true
+		case value == "":
+			return "", nil, fmt.Errorf("invalid behavior %q: %s has no value", spec, argName)
+		case value[0] == '\'' || value[0] == '"':
+			unquoted, err := unquoteBehaviorArg(value)
+			if err != nil {
+				return "", nil, fmt.Errorf("invalid behavior %q: %s", spec, err)
+			}
+			args[argName] = unquoted
+		default:
+			args[argName] = parseBehaviorArgValue(value)
+		}
+	}
+	return name, args, nil
+}
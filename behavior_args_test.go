@@ -0,0 +1,65 @@
+package gohl
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseBehavior(t *testing.T) {
+	tests := []struct {
+		spec   string
+		name   string
+		expect BehaviorArgs
+	}{
+		{"sortable", "sortable", BehaviorArgs{}},
+		{" sortable() ", "sortable", BehaviorArgs{}},
+		{"sortable(column=2, numeric)", "sortable", BehaviorArgs{"column": 2, "numeric": true}},
+		{"x-grid(ratio=0.5,exp=1e3, on=true, off=false, mode=fast, unit=inf)", "x-grid", BehaviorArgs{"ratio": 0.5, "exp": 1000.0, "on": true, "off": false, "mode": "fast", "unit": "inf"}},
+		{`label(text='a, b (c)', other="say \"hi\"", n=-4)`, "label", BehaviorArgs{"text": "a, b (c)", "other": `say "hi"`, "n": -4}},
+		{`quoted(n='2')`, "quoted", BehaviorArgs{"n": "2"}},
+	}
+	for _, test := range tests {
+		name, args, err := ParseBehavior(test.spec)
+		if err != nil {
+			t.Errorf("Failed to parse %q: %s", test.spec, err)
+			continue
+		}
+		if name != test.name || !reflect.DeepEqual(args, test.expect) {
+			t.Errorf("Parsed %q as %q %v", test.spec, name, args)
+		}
+	}
+
+	for _, bad := range []string{"", "()", "a b", "sortable(", "sortable(x", "sortable(x))", "s(x)y", "s(=1)", "s(1)", "s(a=)", "s(a=1, a=2)", "s(a='x)", "s(a='x'y)", "s('a')", "s(a,,b)"} {
+		if _, _, err := ParseBehavior(bad); err == nil {
+			t.Errorf("Expected %q to fail to parse", bad)
+		}
+	}
+}
+
+func TestBehaviorArgsAccessors(t *testing.T) {
+	_, args, err := ParseBehavior("s(i=3, f=1.5, b, s=text)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i, ok := args.Int("i"); !ok || i != 3 {
+		t.Error("Unexpected int: ", i)
+	}
+	if _, ok := args.Int("f"); ok {
+		t.Error("A float should not read as an int")
+	}
+	if f, ok := args.Float("i"); !ok || f != 3 {
+		t.Error("Expected an int to read as a float: ", f)
+	}
+	if f, ok := args.Float("f"); !ok || f != 1.5 {
+		t.Error("Unexpected float: ", f)
+	}
+	if b, ok := args.Bool("b"); !ok || !b {
+		t.Error("Expected a bare name to be true")
+	}
+	if s, ok := args.String("s"); !ok || s != "text" {
+		t.Error("Unexpected string: ", s)
+	}
+	if _, ok := args.String("missing"); ok || args.Has("missing") || !args.Has("b") {
+		t.Error("Unexpected results for a missing argument")
+	}
+}
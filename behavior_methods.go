@@ -0,0 +1,316 @@
+package gohl
+
+import (
+	"unicode/utf16"
+	"unsafe"
+)
+
+func (p *MethodParams) Method() BehaviorMethod {
+	return BehaviorMethod(p.MethodId)
+}
+
+/*
+Decode
+
+Returns the params as the structure their MethodId calls for, for use in an
+OnMethodCall handler:
+
+	switch args := params.Decode().(type) {
+	case *TextEditSelectionParams:
+		args.SelectionStart, args.SelectionEnd = 0, 5
+		return true
+	}
+
+DO_CLICK, application defined methods and unknown ids return the params as
+they are.
+*/
+func (p *MethodParams) Decode() interface{} {
+	ptr := unsafe.Pointer(p)
+	switch p.MethodId {
+	case GET_TEXT_VALUE, SET_TEXT_VALUE:
+		return (*TextValueParams)(ptr)
+	case TEXT_EDIT_GET_SELECTION, TEXT_EDIT_SET_SELECTION:
+		return (*TextEditSelectionParams)(ptr)
+	case TEXT_EDIT_REPLACE_SELECTION:
+		return (*TextEditReplaceSelectionParams)(ptr)
+	case TEXT_EDIT_GET_CARET_POSITION:
+		return (*TextCaretPositionParams)(ptr)
+	case TEXT_EDIT_GET_SELECTION_TEXT, TEXT_EDIT_GET_SELECTION_HTML:
+		return (*TextSelectionParams)(ptr)
+	case TEXT_EDIT_CHAR_POS_AT_XY:
+		return (*TextEditCharPosAtXYParams)(ptr)
+	case SCROLL_BAR_GET_VALUE, SCROLL_BAR_SET_VALUE:
+		return (*ScrollBarValueParams)(ptr)
+	case IS_EMPTY:
+		return (*IsEmptyParams)(ptr)
+	case GET_VALUE, SET_VALUE:
+		return (*ValueParams)(ptr)
+	case XCALL:
+		return (*XcallParams)(ptr)
+	}
+	return p
+}
+
+// Passes one unit of the selection to the caller: a WCHAR for
+// TEXT_EDIT_GET_SELECTION_TEXT or a byte of utf8 for
+// TEXT_EDIT_GET_SELECTION_HTML.  Returns false if the caller wants no more.
+func (p *TextSelectionParams) Output(data uint32) bool {
+	return writeOutputStream(p.Outs, p.Data, data)
+}
+
+// Passes the whole of a text selection to the caller
+func (p *TextSelectionParams) OutputText(text string) {
+	for _, u := range utf16.Encode([]rune(text)) {
+		if !p.Output(uint32(u)) {
+			return
+		}
+	}
+}
+
+// Passes the whole of an html selection to the caller
+func (p *TextSelectionParams) OutputHtml(html string) {
+	for i := 0; i < len(html); i++ {
+		if !p.Output(uint32(html[i])) {
+			return
+		}
+	}
+}
+
+// The scroll bar state read and written with SCROLL_BAR_GET_VALUE and
+// SCROLL_BAR_SET_VALUE
+type ScrollBarValue struct {
+	Value, Min, Max, Page, Step int
+}
+
+// Calls the behavior method, whose params must start with MethodId
+func (e *Element) callMethod(params unsafe.Pointer, what string) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	ret := dom.CallBehaviorMethod(e.handle, (*MethodParams)(params))
+	if ret == HLDOM_OK_NOT_HANDLED {
+		return domError(ret, "This type of element does not support ", what)
+	}
+	return domError(ret, "Failed to call behavior method ", what)
+}
+
+func (e *Element) TryClick() error {
+	args := &MethodParams{MethodId: DO_CLICK}
+	return e.callMethod(unsafe.Pointer(args), "DO_CLICK")
+}
+
+func (e *Element) Click() {
+	mustSucceed(e.TryClick())
+}
+
+// Returns the selected range of characters in a text editing element
+func (e *Element) TryTextSelection() (start, end int, err error) {
+	args := &TextEditSelectionParams{MethodId: TEXT_EDIT_GET_SELECTION}
+	if err := e.callMethod(unsafe.Pointer(args), "TEXT_EDIT_GET_SELECTION"); err != nil {
+		return 0, 0, err
+	}
+	return int(args.SelectionStart), int(args.SelectionEnd), nil
+}
+
+func (e *Element) TextSelection() (start, end int) {
+	start, end, err := e.TryTextSelection()
+	mustSucceed(err)
+	return start, end
+}
+
+func (e *Element) TrySetTextSelection(start, end int) error {
+	args := &TextEditSelectionParams{
+		MethodId:       TEXT_EDIT_SET_SELECTION,
+		SelectionStart: uint32(start),
+		SelectionEnd:   uint32(end),
+	}
+	return e.callMethod(unsafe.Pointer(args), "TEXT_EDIT_SET_SELECTION")
+}
+
+func (e *Element) SetTextSelection(start, end int) {
+	mustSucceed(e.TrySetTextSelection(start, end))
+}
+
+func (e *Element) TryReplaceTextSelection(text string) error {
+	utf16Text := stringToUtf16(text)
+	args := &TextEditReplaceSelectionParams{
+		MethodId:   TEXT_EDIT_REPLACE_SELECTION,
+		Text:       &utf16Text[0],
+		TextLength: uint32(len(utf16Text) - 1),
+	}
+	return e.callMethod(unsafe.Pointer(args), "TEXT_EDIT_REPLACE_SELECTION")
+}
+
+func (e *Element) ReplaceTextSelection(text string) {
+	mustSucceed(e.TryReplaceTextSelection(text))
+}
+
+// Returns the caret's rectangle, relative to the element
+func (e *Element) TryCaretPosition() (left, top, width, height int, err error) {
+	args := &TextCaretPositionParams{MethodId: TEXT_EDIT_GET_CARET_POSITION}
+	if err := e.callMethod(unsafe.Pointer(args), "TEXT_EDIT_GET_CARET_POSITION"); err != nil {
+		return 0, 0, 0, 0, err
+	}
+	return int(args.Left), int(args.Top), int(args.Width), int(args.Height), nil
+}
+
+func (e *Element) CaretPosition() (left, top, width, height int) {
+	left, top, width, height, err := e.TryCaretPosition()
+	mustSucceed(err)
+	return left, top, width, height
+}
+
+// Collects the units passed to the TextSelectionParams output stream
+func (e *Element) selectionOutput(methodId uint32, what string) ([]uint32, error) {
+	units := make([]uint32, 0, 64)
+	output := func(data uint32) bool {
+		units = append(units, data)
+		return true
+	}
+	id := registerOutputStream(output)
+	defer unregisterOutputStream(id)
+	args := &TextSelectionParams{
+		MethodId: methodId,
+		Outs:     goOutputStreamProc,
+		Data:     id,
+	}
+	if err := e.callMethod(unsafe.Pointer(args), what); err != nil {
+		return nil, err
+	}
+	return units, nil
+}
+
+func (e *Element) TrySelectionText() (string, error) {
+	units, err := e.selectionOutput(TEXT_EDIT_GET_SELECTION_TEXT, "TEXT_EDIT_GET_SELECTION_TEXT")
+	if err != nil {
+		return "", err
+	}
+	wchars := make([]uint16, len(units))
+	for i, u := range units {
+		wchars[i] = uint16(u)
+	}
+	return string(utf16.Decode(wchars)), nil
+}
+
+func (e *Element) SelectionText() string {
+	text, err := e.TrySelectionText()
+	mustSucceed(err)
+	return text
+}
+
+func (e *Element) TrySelectionHtml() (string, error) {
+	units, err := e.selectionOutput(TEXT_EDIT_GET_SELECTION_HTML, "TEXT_EDIT_GET_SELECTION_HTML")
+	if err != nil {
+		return "", err
+	}
+	utf8 := make([]byte, len(units))
+	for i, u := range units {
+		utf8[i] = byte(u)
+	}
+	return string(utf8), nil
+}
+
+func (e *Element) SelectionHtml() string {
+	html, err := e.TrySelectionHtml()
+	mustSucceed(err)
+	return html
+}
+
+// Finds the character at the point, given relative to the element.  Returns
+// its position in the text along with the element that holds the character
+// and the position within that element.
+func (e *Element) TryCharPosAtXY(x, y int) (charPos int, holder *Element, holderPos int, err error) {
+	args := &TextEditCharPosAtXYParams{MethodId: TEXT_EDIT_CHAR_POS_AT_XY, X: int32(x), Y: int32(y)}
+	if err := e.callMethod(unsafe.Pointer(args), "TEXT_EDIT_CHAR_POS_AT_XY"); err != nil {
+		return 0, nil, 0, err
+	}
+	if args.He != BAD_HELEMENT {
+		holder = NewElementFromHandle(args.He)
+	}
+	return int(args.CharPos), holder, int(args.HePos), nil
+}
+
+func (e *Element) CharPosAtXY(x, y int) (charPos int, holder *Element, holderPos int) {
+	charPos, holder, holderPos, err := e.TryCharPosAtXY(x, y)
+	mustSucceed(err)
+	return charPos, holder, holderPos
+}
+
+func (e *Element) TryScrollBarValue() (ScrollBarValue, error) {
+	args := &ScrollBarValueParams{MethodId: SCROLL_BAR_GET_VALUE}
+	if err := e.callMethod(unsafe.Pointer(args), "SCROLL_BAR_GET_VALUE"); err != nil {
+		return ScrollBarValue{}, err
+	}
+	return ScrollBarValue{int(args.Value), int(args.MinValue), int(args.MaxValue), int(args.PageValue), int(args.StepValue)}, nil
+}
+
+func (e *Element) ScrollBarValue() ScrollBarValue {
+	v, err := e.TryScrollBarValue()
+	mustSucceed(err)
+	return v
+}
+
+func (e *Element) TrySetScrollBarValue(v ScrollBarValue) error {
+	args := &ScrollBarValueParams{
+		MethodId:  SCROLL_BAR_SET_VALUE,
+		Value:     int32(v.Value),
+		MinValue:  int32(v.Min),
+		MaxValue:  int32(v.Max),
+		PageValue: int32(v.Page),
+		StepValue: int32(v.Step),
+	}
+	return e.callMethod(unsafe.Pointer(args), "SCROLL_BAR_SET_VALUE")
+}
+
+func (e *Element) SetScrollBarValue(v ScrollBarValue) {
+	mustSucceed(e.TrySetScrollBarValue(v))
+}
+
+// Reports the :empty state of an input element
+func (e *Element) TryIsEmpty() (bool, error) {
+	args := &IsEmptyParams{MethodId: IS_EMPTY}
+	if err := e.callMethod(unsafe.Pointer(args), "IS_EMPTY"); err != nil {
+		return false, err
+	}
+	return args.IsEmpty != 0, nil
+}
+
+func (e *Element) IsEmpty() bool {
+	empty, err := e.TryIsEmpty()
+	mustSucceed(err)
+	return empty
+}
+
+// Returns the value of an input element as a raw json value.  The value is a
+// copy that belongs to the caller, who must Clear it when done.  Elements in it
+// are not referenced by the copy; convert it with Value to hold on to them.
+func (e *Element) TryJsonValue() (JsonValue, error) {
+	args := &ValueParams{MethodId: GET_VALUE}
+	if err := e.callMethod(unsafe.Pointer(args), "GET_VALUE"); err != nil {
+		return JsonValue{}, err
+	}
+	// The value the element filled in is the caller's too
+	defer values.Clear(&args.Val)
+	var v JsonValue
+	v.Init()
+	if err := valueError(values.Copy(&v, &args.Val), "Failed to copy the GET_VALUE result"); err != nil {
+		return JsonValue{}, err
+	}
+	return v, nil
+}
+
+func (e *Element) JsonValue() JsonValue {
+	v, err := e.TryJsonValue()
+	mustSucceed(err)
+	return v
+}
+
+// The element copies the value, so the caller still owns v and must Clear it
+func (e *Element) TrySetJsonValue(v JsonValue) error {
+	args := &ValueParams{MethodId: SET_VALUE, Val: v}
+	return e.callMethod(unsafe.Pointer(args), "SET_VALUE")
+}
+
+func (e *Element) SetJsonValue(v JsonValue) {
+	mustSucceed(e.TrySetJsonValue(v))
+}
@@ -0,0 +1,148 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// Services the behavior methods for a fake text editor
+func fakeEditorHandler(calls *[]BehaviorMethod) *EventHandler {
+	start, end := uint32(0), uint32(0)
+	scroll := ScrollBarValueParams{Value: 5, MinValue: 0, MaxValue: 100, PageValue: 10, StepValue: 1}
+	return &EventHandler{
+		OnMethodCall: func(he HELEMENT, params *MethodParams) bool {
+			*calls = append(*calls, params.Method())
+			switch args := params.Decode().(type) {
+			case *TextEditSelectionParams:
+				if args.MethodId == TEXT_EDIT_SET_SELECTION {
+					start, end = args.SelectionStart, args.SelectionEnd
+				} else {
+					args.SelectionStart, args.SelectionEnd = start, end
+				}
+			case *TextEditReplaceSelectionParams:
+				if utf16ToStringLength(args.Text, int(args.TextLength)) != "héllo" {
+					return false
+				}
+			case *TextCaretPositionParams:
+				args.Left, args.Top, args.Width, args.Height = 1, 2, 3, 4
+			case *TextSelectionParams:
+				if args.MethodId == TEXT_EDIT_GET_SELECTION_TEXT {
+					args.OutputText("sel €")
+				} else {
+					args.OutputHtml("<b>sel €</b>")
+				}
+			case *TextEditCharPosAtXYParams:
+				args.CharPos, args.He, args.HePos = args.X+args.Y, he, 7
+			case *ScrollBarValueParams:
+				if args.MethodId == SCROLL_BAR_SET_VALUE {
+					scroll = *args
+					scroll.MethodId = 0
+				} else {
+					methodId := args.MethodId
+					*args = scroll
+					args.MethodId = methodId
+				}
+			case *IsEmptyParams:
+				args.IsEmpty = 1
+			case *ValueParams:
+				if args.MethodId == GET_VALUE {
+					args.Val = JsonValue{T: T_INT, D: 42}
+				}
+			case *MethodParams:
+				return args.MethodId == DO_CLICK
+			default:
+				return false
+			}
+			return true
+		},
+	}
+}
+
+func TestBehaviorMethods(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		var calls []BehaviorMethod
+		d.AttachHandler(fakeEditorHandler(&calls))
+
+		d.Click()
+		d.SetTextSelection(2, 5)
+		if start, end := d.TextSelection(); start != 2 || end != 5 {
+			t.Error("Unexpected selection: ", start, end)
+		}
+		d.ReplaceTextSelection("héllo")
+		if l, tp, w, h := d.CaretPosition(); l != 1 || tp != 2 || w != 3 || h != 4 {
+			t.Error("Unexpected caret position: ", l, tp, w, h)
+		}
+		if text := d.SelectionText(); text != "sel €" {
+			t.Error("Unexpected selection text: ", text)
+		}
+		if html := d.SelectionHtml(); html != "<b>sel €</b>" {
+			t.Error("Unexpected selection html: ", html)
+		}
+		if pos, holder, holderPos := d.CharPosAtXY(3, 4); pos != 7 || !holder.Equals(d) || holderPos != 7 {
+			t.Error("Unexpected character position: ", pos, holderPos)
+		}
+		d.SetScrollBarValue(ScrollBarValue{Value: 20, Min: 0, Max: 50, Page: 5, Step: 2})
+		if v := d.ScrollBarValue(); v != (ScrollBarValue{20, 0, 50, 5, 2}) {
+			t.Error("Unexpected scroll bar value: ", v)
+		}
+		if !d.IsEmpty() {
+			t.Error("Expected the element to be empty")
+		}
+		if v := d.JsonValue(); v.T != T_INT || v.D != 42 {
+			t.Error("Unexpected value: ", v)
+		}
+		d.SetJsonValue(JsonValue{T: T_NULL})
+
+		expect := []BehaviorMethod{DO_CLICK, TEXT_EDIT_SET_SELECTION, TEXT_EDIT_GET_SELECTION, TEXT_EDIT_REPLACE_SELECTION,
+			TEXT_EDIT_GET_CARET_POSITION, TEXT_EDIT_GET_SELECTION_TEXT, TEXT_EDIT_GET_SELECTION_HTML, TEXT_EDIT_CHAR_POS_AT_XY,
+			SCROLL_BAR_SET_VALUE, SCROLL_BAR_GET_VALUE, IS_EMPTY, GET_VALUE, SET_VALUE}
+		if len(calls) != len(expect) {
+			t.Fatal("Unexpected calls: ", calls)
+		}
+		for i := range expect {
+			if calls[i] != expect[i] {
+				t.Fatal("Unexpected calls: ", calls)
+			}
+		}
+
+		if err, ok := d.TryReplaceTextSelection("other").(*DomError); !ok || err.Result != HLDOM_OK_NOT_HANDLED {
+			t.Error("Expected an unhandled method to fail, got: ", err)
+		}
+	})
+}
+
+func TestBehaviorMethodsNotHandled(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		if _, err := d.TryIsEmpty(); err == nil {
+			t.Error("Expected a plain div not to handle IS_EMPTY")
+		}
+		if err := d.TryClick(); err == nil {
+			t.Error("Expected a plain div not to handle DO_CLICK")
+		}
+	})
+}
+
+func TestMethodParamsDecode(t *testing.T) {
+	tests := []struct {
+		methodId uint32
+		check    func(v interface{}) bool
+	}{
+		{GET_TEXT_VALUE, func(v interface{}) bool { _, ok := v.(*TextValueParams); return ok }},
+		{TEXT_EDIT_GET_SELECTION, func(v interface{}) bool { _, ok := v.(*TextEditSelectionParams); return ok }},
+		{TEXT_EDIT_GET_SELECTION_HTML, func(v interface{}) bool { _, ok := v.(*TextSelectionParams); return ok }},
+		{SCROLL_BAR_SET_VALUE, func(v interface{}) bool { _, ok := v.(*ScrollBarValueParams); return ok }},
+		{SET_VALUE, func(v interface{}) bool { _, ok := v.(*ValueParams); return ok }},
+		{XCALL, func(v interface{}) bool { _, ok := v.(*XcallParams); return ok }},
+		{DO_CLICK, func(v interface{}) bool { _, ok := v.(*MethodParams); return ok }},
+		{FIRST_APPLICATION_METHOD_ID + 1, func(v interface{}) bool { _, ok := v.(*MethodParams); return ok }},
+	}
+	for _, test := range tests {
+		// Large enough for any of the structures
+		params := &XcallParams{MethodId: test.methodId}
+		if !test.check((*MethodParams)(unsafe.Pointer(params)).Decode()) {
+			t.Errorf("Decoded %s as the wrong type", BehaviorMethod(test.methodId))
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// Counts the clicks on its own element
+type counterBehavior struct {
+	name   string
+	e      *Element
+	clicks int
+	log    *[]string
+}
+
+func (b *counterBehavior) OnAttached(e *Element) {
+	*b.log = append(*b.log, b.name+" attached to "+elementId(e))
+}
+
+func (b *counterBehavior) OnDetached(e *Element) {
+	*b.log = append(*b.log, b.name+" detached from "+elementId(e))
+}
+
+func (b *counterBehavior) EventHandler() *EventHandler {
+	return &EventHandler{
+		OnMouse: func(he HELEMENT, params *MouseParams) bool {
+			if params.Event() == MOUSE_DOWN && params.Phase() == Phase(BUBBLING) && params.Target == b.e.Handle() {
+				b.clicks++
+			}
+			return false
+		},
+	}
+}
+
+func elementId(e *Element) string {
+	id, _ := e.Attr("id")
+	return id
+}
+
+func counterFactory(name string, log *[]string, created map[string]*counterBehavior) BehaviorFactory {
+	return func(e *Element) Behavior {
+		b := &counterBehavior{name: name, e: e, log: log}
+		created[elementId(e)] = b
+		return b
+	}
+}
+
+func TestBehaviorFactories(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		a, b := root.Select("#a")[0], root.Select("#b")[0]
+		r := registryFor(hwnd, true)
+		before := WindowStats(hwnd)
+
+		var log []string
+		created := make(map[string]*counterBehavior)
+		RegisterBehavior("counter", counterFactory("global", &log, created))
+		defer RegisterBehavior("counter", nil)
+
+		notifyHandler := &NotifyHandler{}
+		if !attachNamedBehavior(r, notifyHandler, a.Handle(), "counter") || !attachNamedBehavior(r, notifyHandler, b.Handle(), "counter") {
+			t.Fatal("Expected the global behavior to be found")
+		}
+		if attachNamedBehavior(r, notifyHandler, b.Handle(), "missing") {
+			t.Fatal("Expected an unknown behavior to be reported")
+		}
+		if len(created) != 2 || created["a"] == created["b"] {
+			t.Fatal("Expected an instance per element: ", created)
+		}
+		if s := WindowStats(hwnd); s.Behaviors != before.Behaviors+2 {
+			t.Fatal("Expected both instances to be kept alive: ", s.Behaviors)
+		}
+
+		params := &MouseParams{Cmd: MOUSE_DOWN, Target: b.Handle()}
+		mem.FireEvent(b.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		mem.FireEvent(b.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		if created["a"].clicks != 0 || created["b"].clicks != 2 {
+			t.Fatal("Expected separate state per element: ", created["a"].clicks, created["b"].clicks)
+		}
+
+		b.Delete()
+		if s := WindowStats(hwnd); s.Behaviors != before.Behaviors+1 {
+			t.Fatal("Expected the detached instance to be released: ", s.Behaviors)
+		}
+		if created["b"].e.Handle() != BAD_HELEMENT {
+			t.Fatal("Expected the instance's element to be released")
+		}
+		expect := []string{"global attached to a", "global attached to b", "global detached from b"}
+		if len(log) != len(expect) {
+			t.Fatal("Unexpected lifecycle: ", log)
+		}
+		for i := range expect {
+			if log[i] != expect[i] {
+				t.Fatal("Unexpected lifecycle: ", log)
+			}
+		}
+	})
+}
+
+func TestBehaviorLookupOrder(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		r := registryFor(hwnd, true)
+		var log []string
+		created := make(map[string]*counterBehavior)
+
+		shared := &EventHandler{OnAttached: func(he HELEMENT) { log = append(log, "shared") }}
+		notifyHandler := &NotifyHandler{Behaviors: map[string]*EventHandler{"counter": shared}}
+		RegisterBehavior("counter", counterFactory("global", &log, created))
+		defer RegisterBehavior("counter", nil)
+		RegisterWindowBehavior(hwnd, "counter", counterFactory("window", &log, created))
+
+		attachNamedBehavior(r, notifyHandler, d.Handle(), "counter")
+		RegisterWindowBehavior(hwnd, "counter", nil)
+		attachNamedBehavior(r, notifyHandler, d.Handle(), "counter")
+		notifyHandler.Behaviors = nil
+		attachNamedBehavior(r, notifyHandler, d.Handle(), "counter")
+
+		expect := []string{"window attached to a", "shared", "global attached to a"}
+		if len(log) != len(expect) {
+			t.Fatal("Unexpected lookups: ", log)
+		}
+		for i := range expect {
+			if log[i] != expect[i] {
+				t.Fatal("Unexpected lookups: ", log)
+			}
+		}
+	})
+}
+
+func TestBehaviorArgsAndUnknownBehaviors(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		r := registryFor(hwnd, true)
+		var log []string
+		var received BehaviorArgs
+		RegisterWindowBehaviorWithArgs(hwnd, "sortable", func(e *Element, args BehaviorArgs) Behavior {
+			received = args
+			return &counterBehavior{name: "sortable", e: e, log: &log}
+		})
+		defer RegisterWindowBehaviorWithArgs(hwnd, "sortable", nil)
+
+		var unknown []*UnknownBehaviorError
+		SetUnknownBehaviorHandler(func(err *UnknownBehaviorError) {
+			unknown = append(unknown, err)
+		})
+		defer SetUnknownBehaviorHandler(nil)
+
+		notifyHandler := &NotifyHandler{}
+		if !attachNamedBehavior(r, notifyHandler, d.Handle(), "sortable(column=2, numeric)") {
+			t.Fatal("Expected the behavior to be attached")
+		}
+		if column, _ := received.Int("column"); column != 2 || !received.Has("numeric") {
+			t.Fatal("Unexpected arguments: ", received)
+		}
+
+		if attachNamedBehavior(r, notifyHandler, d.Handle(), "missing(x=1)") || attachNamedBehavior(r, notifyHandler, d.Handle(), "sortable(x=") {
+			t.Fatal("Expected the behaviors to be rejected")
+		}
+		if len(unknown) != 2 || unknown[0].Err != nil || unknown[0].Spec != "missing(x=1)" || unknown[1].Err == nil || unknown[1].Element != d.Handle() {
+			t.Fatal("Unexpected unknown behavior reports: ", unknown)
+		}
+		if unknown[0].Error() != "No such behavior: missing(x=1)" {
+			t.Fatal("Unexpected message: ", unknown[0].Error())
+		}
+
+		SetUnknownBehaviorHandler(IgnoreUnknownBehavior)
+		attachNamedBehavior(r, notifyHandler, d.Handle(), "missing")
+		if len(unknown) != 2 {
+			t.Fatal("The replaced handler should not be called")
+		}
+	})
+}
+
+// Keeps the handler it hands out, so it can change it after attaching
+type handlerBehavior struct {
+	handler *EventHandler
+}
+
+func (b *handlerBehavior) OnAttached(e *Element)       {}
+func (b *handlerBehavior) OnDetached(e *Element)       {}
+func (b *handlerBehavior) EventHandler() *EventHandler { return b.handler }
+
+func TestBehaviorInstanceDetach(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		r := registryFor(hwnd, true)
+		instance := &handlerBehavior{handler: &EventHandler{OnMouse: func(he HELEMENT, params *MouseParams) bool { return false }}}
+		RegisterWindowBehavior(hwnd, "kept", func(e *Element) Behavior { return instance })
+		defer RegisterWindowBehavior(hwnd, "kept", nil)
+
+		userClicks := 0
+		user := &EventHandler{
+			OnMouse: func(he HELEMENT, params *MouseParams) bool {
+				userClicks++
+				return false
+			},
+		}
+		d.AttachHandler(user)
+		defer d.DetachHandler(user)
+		before := WindowStats(hwnd)
+		refs := refCount(d.Handle())
+
+		if !attachNamedBehavior(r, &NotifyHandler{}, d.Handle(), "kept") {
+			t.Fatal("Expected the behavior to be attached")
+		}
+
+		// Events go through the instance's own handler, changes included
+		instanceClicks := 0
+		instance.handler.OnMouse = func(he HELEMENT, params *MouseParams) bool {
+			instanceClicks++
+			return false
+		}
+		params := &MouseParams{Cmd: MOUSE_DOWN, Target: d.Handle()}
+		mem.FireEvent(d.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		if instanceClicks != 2 {
+			t.Fatal("Expected events to reach the instance's handler, got ", instanceClicks)
+		}
+
+		// Detaching the behavior leaves the element's other handlers alone
+		if ret := mem.DetachEventHandler(d.Handle(), instance.handler); ret != HLDOM_OK {
+			t.Fatal("Failed to detach the behavior: ", ret)
+		}
+		if s := WindowStats(hwnd); s.Behaviors != before.Behaviors || s.ElementHandlers != before.ElementHandlers {
+			t.Fatalf("Expected only the behavior to be forgotten: %+v", s)
+		}
+		if n := refCount(d.Handle()); n != refs {
+			t.Fatal("Expected the behavior's reference to be released, ref count: ", n)
+		}
+		userClicks = 0
+		mem.FireEvent(d.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		if userClicks != 2 || instanceClicks != 2 {
+			t.Fatal("Expected only the ordinary handler to still see events: ", userClicks, instanceClicks)
+		}
+	})
+}
@@ -0,0 +1,113 @@
+package gohl
+
+import (
+	"fmt"
+	"log"
+	"runtime/debug"
+	"unsafe"
+)
+
+/*
+CallbackPanic
+
+Describes a panic that was recovered at the boundary of an htmlayout callback.
+Panics must not unwind through the C frames of the engine, so the element and
+notify procs recover them, hand them to the panic handler and report the
+event as unhandled.
+*/
+type CallbackPanic struct {
+	// The value passed to panic
+	Value interface{}
+
+	// Describe() of the element the event was delivered to, empty for
+	// notifications that do not concern an element
+	Element string
+
+	// The HANDLE_* group of an element event, zero for notifications
+	EventGroup uint32
+
+	// The HLN_* code of a notification, zero for element events
+	NotifyCode uint32
+
+	// Stack of the goroutine at the point of the panic
+	Stack []byte
+}
+
+func (p *CallbackPanic) Error() string {
+	where := fmt.Sprintf("event group %#x", p.EventGroup)
+	if p.EventGroup == 0 && p.NotifyCode == 0 {
+		where = "dispatched function"
+	} else if p.NotifyCode != 0 {
+		where = fmt.Sprintf("notification %#x", p.NotifyCode)
+	}
+	if p.Element != "" {
+		where += " on " + p.Element
+	}
+	return fmt.Sprintf("panic in %s: %v", where, p.Value)
+}
+
+func logCallbackPanic(p *CallbackPanic) {
+	log.Print(p.Error(), "\n", string(p.Stack))
+}
+
+var panicHandler = logCallbackPanic
+
+// Sets the function that receives panics recovered from event and notify handlers.
+// It is called on the thread that runs the window's message loop.  Passing nil
+// restores the default, which logs the panic and its stack.
+func SetPanicHandler(handler func(p *CallbackPanic)) {
+	if handler == nil {
+		handler = logCallbackPanic
+	}
+	panicHandler = handler
+}
+
+// Returns a panic handler that forwards panics to the channel.  The send never
+// blocks the message loop; if the channel is full the panic is logged instead.
+func PanicChannel(ch chan<- *CallbackPanic) func(p *CallbackPanic) {
+	return func(p *CallbackPanic) {
+		select {
+		case ch <- p:
+		default:
+			logCallbackPanic(p)
+		}
+	}
+}
+
+// Describes the element without letting a bad handle cause a second panic
+func describeHandle(he HELEMENT) string {
+	if he == BAD_HELEMENT {
+		return ""
+	}
+	if s, err := (&Element{handle: he}).TryDescribe(); err == nil {
+		return s
+	}
+	return fmt.Sprintf("element %#x", uintptr(he))
+}
+
+// Must be deferred directly by the callback.  Recovers a panic and passes it to
+// the panic handler, a panic in the handler itself is logged and dropped.
+func recoverCallbackPanic(he HELEMENT, evtg, notifyCode uint32) {
+	if value := recover(); value != nil {
+		p := &CallbackPanic{
+			Value:      value,
+			EventGroup: evtg,
+			NotifyCode: notifyCode,
+			Stack:      debug.Stack(),
+		}
+		defer func() {
+			if value := recover(); value != nil {
+				log.Print("Panic in panic handler: ", value, "\n", p.Error())
+			}
+		}()
+		p.Element = describeHandle(he)
+		panicHandler(p)
+	}
+}
+
+// Like handleEvent, but contains panics raised by the callbacks.  Backends
+// dispatch through handleEventRecorded, which calls this.
+func (handler *EventHandler) handleEventSafely(he HELEMENT, evtg uint32, params unsafe.Pointer) (handled bool) {
+	defer recoverCallbackPanic(he, evtg, 0)
+	return handler.handleEvent(he, evtg, params)
+}
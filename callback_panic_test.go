@@ -0,0 +1,67 @@
+package gohl
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCallbackPanicContained(t *testing.T) {
+	panics := make(chan *CallbackPanic, 1)
+	SetPanicHandler(PanicChannel(panics))
+	defer SetPanicHandler(nil)
+
+	testWithMemoryHtml(`<div id="a" class="x"></div>`, func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		d.AttachHandler(&EventHandler{
+			OnBehaviorEvent: func(he HELEMENT, params *BehaviorEventParams) bool {
+				panic("boom")
+			},
+		})
+		if d.SendEvent(FIRST_APPLICATION_EVENT_CODE, d, 0) {
+			t.Fatal("A panicking handler should not handle the event")
+		}
+
+		var p *CallbackPanic
+		select {
+		case p = <-panics:
+		default:
+			t.Fatal("Expected the panic to be reported")
+		}
+		if p.Value != "boom" {
+			t.Fatal("Unexpected panic value: ", p.Value)
+		}
+		if p.Element != "div#a.x" {
+			t.Fatal("Unexpected element description: ", p.Element)
+		}
+		if p.EventGroup != HANDLE_BEHAVIOR_EVENT {
+			t.Fatal("Unexpected event group: ", p.EventGroup)
+		}
+		if !strings.Contains(string(p.Stack), "TestCallbackPanicContained") {
+			t.Fatal("Stack should include the panicking handler")
+		}
+	})
+}
+
+func TestUnknownEventGroupReported(t *testing.T) {
+	var reported *CallbackPanic
+	SetPanicHandler(func(p *CallbackPanic) { reported = p })
+	defer SetPanicHandler(nil)
+
+	handler := &EventHandler{}
+	if handler.handleEventSafely(BAD_HELEMENT, 0x7fff0000, nil) {
+		t.Fatal("Unknown event group should not be handled")
+	}
+	if reported == nil || reported.EventGroup != 0x7fff0000 || reported.Element != "" {
+		t.Fatal("Expected the unknown event group to be reported")
+	}
+}
+
+func TestPanicInPanicHandler(t *testing.T) {
+	SetPanicHandler(func(p *CallbackPanic) { panic("again") })
+	defer SetPanicHandler(nil)
+
+	handler := &EventHandler{
+		OnSize: func(he HELEMENT) { panic("boom") },
+	}
+	handler.handleEventSafely(BAD_HELEMENT, HANDLE_SIZE, nil)
+}
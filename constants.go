@@ -0,0 +1,314 @@
+package gohl
+
+const (
+
+	// HTMLayout Notify Events
+	HLN_CREATE_CONTROL    = 0xAFF + 0x01
+	HLN_LOAD_DATA         = 0xAFF + 0x02
+	HLN_CONTROL_CREATED   = 0xAFF + 0x03
+	HLN_DATA_LOADED       = 0xAFF + 0x04
+	HLN_DOCUMENT_COMPLETE = 0xAFF + 0x05
+	HLN_UPDATE_UI         = 0xAFF + 0x06
+	HLN_DESTROY_CONTROL   = 0xAFF + 0x07
+	HLN_ATTACH_BEHAVIOR   = 0xAFF + 0x08
+	HLN_BEHAVIOR_CHANGED  = 0xAFF + 0x09
+	HLN_DIALOG_CREATED    = 0xAFF + 0x10
+	HLN_DIALOG_CLOSE_RQ   = 0xAFF + 0x0A
+	HLN_DOCUMENT_LOADED   = 0xAFF + 0x0B
+
+	// PhaseMask
+	BUBBLING = uint32(0)       // bubbling (emersion) phase
+	SINKING  = uint32(0x08000) // capture (immersion) phase, this flag is or'ed with EVENTS codes below
+	HANDLED  = uint32(0x10000) // event already processed.
+
+	// EventGroups
+	HANDLE_INITIALIZATION = 0x0000 /** attached/detached */
+	HANDLE_MOUSE          = 0x0001 /** mouse events */
+	HANDLE_KEY            = 0x0002 /** key events */
+	HANDLE_FOCUS          = 0x0004 /** focus events, if this flag is set it also means that element it attached to is focusable */
+	HANDLE_SCROLL         = 0x0008 /** scroll events */
+	HANDLE_TIMER          = 0x0010 /** timer event */
+	HANDLE_SIZE           = 0x0020 /** size changed event */
+	HANDLE_DRAW           = 0x0040 /** drawing request (event) */
+	HANDLE_DATA_ARRIVED   = 0x0080 /** requested data () has been delivered */
+	HANDLE_BEHAVIOR_EVENT = 0x0100 /** secondary, synthetic events:
+	BUTTON_CLICK, HYPERLINK_CLICK, etc.,
+	a.k.a. notifications from intrinsic behaviors */
+	HANDLE_METHOD_CALL     = 0x0200     /** behavior specific methods */
+	HANDLE_EXCHANGE        = 0x1000     /** system drag-n-drop */
+	HANDLE_GESTURE         = 0x2000     /** touch input events */
+	HANDLE_ALL             = 0xFFFF     /** all of them */
+	DISABLE_INITIALIZATION = 0x80000000 /** disable INITIALIZATION events to be sent. */
+
+	// KeyboardStates
+	CONTROL_KEY_PRESSED = 0x1
+	SHIFT_KEY_PRESSED   = 0x2
+	ALT_KEY_PRESSED     = 0x4
+
+	// InitializationEvents
+	BEHAVIOR_DETACH = 0
+	BEHAVIOR_ATTACH = 1
+
+	// DraggingType
+	NO_DRAGGING   = 0
+	DRAGGING_MOVE = 1
+	DRAGGING_COPY = 2
+
+	// MouseButtons
+	MAIN_MOUSE_BUTTON   = 1 //aka left button
+	PROP_MOUSE_BUTTON   = 2 //aka right button
+	MIDDLE_MOUSE_BUTTON = 4
+	X1_MOUSE_BUTTON     = 8
+	X2_MOUSE_BUTTON     = 16
+
+	// MouseEvents
+	MOUSE_ENTER  = 0
+	MOUSE_LEAVE  = 1
+	MOUSE_MOVE   = 2
+	MOUSE_UP     = 3
+	MOUSE_DOWN   = 4
+	MOUSE_DCLICK = 5
+	MOUSE_WHEEL  = 6
+	MOUSE_TICK   = 7     // mouse pressed ticks
+	MOUSE_IDLE   = 8     // mouse stay idle for some time
+	DROP         = 9     // item dropped, target is that dropped item
+	DRAG_ENTER   = 10    // drag arrived to the target element that is one of current drop targets.
+	DRAG_LEAVE   = 11    // drag left one of current drop targets. target is the drop target element.
+	DRAG_REQUEST = 12    // drag src notification before drag start. To cancel - return true from handler.
+	MOUSE_CLICK  = 0xFF  // mouse click event
+	DRAGGING     = 0x100 // This flag is 'ORed' with MOUSE_ENTER..MOUSE_DOWN codes if dragging operation is in effect.
+	// E.g. event DRAGGING | MOUSE_MOVE is sent to underlying DOM elements while dragging.
+
+	// CursorType
+	CURSOR_ARROW       = 0  //0
+	CURSOR_IBEAM       = 1  //1
+	CURSOR_WAIT        = 2  //2
+	CURSOR_CROSS       = 3  //3
+	CURSOR_UPARROW     = 4  //4
+	CURSOR_SIZENWSE    = 5  //5
+	CURSOR_SIZENESW    = 6  //6
+	CURSOR_SIZEWE      = 7  //7
+	CURSOR_SIZENS      = 8  //8
+	CURSOR_SIZEALL     = 9  //9
+	CURSOR_NO          = 10 //10
+	CURSOR_APPSTARTING = 11 //11
+	CURSOR_HELP        = 12 //12
+	CURSOR_HAND        = 13 //13
+	CURSOR_DRAG_MOVE   = 14 //14
+	CURSOR_DRAG_COPY   = 15 //15
+
+	// KeyEvents
+	KEY_DOWN = 0
+	KEY_UP   = 1
+	KEY_CHAR = 2
+
+	// FocusEvents
+	FOCUS_LOST = 0
+	FOCUS_GOT  = 1
+
+	// FocusCause
+	BY_CODE     = 0
+	BY_MOUSE    = 1
+	BY_KEY_NEXT = 2
+	BY_KEY_PREV = 3
+
+	// ScrollEvents
+	SCROLL_HOME            = 0
+	SCROLL_END             = 1
+	SCROLL_STEP_PLUS       = 2
+	SCROLL_STEP_MINUS      = 3
+	SCROLL_PAGE_PLUS       = 4
+	SCROLL_PAGE_MINUS      = 5
+	SCROLL_POS             = 6
+	SCROLL_SLIDER_RELEASED = 7
+
+	// GestureCmd
+	GESTURE_REQUEST = 0 // return true and fill flags if it will handle gestures.
+	GESTURE_ZOOM    = 1 // The zoom gesture.
+	GESTURE_PAN     = 2 // The pan gesture.
+	GESTURE_ROTATE  = 3 // The rotation gesture.
+	GESTURE_TAP1    = 4 // The tap gesture.
+	GESTURE_TAP2    = 5 // The two-finger tap gesture.
+
+	// GestureState
+	GESTURE_STATE_BEGIN   = 1 // starts
+	GESTURE_STATE_INERTIA = 2 // events generated by inertia processor
+	GESTURE_STATE_END     = 4 // end, last event of the gesture sequence
+
+	// GestureTypeFlags
+	GESTURE_FLAG_ZOOM             = 0x0001
+	GESTURE_FLAG_ROTATE           = 0x0002
+	GESTURE_FLAG_PAN_VERTICAL     = 0x0004
+	GESTURE_FLAG_PAN_HORIZONTAL   = 0x0008
+	GESTURE_FLAG_TAP1             = 0x0010 // press & tap
+	GESTURE_FLAG_TAP2             = 0x0020 // two fingers tap
+	GESTURE_FLAG_PAN_WITH_GUTTER  = 0x4000 // PAN_VERTICAL and PAN_HORIZONTAL modifiers
+	GESTURE_FLAG_PAN_WITH_INERTIA = 0x8000 //
+	GESTURE_FLAGS_ALL             = 0xFFFF //
+
+	// DrawEvents
+	DRAW_BACKGROUND = 0
+	DRAW_CONTENT    = 1
+	DRAW_FOREGROUND = 2
+
+	// ExchangeEvents
+	X_DRAG_ENTER = 0
+	X_DRAG_LEAVE = 1
+	X_DRAG       = 2
+	X_DROP       = 3
+
+	// ExchangeDataType
+	EXF_UNDEFINED = 0
+	EXF_TEXT      = 0x01 // FETCH_EXCHANGE_DATA will receive UTF8 encoded string - plain text
+	EXF_HTML      = 0x02 // FETCH_EXCHANGE_DATA will receive UTF8 encoded string - html
+	EXF_HYPERLINK = 0x04 // FETCH_EXCHANGE_DATA will receive UTF8 encoded string with pair url\0caption (null separated)
+	EXF_JSON      = 0x08 // FETCH_EXCHANGE_DATA will receive UTF8 encoded string with JSON literal
+	EXF_FILE      = 0x10 // FETCH_EXCHANGE_DATA will receive UTF8 encoded list of file names separated by nulls
+
+	// ExchangeCommands
+	EXC_NONE = 0
+	EXC_COPY = 1
+	EXC_MOVE = 2
+	EXC_LINK = 4
+
+	// BehaviorEvents
+	BUTTON_CLICK             = 0 // click on button
+	BUTTON_PRESS             = 1 // mouse down or key down in button
+	BUTTON_STATE_CHANGED     = 2 // checkbox/radio/slider changed its state/value
+	EDIT_VALUE_CHANGING      = 3 // before text change
+	EDIT_VALUE_CHANGED       = 4 // after text change
+	SELECT_SELECTION_CHANGED = 5 // selection in <select> changed
+	SELECT_STATE_CHANGED     = 6 // node in select expanded/collapsed, heTarget is the node
+	POPUP_REQUEST            = 7 // request to show popup just received,
+	//     here DOM of popup element can be modifed.
+	POPUP_READY = 8 // popup element has been measured and ready to be shown on screen,
+	//     here you can use functions like ScrollToView.
+	POPUP_DISMISSED = 9 // popup element is closed,
+	//     here DOM of popup element can be modifed again - e.g. some items can be removed
+	//     to free memory.
+	MENU_ITEM_ACTIVE = 10 // menu item activated by mouse hover or by keyboard,
+	MENU_ITEM_CLICK  = 11 // menu item click,
+	//   BEHAVIOR_EVENT_PARAMS structure layout
+	//   BEHAVIOR_EVENT_PARAMS.cmd - MENU_ITEM_CLICK/MENU_ITEM_ACTIVE
+	//   BEHAVIOR_EVENT_PARAMS.heTarget - the menu item, presumably <li> element
+	//   BEHAVIOR_EVENT_PARAMS.reason - BY_MOUSE_CLICK | BY_KEY_CLICK
+	CONTEXT_MENU_SETUP   = 0xF  // evt.he is a menu dom element that is about to be shown. You can disable/enable items in it.
+	CONTEXT_MENU_REQUEST = 0x10 // "right-click", BEHAVIOR_EVENT_PARAMS::he is current popup menu HELEMENT being processed or NULL.
+	// application can provide its own HELEMENT here (if it is NULL) or modify current menu element.
+	VISIUAL_STATUS_CHANGED  = 0x11 // broadcast notification, sent to all elements of some container being shown or hidden
+	DISABLED_STATUS_CHANGED = 0x12 // broadcast notification, sent to all elements of some container that got new value of :disabled state
+	POPUP_DISMISSING        = 0x13 // popup is about to be closed
+
+	// "grey" event codes  - notfications from behaviors from this SDK
+	HYPERLINK_CLICK    = 0x80 // hyperlink click
+	TABLE_HEADER_CLICK = 0x81 // click on some cell in table header,
+	//     target = the cell,
+	//     reason = index of the cell (column number, 0..n)
+	TABLE_ROW_CLICK = 0x82 // click on data row in the table, target is the row
+	//     target = the row,
+	//     reason = index of the row (fixed_rows..n)
+	TABLE_ROW_DBL_CLICK = 0x83 // mouse dbl click on data row in the table, target is the row
+	//     target = the row,
+	//     reason = index of the row (fixed_rows..n)
+	ELEMENT_COLLAPSED = 0x90 // element was collapsed, so far only behavior:tabs is sending these two to the panels
+	ELEMENT_EXPANDED  = 0x91 // element was expanded,
+	ACTIVATE_CHILD    = 0x92 // activate (select) child,
+	// used for example by accesskeys behaviors to send activation request, e.g. tab on behavior:tabs.
+	DO_SWITCH_TAB = ACTIVATE_CHILD // command to switch tab programmatically, handled by behavior:tabs
+	// use it as HTMLayoutPostEvent(tabsElementOrItsChild, DO_SWITCH_TAB, tabElementToShow, 0);
+	INIT_DATA_VIEW    = 0x93 // request to virtual grid to initialize its view
+	ROWS_DATA_REQUEST = 0x94 // request from virtual grid to data source behavior to fill data in the table
+	// parameters passed throug DATA_ROWS_PARAMS structure.
+	UI_STATE_CHANGED = 0x95 // ui state changed, observers shall update their visual states.
+	// is sent for example by behavior:richtext when caret position/selection has changed.
+	FORM_SUBMIT = 0x96 // behavior:form detected submission event. BEHAVIOR_EVENT_PARAMS::data field contains data to be posted.
+	// BEHAVIOR_EVENT_PARAMS::data is of type T_MAP in this case key/value pairs of data that is about
+	// to be submitted. You can modify the data or discard submission by returning TRUE from the handler.
+	FORM_RESET = 0x97 // behavior:form detected reset event (from button type = reset). BEHAVIOR_EVENT_PARAMS::data field contains data to be reset.
+	// BEHAVIOR_EVENT_PARAMS::data is of type T_MAP in this case key/value pairs of data that is about
+	// to be rest. You can modify the data or discard reset by returning TRUE from the handler.
+	DOCUMENT_COMPLETE            = 0x98 // behavior:frame have complete document.
+	HISTORY_PUSH                 = 0x99 // behavior:history stuff
+	HISTORY_DROP                 = 0x9A
+	HISTORY_PRIOR                = 0x9B
+	HISTORY_NEXT                 = 0x9C
+	HISTORY_STATE_CHANGED        = 0x9D // behavior:history notification - history stack has changed
+	CLOSE_POPUP                  = 0x9E // close popup request,
+	REQUEST_TOOLTIP              = 0x9F // request tooltip, BEHAVIOR_EVENT_PARAMS.he <- is the tooltip element.
+	ANIMATION                    = 0xA0 // animation started (reason = 1) or ended(reason = 0) on the element.
+	FIRST_APPLICATION_EVENT_CODE = 0x100
+	// all custom event codes shall be greater
+	// than this number. All codes below this will be used
+	// solely by application - HTMLayout will not intrepret it
+	// and will do just dispatching.
+	// To send event notifications with  these codes use
+	// HTMLayoutSend/PostEvent API.
+
+	// EventReason
+	BY_MOUSE_CLICK = 0
+	BY_KEY_CLICK   = 1
+	SYNTHESIZED    = 2
+
+	// EventChangedReason
+	BY_INS_CHAR  = 0 // single char insertion
+	BY_INS_CHARS = 1 // character range insertion, clipboard
+	BY_DEL_CHAR  = 2 // single char deletion
+	BY_DEL_CHARS = 3 // character range deletion (selection)
+
+	// BehaviorMethodIdentifiers
+	DO_CLICK                = 0
+	GET_TEXT_VALUE          = 1
+	SET_TEXT_VALUE          = 2 // p - TEXT_VALUE_PARAMS
+	TEXT_EDIT_GET_SELECTION = 3 // p - TEXT_EDIT_SELECTION_PARAMS
+	TEXT_EDIT_SET_SELECTION = 4 // p - TEXT_EDIT_SELECTION_PARAMS
+	// Replace selection content or insert text at current caret position.
+	// Replaced text will be selected.
+	TEXT_EDIT_REPLACE_SELECTION = 5 // p - TEXT_EDIT_REPLACE_SELECTION_PARAMS
+	// Set value of type = "vscrollbar"/"hscrollbar"
+	SCROLL_BAR_GET_VALUE = 6
+	SCROLL_BAR_SET_VALUE = 7
+	// get current caret position, it returns rectangle that is relative to origin of the editing element.
+	TEXT_EDIT_GET_CARET_POSITION = 8    // p - TEXT_CARET_POSITION_PARAMS
+	TEXT_EDIT_GET_SELECTION_TEXT = 9    // p - TEXT_SELECTION_PARAMS, OutputStreamProc will receive stream of WCHARs
+	TEXT_EDIT_GET_SELECTION_HTML = 10   // p - TEXT_SELECTION_PARAMS, OutputStreamProc will receive stream of BYTEs - utf8 encoded html fragment.
+	TEXT_EDIT_CHAR_POS_AT_XY     = 11   // p - TEXT_EDIT_CHAR_POS_AT_XY_PARAMS
+	IS_EMPTY                     = 0xFC // p - IS_EMPTY_PARAMS // set VALUE_PARAMS::is_empty (false/true) reflects :empty state of the element.
+	GET_VALUE                    = 0xFD // p - VALUE_PARAMS
+	SET_VALUE                    = 0xFE // p - VALUE_PARAMS
+	XCALL                        = 0xFF // p - XCALL_PARAMS
+	FIRST_APPLICATION_METHOD_ID  = 0x100
+
+	// Content insertion locations
+	SIH_REPLACE_CONTENT   = 0
+	SIH_INSERT_AT_START   = 1
+	SIH_APPEND_AFTER_LAST = 2
+	SOH_REPLACE           = 3
+	SOH_INSERT_BEFORE     = 4
+	SOH_INSERT_AFTER      = 5
+
+	CONTENT_BOX = 0x00
+	PADDING_BOX = 0x10
+	BORDER_BOX  = 0x20
+	MARGIN_BOX  = 0x30
+
+	ROOT_RELATIVE      = 0x01 // - or this flag if you want to get HTMLayout window relative coordinates, otherwise it will use nearest windowed container e.g. popup window.
+	SELF_RELATIVE      = 0x02 // - "or" this flag if you want to get coordinates relative to the origin of element iself.
+	CONTAINER_RELATIVE = 0x03 // - position inside immediate container.
+	VIEW_RELATIVE      = 0x04
+
+	T_UNDEFINED  = 0
+	T_NULL       = 1
+	T_BOOL       = 2
+	T_INT        = 3
+	T_FLOAT      = 4
+	T_STRING     = 5
+	T_DATE       = 6
+	T_CURRENCY   = 7
+	T_LENGTH     = 8
+	T_ARRAY      = 9
+	T_MAP        = 10
+	T_FUNCTION   = 11
+	T_BYTES      = 12
+	T_OBJECT     = 13
+	T_DOM_OBJECT = 14
+)
@@ -0,0 +1,243 @@
+package gohl
+
+/*
+#cgo CFLAGS: -I./htmlayout/include
+
+#include <htmlayout.h>
+*/
+import "C"
+
+// The constants are written out so that the package builds without the
+// headers.  This fails to compile if one of them differs from the headers:
+// the index is out of range unless the two are equal.
+var (
+	_ = [1]struct{}{}[HLN_CREATE_CONTROL-C.HLN_CREATE_CONTROL]
+	_ = [1]struct{}{}[HLN_LOAD_DATA-C.HLN_LOAD_DATA]
+	_ = [1]struct{}{}[HLN_CONTROL_CREATED-C.HLN_CONTROL_CREATED]
+	_ = [1]struct{}{}[HLN_DATA_LOADED-C.HLN_DATA_LOADED]
+	_ = [1]struct{}{}[HLN_DOCUMENT_COMPLETE-C.HLN_DOCUMENT_COMPLETE]
+	_ = [1]struct{}{}[HLN_UPDATE_UI-C.HLN_UPDATE_UI]
+	_ = [1]struct{}{}[HLN_DESTROY_CONTROL-C.HLN_DESTROY_CONTROL]
+	_ = [1]struct{}{}[HLN_ATTACH_BEHAVIOR-C.HLN_ATTACH_BEHAVIOR]
+	_ = [1]struct{}{}[HLN_BEHAVIOR_CHANGED-C.HLN_BEHAVIOR_CHANGED]
+	_ = [1]struct{}{}[HLN_DIALOG_CREATED-C.HLN_DIALOG_CREATED]
+	_ = [1]struct{}{}[HLN_DIALOG_CLOSE_RQ-C.HLN_DIALOG_CLOSE_RQ]
+	_ = [1]struct{}{}[HLN_DOCUMENT_LOADED-C.HLN_DOCUMENT_LOADED]
+	_ = [1]struct{}{}[BUBBLING-C.BUBBLING]
+	_ = [1]struct{}{}[SINKING-C.SINKING]
+	_ = [1]struct{}{}[HANDLED-C.HANDLED]
+	_ = [1]struct{}{}[HANDLE_INITIALIZATION-C.HANDLE_INITIALIZATION]
+	_ = [1]struct{}{}[HANDLE_MOUSE-C.HANDLE_MOUSE]
+	_ = [1]struct{}{}[HANDLE_KEY-C.HANDLE_KEY]
+	_ = [1]struct{}{}[HANDLE_FOCUS-C.HANDLE_FOCUS]
+	_ = [1]struct{}{}[HANDLE_SCROLL-C.HANDLE_SCROLL]
+	_ = [1]struct{}{}[HANDLE_TIMER-C.HANDLE_TIMER]
+	_ = [1]struct{}{}[HANDLE_SIZE-C.HANDLE_SIZE]
+	_ = [1]struct{}{}[HANDLE_DRAW-C.HANDLE_DRAW]
+	_ = [1]struct{}{}[HANDLE_DATA_ARRIVED-C.HANDLE_DATA_ARRIVED]
+	_ = [1]struct{}{}[HANDLE_BEHAVIOR_EVENT-C.HANDLE_BEHAVIOR_EVENT]
+	_ = [1]struct{}{}[HANDLE_METHOD_CALL-C.HANDLE_METHOD_CALL]
+	_ = [1]struct{}{}[HANDLE_EXCHANGE-C.HANDLE_EXCHANGE]
+	_ = [1]struct{}{}[HANDLE_GESTURE-C.HANDLE_GESTURE]
+	_ = [1]struct{}{}[HANDLE_ALL-C.HANDLE_ALL]
+	_ = [1]struct{}{}[DISABLE_INITIALIZATION-C.DISABLE_INITIALIZATION]
+	_ = [1]struct{}{}[CONTROL_KEY_PRESSED-C.CONTROL_KEY_PRESSED]
+	_ = [1]struct{}{}[SHIFT_KEY_PRESSED-C.SHIFT_KEY_PRESSED]
+	_ = [1]struct{}{}[ALT_KEY_PRESSED-C.ALT_KEY_PRESSED]
+	_ = [1]struct{}{}[BEHAVIOR_DETACH-C.BEHAVIOR_DETACH]
+	_ = [1]struct{}{}[BEHAVIOR_ATTACH-C.BEHAVIOR_ATTACH]
+	_ = [1]struct{}{}[NO_DRAGGING-C.NO_DRAGGING]
+	_ = [1]struct{}{}[DRAGGING_MOVE-C.DRAGGING_MOVE]
+	_ = [1]struct{}{}[DRAGGING_COPY-C.DRAGGING_COPY]
+	_ = [1]struct{}{}[MAIN_MOUSE_BUTTON-C.MAIN_MOUSE_BUTTON]
+	_ = [1]struct{}{}[PROP_MOUSE_BUTTON-C.PROP_MOUSE_BUTTON]
+	_ = [1]struct{}{}[MIDDLE_MOUSE_BUTTON-C.MIDDLE_MOUSE_BUTTON]
+	_ = [1]struct{}{}[X1_MOUSE_BUTTON-C.X1_MOUSE_BUTTON]
+	_ = [1]struct{}{}[X2_MOUSE_BUTTON-C.X2_MOUSE_BUTTON]
+	_ = [1]struct{}{}[MOUSE_ENTER-C.MOUSE_ENTER]
+	_ = [1]struct{}{}[MOUSE_LEAVE-C.MOUSE_LEAVE]
+	_ = [1]struct{}{}[MOUSE_MOVE-C.MOUSE_MOVE]
+	_ = [1]struct{}{}[MOUSE_UP-C.MOUSE_UP]
+	_ = [1]struct{}{}[MOUSE_DOWN-C.MOUSE_DOWN]
+	_ = [1]struct{}{}[MOUSE_DCLICK-C.MOUSE_DCLICK]
+	_ = [1]struct{}{}[MOUSE_WHEEL-C.MOUSE_WHEEL]
+	_ = [1]struct{}{}[MOUSE_TICK-C.MOUSE_TICK]
+	_ = [1]struct{}{}[MOUSE_IDLE-C.MOUSE_IDLE]
+	_ = [1]struct{}{}[DROP-C.DROP]
+	_ = [1]struct{}{}[DRAG_ENTER-C.DRAG_ENTER]
+	_ = [1]struct{}{}[DRAG_LEAVE-C.DRAG_LEAVE]
+	_ = [1]struct{}{}[DRAG_REQUEST-C.DRAG_REQUEST]
+	_ = [1]struct{}{}[MOUSE_CLICK-C.MOUSE_CLICK]
+	_ = [1]struct{}{}[DRAGGING-C.DRAGGING]
+	_ = [1]struct{}{}[CURSOR_ARROW-C.CURSOR_ARROW]
+	_ = [1]struct{}{}[CURSOR_IBEAM-C.CURSOR_IBEAM]
+	_ = [1]struct{}{}[CURSOR_WAIT-C.CURSOR_WAIT]
+	_ = [1]struct{}{}[CURSOR_CROSS-C.CURSOR_CROSS]
+	_ = [1]struct{}{}[CURSOR_UPARROW-C.CURSOR_UPARROW]
+	_ = [1]struct{}{}[CURSOR_SIZENWSE-C.CURSOR_SIZENWSE]
+	_ = [1]struct{}{}[CURSOR_SIZENESW-C.CURSOR_SIZENESW]
+	_ = [1]struct{}{}[CURSOR_SIZEWE-C.CURSOR_SIZEWE]
+	_ = [1]struct{}{}[CURSOR_SIZENS-C.CURSOR_SIZENS]
+	_ = [1]struct{}{}[CURSOR_SIZEALL-C.CURSOR_SIZEALL]
+	_ = [1]struct{}{}[CURSOR_NO-C.CURSOR_NO]
+	_ = [1]struct{}{}[CURSOR_APPSTARTING-C.CURSOR_APPSTARTING]
+	_ = [1]struct{}{}[CURSOR_HELP-C.CURSOR_HELP]
+	_ = [1]struct{}{}[CURSOR_HAND-C.CURSOR_HAND]
+	_ = [1]struct{}{}[CURSOR_DRAG_MOVE-C.CURSOR_DRAG_MOVE]
+	_ = [1]struct{}{}[CURSOR_DRAG_COPY-C.CURSOR_DRAG_COPY]
+	_ = [1]struct{}{}[KEY_DOWN-C.KEY_DOWN]
+	_ = [1]struct{}{}[KEY_UP-C.KEY_UP]
+	_ = [1]struct{}{}[KEY_CHAR-C.KEY_CHAR]
+	_ = [1]struct{}{}[FOCUS_LOST-C.FOCUS_LOST]
+	_ = [1]struct{}{}[FOCUS_GOT-C.FOCUS_GOT]
+	_ = [1]struct{}{}[BY_CODE-C.BY_CODE]
+	_ = [1]struct{}{}[BY_MOUSE-C.BY_MOUSE]
+	_ = [1]struct{}{}[BY_KEY_NEXT-C.BY_KEY_NEXT]
+	_ = [1]struct{}{}[BY_KEY_PREV-C.BY_KEY_PREV]
+	_ = [1]struct{}{}[SCROLL_HOME-C.SCROLL_HOME]
+	_ = [1]struct{}{}[SCROLL_END-C.SCROLL_END]
+	_ = [1]struct{}{}[SCROLL_STEP_PLUS-C.SCROLL_STEP_PLUS]
+	_ = [1]struct{}{}[SCROLL_STEP_MINUS-C.SCROLL_STEP_MINUS]
+	_ = [1]struct{}{}[SCROLL_PAGE_PLUS-C.SCROLL_PAGE_PLUS]
+	_ = [1]struct{}{}[SCROLL_PAGE_MINUS-C.SCROLL_PAGE_MINUS]
+	_ = [1]struct{}{}[SCROLL_POS-C.SCROLL_POS]
+	_ = [1]struct{}{}[SCROLL_SLIDER_RELEASED-C.SCROLL_SLIDER_RELEASED]
+	_ = [1]struct{}{}[GESTURE_REQUEST-C.GESTURE_REQUEST]
+	_ = [1]struct{}{}[GESTURE_ZOOM-C.GESTURE_ZOOM]
+	_ = [1]struct{}{}[GESTURE_PAN-C.GESTURE_PAN]
+	_ = [1]struct{}{}[GESTURE_ROTATE-C.GESTURE_ROTATE]
+	_ = [1]struct{}{}[GESTURE_TAP1-C.GESTURE_TAP1]
+	_ = [1]struct{}{}[GESTURE_TAP2-C.GESTURE_TAP2]
+	_ = [1]struct{}{}[GESTURE_STATE_BEGIN-C.GESTURE_STATE_BEGIN]
+	_ = [1]struct{}{}[GESTURE_STATE_INERTIA-C.GESTURE_STATE_INERTIA]
+	_ = [1]struct{}{}[GESTURE_STATE_END-C.GESTURE_STATE_END]
+	_ = [1]struct{}{}[GESTURE_FLAG_ZOOM-C.GESTURE_FLAG_ZOOM]
+	_ = [1]struct{}{}[GESTURE_FLAG_ROTATE-C.GESTURE_FLAG_ROTATE]
+	_ = [1]struct{}{}[GESTURE_FLAG_PAN_VERTICAL-C.GESTURE_FLAG_PAN_VERTICAL]
+	_ = [1]struct{}{}[GESTURE_FLAG_PAN_HORIZONTAL-C.GESTURE_FLAG_PAN_HORIZONTAL]
+	_ = [1]struct{}{}[GESTURE_FLAG_TAP1-C.GESTURE_FLAG_TAP1]
+	_ = [1]struct{}{}[GESTURE_FLAG_TAP2-C.GESTURE_FLAG_TAP2]
+	_ = [1]struct{}{}[GESTURE_FLAG_PAN_WITH_GUTTER-C.GESTURE_FLAG_PAN_WITH_GUTTER]
+	_ = [1]struct{}{}[GESTURE_FLAG_PAN_WITH_INERTIA-C.GESTURE_FLAG_PAN_WITH_INERTIA]
+	_ = [1]struct{}{}[GESTURE_FLAGS_ALL-C.GESTURE_FLAGS_ALL]
+	_ = [1]struct{}{}[DRAW_BACKGROUND-C.DRAW_BACKGROUND]
+	_ = [1]struct{}{}[DRAW_CONTENT-C.DRAW_CONTENT]
+	_ = [1]struct{}{}[DRAW_FOREGROUND-C.DRAW_FOREGROUND]
+	_ = [1]struct{}{}[X_DRAG_ENTER-C.X_DRAG_ENTER]
+	_ = [1]struct{}{}[X_DRAG_LEAVE-C.X_DRAG_LEAVE]
+	_ = [1]struct{}{}[X_DRAG-C.X_DRAG]
+	_ = [1]struct{}{}[X_DROP-C.X_DROP]
+	_ = [1]struct{}{}[EXF_UNDEFINED-C.EXF_UNDEFINED]
+	_ = [1]struct{}{}[EXF_TEXT-C.EXF_TEXT]
+	_ = [1]struct{}{}[EXF_HTML-C.EXF_HTML]
+	_ = [1]struct{}{}[EXF_HYPERLINK-C.EXF_HYPERLINK]
+	_ = [1]struct{}{}[EXF_JSON-C.EXF_JSON]
+	_ = [1]struct{}{}[EXF_FILE-C.EXF_FILE]
+	_ = [1]struct{}{}[EXC_NONE-C.EXC_NONE]
+	_ = [1]struct{}{}[EXC_COPY-C.EXC_COPY]
+	_ = [1]struct{}{}[EXC_MOVE-C.EXC_MOVE]
+	_ = [1]struct{}{}[EXC_LINK-C.EXC_LINK]
+	_ = [1]struct{}{}[BUTTON_CLICK-C.BUTTON_CLICK]
+	_ = [1]struct{}{}[BUTTON_PRESS-C.BUTTON_PRESS]
+	_ = [1]struct{}{}[BUTTON_STATE_CHANGED-C.BUTTON_STATE_CHANGED]
+	_ = [1]struct{}{}[EDIT_VALUE_CHANGING-C.EDIT_VALUE_CHANGING]
+	_ = [1]struct{}{}[EDIT_VALUE_CHANGED-C.EDIT_VALUE_CHANGED]
+	_ = [1]struct{}{}[SELECT_SELECTION_CHANGED-C.SELECT_SELECTION_CHANGED]
+	_ = [1]struct{}{}[SELECT_STATE_CHANGED-C.SELECT_STATE_CHANGED]
+	_ = [1]struct{}{}[POPUP_REQUEST-C.POPUP_REQUEST]
+	_ = [1]struct{}{}[POPUP_READY-C.POPUP_READY]
+	_ = [1]struct{}{}[POPUP_DISMISSED-C.POPUP_DISMISSED]
+	_ = [1]struct{}{}[MENU_ITEM_ACTIVE-C.MENU_ITEM_ACTIVE]
+	_ = [1]struct{}{}[MENU_ITEM_CLICK-C.MENU_ITEM_CLICK]
+	_ = [1]struct{}{}[CONTEXT_MENU_SETUP-C.CONTEXT_MENU_SETUP]
+	_ = [1]struct{}{}[CONTEXT_MENU_REQUEST-C.CONTEXT_MENU_REQUEST]
+	_ = [1]struct{}{}[VISIUAL_STATUS_CHANGED-C.VISIUAL_STATUS_CHANGED]
+	_ = [1]struct{}{}[DISABLED_STATUS_CHANGED-C.DISABLED_STATUS_CHANGED]
+	_ = [1]struct{}{}[POPUP_DISMISSING-C.POPUP_DISMISSING]
+	_ = [1]struct{}{}[HYPERLINK_CLICK-C.HYPERLINK_CLICK]
+	_ = [1]struct{}{}[TABLE_HEADER_CLICK-C.TABLE_HEADER_CLICK]
+	_ = [1]struct{}{}[TABLE_ROW_CLICK-C.TABLE_ROW_CLICK]
+	_ = [1]struct{}{}[TABLE_ROW_DBL_CLICK-C.TABLE_ROW_DBL_CLICK]
+	_ = [1]struct{}{}[ELEMENT_COLLAPSED-C.ELEMENT_COLLAPSED]
+	_ = [1]struct{}{}[ELEMENT_EXPANDED-C.ELEMENT_EXPANDED]
+	_ = [1]struct{}{}[ACTIVATE_CHILD-C.ACTIVATE_CHILD]
+	_ = [1]struct{}{}[DO_SWITCH_TAB-C.DO_SWITCH_TAB]
+	_ = [1]struct{}{}[INIT_DATA_VIEW-C.INIT_DATA_VIEW]
+	_ = [1]struct{}{}[ROWS_DATA_REQUEST-C.ROWS_DATA_REQUEST]
+	_ = [1]struct{}{}[UI_STATE_CHANGED-C.UI_STATE_CHANGED]
+	_ = [1]struct{}{}[FORM_SUBMIT-C.FORM_SUBMIT]
+	_ = [1]struct{}{}[FORM_RESET-C.FORM_RESET]
+	_ = [1]struct{}{}[DOCUMENT_COMPLETE-C.DOCUMENT_COMPLETE]
+	_ = [1]struct{}{}[HISTORY_PUSH-C.HISTORY_PUSH]
+	_ = [1]struct{}{}[HISTORY_DROP-C.HISTORY_DROP]
+	_ = [1]struct{}{}[HISTORY_PRIOR-C.HISTORY_PRIOR]
+	_ = [1]struct{}{}[HISTORY_NEXT-C.HISTORY_NEXT]
+	_ = [1]struct{}{}[HISTORY_STATE_CHANGED-C.HISTORY_STATE_CHANGED]
+	_ = [1]struct{}{}[CLOSE_POPUP-C.CLOSE_POPUP]
+	_ = [1]struct{}{}[REQUEST_TOOLTIP-C.REQUEST_TOOLTIP]
+	_ = [1]struct{}{}[ANIMATION-C.ANIMATION]
+	_ = [1]struct{}{}[FIRST_APPLICATION_EVENT_CODE-C.FIRST_APPLICATION_EVENT_CODE]
+	_ = [1]struct{}{}[BY_MOUSE_CLICK-C.BY_MOUSE_CLICK]
+	_ = [1]struct{}{}[BY_KEY_CLICK-C.BY_KEY_CLICK]
+	_ = [1]struct{}{}[SYNTHESIZED-C.SYNTHESIZED]
+	_ = [1]struct{}{}[BY_INS_CHAR-C.BY_INS_CHAR]
+	_ = [1]struct{}{}[BY_INS_CHARS-C.BY_INS_CHARS]
+	_ = [1]struct{}{}[BY_DEL_CHAR-C.BY_DEL_CHAR]
+	_ = [1]struct{}{}[BY_DEL_CHARS-C.BY_DEL_CHARS]
+	_ = [1]struct{}{}[DO_CLICK-C.DO_CLICK]
+	_ = [1]struct{}{}[GET_TEXT_VALUE-C.GET_TEXT_VALUE]
+	_ = [1]struct{}{}[SET_TEXT_VALUE-C.SET_TEXT_VALUE]
+	_ = [1]struct{}{}[TEXT_EDIT_GET_SELECTION-C.TEXT_EDIT_GET_SELECTION]
+	_ = [1]struct{}{}[TEXT_EDIT_SET_SELECTION-C.TEXT_EDIT_SET_SELECTION]
+	_ = [1]struct{}{}[TEXT_EDIT_REPLACE_SELECTION-C.TEXT_EDIT_REPLACE_SELECTION]
+	_ = [1]struct{}{}[SCROLL_BAR_GET_VALUE-C.SCROLL_BAR_GET_VALUE]
+	_ = [1]struct{}{}[SCROLL_BAR_SET_VALUE-C.SCROLL_BAR_SET_VALUE]
+	_ = [1]struct{}{}[TEXT_EDIT_GET_CARET_POSITION-C.TEXT_EDIT_GET_CARET_POSITION]
+	_ = [1]struct{}{}[TEXT_EDIT_GET_SELECTION_TEXT-C.TEXT_EDIT_GET_SELECTION_TEXT]
+	_ = [1]struct{}{}[TEXT_EDIT_GET_SELECTION_HTML-C.TEXT_EDIT_GET_SELECTION_HTML]
+	_ = [1]struct{}{}[TEXT_EDIT_CHAR_POS_AT_XY-C.TEXT_EDIT_CHAR_POS_AT_XY]
+	_ = [1]struct{}{}[IS_EMPTY-C.IS_EMPTY]
+	_ = [1]struct{}{}[GET_VALUE-C.GET_VALUE]
+	_ = [1]struct{}{}[SET_VALUE-C.SET_VALUE]
+	_ = [1]struct{}{}[XCALL-C.XCALL]
+	_ = [1]struct{}{}[FIRST_APPLICATION_METHOD_ID-C.FIRST_APPLICATION_METHOD_ID]
+	_ = [1]struct{}{}[SIH_REPLACE_CONTENT-C.SIH_REPLACE_CONTENT]
+	_ = [1]struct{}{}[SIH_INSERT_AT_START-C.SIH_INSERT_AT_START]
+	_ = [1]struct{}{}[SIH_APPEND_AFTER_LAST-C.SIH_APPEND_AFTER_LAST]
+	_ = [1]struct{}{}[SOH_REPLACE-C.SOH_REPLACE]
+	_ = [1]struct{}{}[SOH_INSERT_BEFORE-C.SOH_INSERT_BEFORE]
+	_ = [1]struct{}{}[SOH_INSERT_AFTER-C.SOH_INSERT_AFTER]
+	_ = [1]struct{}{}[CONTENT_BOX-C.CONTENT_BOX]
+	_ = [1]struct{}{}[PADDING_BOX-C.PADDING_BOX]
+	_ = [1]struct{}{}[BORDER_BOX-C.BORDER_BOX]
+	_ = [1]struct{}{}[MARGIN_BOX-C.MARGIN_BOX]
+	_ = [1]struct{}{}[ROOT_RELATIVE-C.ROOT_RELATIVE]
+	_ = [1]struct{}{}[SELF_RELATIVE-C.SELF_RELATIVE]
+	_ = [1]struct{}{}[CONTAINER_RELATIVE-C.CONTAINER_RELATIVE]
+	_ = [1]struct{}{}[VIEW_RELATIVE-C.VIEW_RELATIVE]
+	_ = [1]struct{}{}[T_UNDEFINED-C.T_UNDEFINED]
+	_ = [1]struct{}{}[T_NULL-C.T_NULL]
+	_ = [1]struct{}{}[T_BOOL-C.T_BOOL]
+	_ = [1]struct{}{}[T_INT-C.T_INT]
+	_ = [1]struct{}{}[T_FLOAT-C.T_FLOAT]
+	_ = [1]struct{}{}[T_STRING-C.T_STRING]
+	_ = [1]struct{}{}[T_DATE-C.T_DATE]
+	_ = [1]struct{}{}[T_CURRENCY-C.T_CURRENCY]
+	_ = [1]struct{}{}[T_LENGTH-C.T_LENGTH]
+	_ = [1]struct{}{}[T_ARRAY-C.T_ARRAY]
+	_ = [1]struct{}{}[T_MAP-C.T_MAP]
+	_ = [1]struct{}{}[T_FUNCTION-C.T_FUNCTION]
+	_ = [1]struct{}{}[T_BYTES-C.T_BYTES]
+	_ = [1]struct{}{}[T_OBJECT-C.T_OBJECT]
+	_ = [1]struct{}{}[T_DOM_OBJECT-C.T_DOM_OBJECT]
+	_ = [1]struct{}{}[HLDOM_OK-C.HLDOM_OK]
+	_ = [1]struct{}{}[HLDOM_INVALID_HWND-C.HLDOM_INVALID_HWND]
+	_ = [1]struct{}{}[HLDOM_INVALID_HANDLE-C.HLDOM_INVALID_HANDLE]
+	_ = [1]struct{}{}[HLDOM_PASSIVE_HANDLE-C.HLDOM_PASSIVE_HANDLE]
+	_ = [1]struct{}{}[HLDOM_INVALID_PARAMETER-C.HLDOM_INVALID_PARAMETER]
+	_ = [1]struct{}{}[HLDOM_OPERATION_FAILED-C.HLDOM_OPERATION_FAILED]
+	_ = [1]struct{}{}[HLDOM_OK_NOT_HANDLED-C.HLDOM_OK_NOT_HANDLED]
+	_ = [1]struct{}{}[HV_OK-C.HV_OK]
+	_ = [1]struct{}{}[HV_BAD_PARAMETER-C.HV_BAD_PARAMETER]
+	_ = [1]struct{}{}[HV_INCOMPATIBLE_TYPE-C.HV_INCOMPATIBLE_TYPE]
+)
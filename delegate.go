@@ -0,0 +1,136 @@
+package gohl
+
+/*
+DelegatedEventHandler
+
+Handles events for every descendant of a container that matches Selector,
+using a single handler attached to the container, like jQuery's
+.on("click", "li.item", fn).  An event is passed on when its Target, or the
+nearest ancestor of the Target below the container, matches the selector, and
+the callback receives that element.  The container itself is never matched.
+The matched element is released when the callback returns; callbacks that
+keep it must wrap its handle again with NewElementFromHandle.
+
+Callbacks are only called in the bubbling phase, after the matched element's
+own handlers have had their turn.  Attach it with
+container.AttachHandler(d.EventHandler()).
+*/
+type DelegatedEventHandler struct {
+	Selector string
+
+	OnMouse         func(matched *Element, params *MouseParams) bool
+	OnKey           func(matched *Element, params *KeyParams) bool
+	OnFocus         func(matched *Element, params *FocusParams) bool
+	OnBehaviorEvent func(matched *Element, params *BehaviorEventParams) bool
+
+	// Created on first use by EventHandler and reused after that, since
+	// handlers are attached and detached by pointer
+	handler *EventHandler
+}
+
+// Finds the element that target delegates to, or BAD_HELEMENT if there is none.
+// The depth passed to SelectParent stops the search just below the container.
+// The selector is checked when the handler is attached, so a failed selection
+// here counts as no match rather than panicking in the middle of dispatch.
+func (d *DelegatedEventHandler) match(container, target HELEMENT) HELEMENT {
+	if target == BAD_HELEMENT || target == container {
+		return BAD_HELEMENT
+	}
+	depth := uint(1)
+	for he := target; ; depth++ {
+		parent, ret := dom.ParentElement(he)
+		if ret != HLDOM_OK || parent == BAD_HELEMENT {
+			// Target is not inside the container
+			return BAD_HELEMENT
+		}
+		if parent == container {
+			break
+		}
+		he = parent
+	}
+	matched, ret := dom.SelectParent(target, d.Selector, depth)
+	if ret != HLDOM_OK {
+		return BAD_HELEMENT
+	}
+	return matched
+}
+
+// Calls f with the element that target delegates to, if any, and drops the
+// reference taken for f once it returns.  Only the bubbling phase is delegated.
+func (d *DelegatedEventHandler) dispatch(container, target HELEMENT, cmd uint32, f func(matched *Element) bool) bool {
+	if cmd&SINKING != 0 {
+		return false
+	}
+	he := d.match(container, target)
+	if he == BAD_HELEMENT {
+		return false
+	}
+	matched := NewElementFromHandle(he)
+	// Release would also detach the matched element's own handlers
+	defer matched.releaseReference()
+	return f(matched)
+}
+
+// Returns the EventHandler that dispatches to the callbacks.  The same pointer
+// is returned every time, so it can be detached again later.  Its event groups
+// are brought up to date with the callbacks that are set on each call.
+func (d *DelegatedEventHandler) EventHandler() *EventHandler {
+	if d.handler == nil {
+		d.handler = &EventHandler{}
+	}
+	h := d.handler
+
+	h.OnMouse = nil
+	if d.OnMouse != nil {
+		h.OnMouse = func(he HELEMENT, params *MouseParams) bool {
+			return d.dispatch(he, params.Target, params.Cmd, func(matched *Element) bool {
+				return d.OnMouse(matched, params)
+			})
+		}
+	}
+
+	h.OnKey = nil
+	if d.OnKey != nil {
+		h.OnKey = func(he HELEMENT, params *KeyParams) bool {
+			return d.dispatch(he, params.Target, params.Cmd, func(matched *Element) bool {
+				return d.OnKey(matched, params)
+			})
+		}
+	}
+
+	h.OnFocus = nil
+	if d.OnFocus != nil {
+		h.OnFocus = func(he HELEMENT, params *FocusParams) bool {
+			return d.dispatch(he, params.Target, params.Cmd, func(matched *Element) bool {
+				return d.OnFocus(matched, params)
+			})
+		}
+	}
+
+	h.OnBehaviorEvent = nil
+	if d.OnBehaviorEvent != nil {
+		h.OnBehaviorEvent = func(he HELEMENT, params *BehaviorEventParams) bool {
+			return d.dispatch(he, params.Target, params.Cmd, func(matched *Element) bool {
+				return d.OnBehaviorEvent(matched, params)
+			})
+		}
+	}
+	return h
+}
+
+// Checks the selector, then sets it and attaches the delegated handler to the
+// container.  It is detached again with DetachHandler(d.EventHandler()).
+func (e *Element) TryDelegate(selector string, d *DelegatedEventHandler) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	if _, ret := dom.SelectParent(e.handle, selector, 1); ret != HLDOM_OK {
+		return domError(ret, "Invalid delegate selector: '", selector, "'")
+	}
+	d.Selector = selector
+	return e.TryAttachHandler(d.EventHandler())
+}
+
+func (e *Element) Delegate(selector string, d *DelegatedEventHandler) {
+	mustSucceed(e.TryDelegate(selector, d))
+}
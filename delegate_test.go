@@ -0,0 +1,179 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+const delegateHtml = `<ul id="list"><li class="item" id="one"><span id="label">one</span></li><li class="item" id="two"></li><li id="three"></li></ul><div id="outside"></div>`
+
+func TestDelegateMouse(t *testing.T) {
+	testWithMemoryHtml(delegateHtml, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		list := root.Select("#list")[0]
+		var matched []string
+		d := &DelegatedEventHandler{
+			OnMouse: func(item *Element, params *MouseParams) bool {
+				if params.Event() == MOUSE_CLICK {
+					id, _ := item.Attr("id")
+					matched = append(matched, id)
+				}
+				return false
+			},
+		}
+		list.Delegate("li.item", d)
+		defer list.DetachHandler(d.EventHandler())
+
+		click := func(target *Element) {
+			params := &MouseParams{Cmd: MOUSE_CLICK, Target: target.Handle()}
+			mem.FireEvent(target.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		}
+		click(root.Select("#label")[0])
+		click(root.Select("#two")[0])
+		click(root.Select("#three")[0])
+		click(list)
+		if len(matched) != 2 || matched[0] != "one" || matched[1] != "two" {
+			t.Fatal("Expected the matching list items to be passed to the callback, got: ", matched)
+		}
+	})
+}
+
+func TestDelegateBehaviorEvent(t *testing.T) {
+	testWithMemoryHtml(delegateHtml, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		calls := 0
+		d := &DelegatedEventHandler{
+			OnBehaviorEvent: func(item *Element, params *BehaviorEventParams) bool {
+				calls++
+				if id, _ := item.Attr("id"); id != "two" {
+					t.Fatal("Wrong element matched: ", item.Describe())
+				}
+				return true
+			},
+		}
+		root.Delegate("#two", d)
+		defer root.DetachHandler(d.EventHandler())
+
+		two := root.Select("#two")[0]
+		if !two.SendEvent(BUTTON_CLICK, two, 0) {
+			t.Fatal("Event should have been handled by the delegate")
+		}
+		if calls != 1 {
+			t.Fatal("Expected a single call in the bubbling phase, got ", calls)
+		}
+		outside := root.Select("#outside")[0]
+		if outside.SendEvent(BUTTON_CLICK, outside, 0) || calls != 1 {
+			t.Fatal("Elements that don't match should not reach the delegate")
+		}
+	})
+}
+
+func TestDelegateStopsAtContainer(t *testing.T) {
+	testWithMemoryHtml(delegateHtml, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		list := root.Select("#list")[0]
+		calls := 0
+		d := &DelegatedEventHandler{
+			OnBehaviorEvent: func(item *Element, params *BehaviorEventParams) bool {
+				calls++
+				return false
+			},
+		}
+		// The container matches the selector, but only its descendants count
+		list.Delegate("ul", d)
+		defer list.DetachHandler(d.EventHandler())
+
+		label := root.Select("#label")[0]
+		label.SendEvent(BUTTON_CLICK, label, 0)
+		if calls != 0 {
+			t.Fatal("The container and its ancestors should never be matched")
+		}
+	})
+}
+
+func TestDelegateInvalidSelector(t *testing.T) {
+	testWithMemoryHtml(delegateHtml, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		list := root.Select("#list")[0]
+		d := &DelegatedEventHandler{
+			OnMouse: func(item *Element, params *MouseParams) bool { return false },
+		}
+		err := list.TryDelegate("li[", d)
+		if de, ok := err.(*DomError); !ok || de.Result != HLDOM_INVALID_PARAMETER {
+			t.Fatal("Expected an invalid selector to be refused, got: ", err)
+		}
+		if d.Selector != "" {
+			t.Fatal("The selector should not be set when it is refused")
+		}
+	})
+}
+
+func TestDelegateReleasesMatched(t *testing.T) {
+	testWithMemoryHtml(delegateHtml, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		list := root.Select("#list")[0]
+		two := root.Select("#two")[0]
+		var kept *Element
+		d := &DelegatedEventHandler{
+			OnMouse: func(item *Element, params *MouseParams) bool {
+				kept = item
+				return false
+			},
+		}
+		list.Delegate("li.item", d)
+		defer list.DetachHandler(d.EventHandler())
+
+		before := refCount(two.Handle())
+		params := &MouseParams{Cmd: MOUSE_CLICK, Target: two.Handle()}
+		mem.FireEvent(two.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		if kept == nil {
+			t.Fatal("Expected the delegate to be called")
+		}
+		if n := refCount(two.Handle()); n != before {
+			t.Fatal("Expected the matched element to be released after the callback, ref count: ", n)
+		}
+		if kept.checkReleased() == nil {
+			t.Fatal("The matched element should be released once the callback returns")
+		}
+	})
+}
+
+func TestDelegateKeepsMatchedHandlers(t *testing.T) {
+	testWithMemoryHtml(delegateHtml, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		list := root.Select("#list")[0]
+		two := root.Select("#two")[0]
+		own := 0
+		handler := &EventHandler{
+			OnMouse: func(he HELEMENT, params *MouseParams) bool {
+				if params.Cmd == MOUSE_CLICK {
+					own++
+				}
+				return false
+			},
+		}
+		two.AttachHandler(handler)
+		defer two.DetachHandler(handler)
+		delegated := 0
+		d := &DelegatedEventHandler{
+			OnMouse: func(item *Element, params *MouseParams) bool {
+				delegated++
+				return false
+			},
+		}
+		list.Delegate("li.item", d)
+		defer list.DetachHandler(d.EventHandler())
+
+		handlers := WindowStats(hwnd).ElementHandlers
+		for i := 0; i < 2; i++ {
+			params := &MouseParams{Cmd: MOUSE_CLICK, Target: two.Handle()}
+			mem.FireEvent(two.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		}
+		if delegated != 2 || own != 2 {
+			t.Fatalf("Expected both handlers to see both clicks, delegated %d, own %d", delegated, own)
+		}
+		if n := WindowStats(hwnd).ElementHandlers; n != handlers {
+			t.Fatalf("Expected %d element handlers after delegating, got %d", handlers, n)
+		}
+	})
+}
@@ -0,0 +1,199 @@
+package gohl
+
+import (
+	"errors"
+	"sync"
+)
+
+// Returned for work handed to a Dispatcher after it has been closed, and for
+// Invoke calls whose work was still queued when it was closed
+var ErrDispatcherClosed = errors.New("dispatcher is closed")
+
+// Posted to a window to wake its message loop when a Dispatcher has work queued.
+// ProcNoDefault drains the window's dispatcher when it receives this message.
+const DISPATCHER_MESSAGE = 0x8000 + 0x0471 // WM_APP + 0x471
+
+var (
+	dispatchersMutex sync.Mutex
+	dispatchers      = make(map[HWND]*Dispatcher, 4)
+)
+
+/*
+Dispatcher
+
+HTMLayout may only be driven from the thread that runs the window's message loop.
+A Dispatcher lets other goroutines hand work to that thread: BeginInvoke queues
+a function and returns, Invoke queues it and waits for it to finish.  Queued
+functions run when the message loop drains the dispatcher.
+*/
+type Dispatcher struct {
+	owner    uint32
+	wake     func()
+	threadId func() uint32
+
+	mutex  sync.Mutex
+	queue  []dispatcherWork
+	closed bool
+}
+
+// A queued function.  Done is set for Invoke, which waits on it.
+type dispatcherWork struct {
+	f    func()
+	done chan invokeResult
+}
+
+type invokeResult struct {
+	recovered interface{}
+	err       error
+}
+
+// Creates a dispatcher for the window, owned by the calling thread, which must be
+// the thread running the window's message loop (see runtime.LockOSThread).
+// Queued work wakes the loop with DISPATCHER_MESSAGE and runs inside ProcNoDefault.
+func NewDispatcher(hwnd HWND) *Dispatcher {
+	wake := func() {
+		postDispatcherMessage(hwnd)
+	}
+	d := NewDispatcherWithPump(wake, nil)
+	dispatchersMutex.Lock()
+	dispatchers[hwnd] = d
+	dispatchersMutex.Unlock()
+	return d
+}
+
+// Creates a dispatcher that is not tied to a window.  Wake is called, from the
+// queueing goroutine, whenever work is queued, and is expected to arrange for
+// Drain to be called on the owning thread.  ThreadId identifies the calling
+// thread; nil means the OS thread id.  The caller of this function becomes
+// the owner.
+func NewDispatcherWithPump(wake func(), threadId func() uint32) *Dispatcher {
+	if threadId == nil {
+		threadId = currentThreadId
+	}
+	return &Dispatcher{
+		owner:    threadId(),
+		wake:     wake,
+		threadId: threadId,
+	}
+}
+
+// Returns the dispatcher created for the window with NewDispatcher, or nil
+func WindowDispatcher(hwnd HWND) *Dispatcher {
+	dispatchersMutex.Lock()
+	defer dispatchersMutex.Unlock()
+	return dispatchers[hwnd]
+}
+
+// Unregisters the dispatcher from its window.  Work that is still queued is
+// dropped, and the Invoke calls waiting on it fail with ErrDispatcherClosed,
+// as does any work handed to the dispatcher afterwards.
+func (d *Dispatcher) Close() {
+	dispatchersMutex.Lock()
+	for hwnd, other := range dispatchers {
+		if other == d {
+			delete(dispatchers, hwnd)
+		}
+	}
+	dispatchersMutex.Unlock()
+
+	d.mutex.Lock()
+	queue := d.queue
+	d.queue = nil
+	d.closed = true
+	d.mutex.Unlock()
+
+	for _, work := range queue {
+		if work.done != nil {
+			work.done <- invokeResult{err: ErrDispatcherClosed}
+		}
+	}
+}
+
+// Returns true if called on the thread that owns the dispatcher
+func (d *Dispatcher) OnOwnerThread() bool {
+	return d.threadId() == d.owner
+}
+
+func (d *Dispatcher) queueWork(work dispatcherWork) error {
+	d.mutex.Lock()
+	if d.closed {
+		d.mutex.Unlock()
+		return ErrDispatcherClosed
+	}
+	d.queue = append(d.queue, work)
+	d.mutex.Unlock()
+	if d.wake != nil {
+		d.wake()
+	}
+	return nil
+}
+
+// Queues f to run on the owning thread and returns immediately.  Fails with
+// ErrDispatcherClosed once the dispatcher is closed.
+func (d *Dispatcher) TryBeginInvoke(f func()) error {
+	return d.queueWork(dispatcherWork{f: f})
+}
+
+func (d *Dispatcher) BeginInvoke(f func()) {
+	mustSucceed(d.TryBeginInvoke(f))
+}
+
+// Runs f on the owning thread and waits for it to return.  When called on the
+// owning thread f runs immediately, otherwise a panic in f is raised again in
+// the caller.  Blocks until the message loop drains the dispatcher, or fails
+// with ErrDispatcherClosed if it is closed before f runs.
+func (d *Dispatcher) TryInvoke(f func()) error {
+	d.mutex.Lock()
+	closed := d.closed
+	d.mutex.Unlock()
+	if closed {
+		return ErrDispatcherClosed
+	}
+	if d.OnOwnerThread() {
+		f()
+		return nil
+	}
+	done := make(chan invokeResult, 1)
+	err := d.queueWork(dispatcherWork{f: func() {
+		defer func() {
+			done <- invokeResult{recovered: recover()}
+		}()
+		f()
+	}, done: done})
+	if err != nil {
+		return err
+	}
+	result := <-done
+	if result.recovered != nil {
+		panic(result.recovered)
+	}
+	return result.err
+}
+
+func (d *Dispatcher) Invoke(f func()) {
+	mustSucceed(d.TryInvoke(f))
+}
+
+// Runs the queued work, in the order it was queued, and returns the number of
+// functions run.  Must be called on the owning thread.  Work queued while
+// draining is left for the next call.  A panic in a queued function is passed
+// to the panic handler and does not stop the rest of the queue.  Handles of
+// collected Elements are released as well (see DrainReleaseQueue).
+func (d *Dispatcher) Drain() int {
+	defer DrainReleaseQueue()
+
+	d.mutex.Lock()
+	queue := d.queue
+	d.queue = nil
+	d.mutex.Unlock()
+
+	for _, work := range queue {
+		d.run(work.f)
+	}
+	return len(queue)
+}
+
+func (d *Dispatcher) run(f func()) {
+	defer recoverCallbackPanic(BAD_HELEMENT, 0, 0)
+	f()
+}
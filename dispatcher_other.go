@@ -0,0 +1,14 @@
+//go:build !windows
+
+package gohl
+
+// There are no windows to wake outside of Windows; dispatchers created with
+// NewDispatcher are drained by whoever calls Drain.
+func postDispatcherMessage(hwnd HWND) {}
+
+// Without a native thread id every caller counts as the same thread, so
+// dispatchers that need to tell threads apart must be given a threadId
+// function.
+func currentThreadId() uint32 {
+	return 0
+}
@@ -0,0 +1,182 @@
+package gohl
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+// Stands in for the OS thread id, so that each goroutine looks like its own thread
+func goroutineId() uint32 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	id, _ := strconv.Atoi(string(buf[:bytes.IndexByte(buf, ' ')]))
+	return uint32(id)
+}
+
+// A dispatcher owned by the calling goroutine, whose wake function
+// signals the returned channel instead of posting a window message
+func newFakePumpDispatcher() (*Dispatcher, chan bool) {
+	wakes := make(chan bool, 16)
+	d := NewDispatcherWithPump(func() { wakes <- true }, goroutineId)
+	return d, wakes
+}
+
+func TestDispatcherBeginInvoke(t *testing.T) {
+	d, wakes := newFakePumpDispatcher()
+	var order []int
+	done := make(chan bool)
+	go func() {
+		d.BeginInvoke(func() { order = append(order, 1) })
+		d.BeginInvoke(func() { order = append(order, 2) })
+		done <- true
+	}()
+	<-done
+	if len(wakes) != 2 {
+		t.Fatal("Expected the pump to be woken for each queued function")
+	}
+	if len(order) != 0 {
+		t.Fatal("Queued functions should not run until the dispatcher is drained")
+	}
+	if n := d.Drain(); n != 2 {
+		t.Fatal("Expected two functions to run, got: ", n)
+	}
+	if len(order) != 2 || order[0] != 1 || order[1] != 2 {
+		t.Fatal("Queued functions should run in order: ", order)
+	}
+	if n := d.Drain(); n != 0 {
+		t.Fatal("Queue should be empty after draining")
+	}
+}
+
+func TestDispatcherInvoke(t *testing.T) {
+	d, wakes := newFakePumpDispatcher()
+	ranOn := make(chan uint32, 1)
+	returned := make(chan bool)
+	go func() {
+		d.Invoke(func() { ranOn <- goroutineId() })
+		returned <- true
+	}()
+
+	// Play the part of the message loop
+	<-wakes
+	d.Drain()
+	<-returned
+	if thread := <-ranOn; thread != d.owner {
+		t.Fatal("Invoked function should run on the owning thread")
+	}
+}
+
+func TestDispatcherInvokeOnOwner(t *testing.T) {
+	d, wakes := newFakePumpDispatcher()
+	ran := false
+	d.Invoke(func() { ran = true })
+	if !ran || len(wakes) != 0 {
+		t.Fatal("Invoke on the owning thread should run immediately")
+	}
+}
+
+func TestDispatcherInvokePanic(t *testing.T) {
+	d, wakes := newFakePumpDispatcher()
+	recovered := make(chan interface{})
+	go func() {
+		defer func() { recovered <- recover() }()
+		d.Invoke(func() { panic("boom") })
+	}()
+	<-wakes
+	d.Drain()
+	if err := <-recovered; err != "boom" {
+		t.Fatal("Expected the panic to be raised in the caller, got: ", err)
+	}
+}
+
+func TestDispatcherDrainContainsPanic(t *testing.T) {
+	var reported *CallbackPanic
+	SetPanicHandler(func(p *CallbackPanic) { reported = p })
+	defer SetPanicHandler(nil)
+
+	d, _ := newFakePumpDispatcher()
+	ran := false
+	d.BeginInvoke(func() { panic("boom") })
+	d.BeginInvoke(func() { ran = true })
+	d.Drain()
+	if reported == nil || reported.Value != "boom" {
+		t.Fatal("Expected the panic to be reported")
+	}
+	if !ran {
+		t.Fatal("A panic should not stop the rest of the queue")
+	}
+}
+
+func TestDispatcherInvokeWhileClosing(t *testing.T) {
+	d, wakes := newFakePumpDispatcher()
+	result := make(chan error)
+	go func() {
+		result <- d.TryInvoke(func() { t.Error("Work queued before Close should not run") })
+	}()
+
+	// Close while the work is queued and the caller is waiting on it
+	<-wakes
+	d.Close()
+	if err := <-result; err != ErrDispatcherClosed {
+		t.Fatal("Expected the waiting Invoke to fail, got: ", err)
+	}
+	if n := d.Drain(); n != 0 {
+		t.Fatal("Expected the queue to be dropped, ran: ", n)
+	}
+
+	go func() {
+		result <- d.TryInvoke(func() {})
+	}()
+	if err := <-result; err != ErrDispatcherClosed {
+		t.Fatal("Expected Invoke after Close to fail, got: ", err)
+	}
+	if err := d.TryInvoke(func() {}); err != ErrDispatcherClosed {
+		t.Fatal("Expected Invoke on the owner after Close to fail, got: ", err)
+	}
+	if err := d.TryBeginInvoke(func() {}); err != ErrDispatcherClosed || len(wakes) != 0 {
+		t.Fatal("Expected BeginInvoke after Close to fail, got: ", err)
+	}
+	func() {
+		defer expectPanic()
+		d.BeginInvoke(func() {})
+	}()
+}
+
+func TestAffinityCheck(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d, wakes := newFakePumpDispatcher()
+		SetAffinityCheck(d)
+		defer SetAffinityCheck(nil)
+
+		root := RootElement(hwnd)
+		if root.ChildCount() != 1 {
+			t.Fatal("Expected the owning thread to be allowed")
+		}
+
+		recovered := make(chan interface{})
+		go func() {
+			defer func() { recovered <- recover() }()
+			root.ChildCount()
+		}()
+		if err := <-recovered; err == nil {
+			t.Fatal("Expected a panic when calling an Element method from another thread")
+		}
+
+		// The same work marshalled through the dispatcher is fine
+		count := make(chan uint, 1)
+		go func() {
+			d.Invoke(func() { count <- root.ChildCount() })
+		}()
+		<-wakes
+		d.Drain()
+		if <-count != 1 {
+			t.Fatal("Unexpected child count")
+		}
+	})
+	if _, ok := dom.(affinityBackend); ok {
+		t.Fatal("Affinity check should have been removed")
+	}
+}
@@ -0,0 +1,16 @@
+package gohl
+
+/*
+#cgo CFLAGS: -I./htmlayout/include
+
+#include <htmlayout.h>
+*/
+import "C"
+
+func postDispatcherMessage(hwnd HWND) {
+	C.PostMessageW(C.HWND(C.HANDLE(uintptr(hwnd))), DISPATCHER_MESSAGE, 0, 0)
+}
+
+func currentThreadId() uint32 {
+	return uint32(C.GetCurrentThreadId())
+}
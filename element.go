@@ -1,14 +1,5 @@
 package gohl
 
-/*
-#cgo CFLAGS: -I./htmlayout/include
-#cgo LDFLAGS: ./htmlayout/lib/HTMLayout.lib
-
-#include <stdlib.h>
-#include <htmlayout.h>
-*/
-import "C"
-
 import (
 	"fmt"
 	"errors"
@@ -22,18 +13,18 @@ import (
 )
 
 const (
-	HLDOM_OK                = C.HLDOM_OK
-	HLDOM_INVALID_HWND      = C.HLDOM_INVALID_HWND
-	HLDOM_INVALID_HANDLE    = C.HLDOM_INVALID_HANDLE
-	HLDOM_PASSIVE_HANDLE    = C.HLDOM_PASSIVE_HANDLE
-	HLDOM_INVALID_PARAMETER = C.HLDOM_INVALID_PARAMETER
-	HLDOM_OPERATION_FAILED  = C.HLDOM_OPERATION_FAILED
-	HLDOM_OK_NOT_HANDLED    = C.HLDOM_OK_NOT_HANDLED
+	HLDOM_OK                = 0
+	HLDOM_INVALID_HWND      = 1
+	HLDOM_INVALID_HANDLE    = 2
+	HLDOM_PASSIVE_HANDLE    = 3
+	HLDOM_INVALID_PARAMETER = 4
+	HLDOM_OPERATION_FAILED  = 5
+	HLDOM_OK_NOT_HANDLED    = -1
 
 	HV_OK_TRUE = 0xffffffff
-	HV_OK = C.HV_OK
-	HV_BAD_PARAMETER = C.HV_BAD_PARAMETER
-	HV_INCOMPATIBLE_TYPE = C.HV_INCOMPATIBLE_TYPE
+	HV_OK = 0
+	HV_BAD_PARAMETER = 1
+	HV_INCOMPATIBLE_TYPE = 2
 
 	STATE_LINK       = 0x00000001 // selector :link,    any element having href attribute
 	STATE_HOVER      = 0x00000002 // selector :hover,   element is under the cursor, mouse hover  
@@ -78,7 +69,7 @@ const (
 	MEASURE_DEEP     = 0x0002 // use this flag if changes of some attributes/content may cause change of dimensions of the element  
 	REDRAW_NOW       = 0x8000
 
-	BAD_HELEMENT = HELEMENT(unsafe.Pointer(uintptr(0)))
+	BAD_HELEMENT = HELEMENT(0)
 )
 
 var errorToString = map[HLDOM_RESULT]string{
@@ -112,12 +103,72 @@ func (e *DomError) Error() string {
 	return fmt.Sprintf("%s: %s", errorToString[e.Result], e.Message)
 }
 
+// Reports whether target is a DomError with the same result code, which lets
+// errors.Is match a DomError against the sentinel values below.
+func (e *DomError) Is(target error) bool {
+	t, ok := target.(*DomError)
+	return ok && t.Result == e.Result
+}
+
+// Sentinel errors for use with errors.Is.  They compare equal to any
+// DomError carrying the same result code.
+var (
+	ErrInvalidHwnd      = &DomError{HLDOM_INVALID_HWND, "invalid window handle"}
+	ErrInvalidHandle    = &DomError{HLDOM_INVALID_HANDLE, "invalid element handle"}
+	ErrPassiveHandle    = &DomError{HLDOM_PASSIVE_HANDLE, "element is not attached to a dom"}
+	ErrInvalidParameter = &DomError{HLDOM_INVALID_PARAMETER, "invalid parameter"}
+	ErrOperationFailed  = &DomError{HLDOM_OPERATION_FAILED, "operation failed"}
+	ErrNotHandled       = &DomError{HLDOM_OK_NOT_HANDLED, "not handled"}
+)
+
+// Returned by the methods of an Element after it has been released or deleted.
+// Element holds Describe() of the element from just before that happened.
+type ReleasedError struct {
+	Element string
+	Deleted bool
+}
+
+func (e *ReleasedError) Error() string {
+	what := "element"
+	if e.Element != "" {
+		what += " " + e.Element
+	}
+	if e.Deleted {
+		return what + " has been deleted"
+	}
+	return what + " has been released"
+}
+
+// Any ReleasedError matches ErrReleased
+func (e *ReleasedError) Is(target error) bool {
+	_, ok := target.(*ReleasedError)
+	return ok
+}
+
+// Sentinel for use with errors.Is, see ReleasedError
+var ErrReleased = &ReleasedError{}
+
 func domResultAsString(result HLDOM_RESULT) string {
 	return errorToString[result]
 }
 
-func domPanic(result C.HLDOM_RESULT, message ...interface{}) {
-	panic(&DomError{HLDOM_RESULT(result), fmt.Sprint(message...)})
+// Returns nil if result is HLDOM_OK, otherwise a *DomError
+func domError(result HLDOM_RESULT, message ...interface{}) error {
+	if result == HLDOM_OK {
+		return nil
+	}
+	return &DomError{result, fmt.Sprint(message...)}
+}
+
+func domPanic(result HLDOM_RESULT, message ...interface{}) {
+	panic(&DomError{result, fmt.Sprint(message...)})
+}
+
+// Used by the panicking Element methods to re-raise the error from their Try counterpart
+func mustSucceed(err error) {
+	if err != nil {
+		panic(err)
+	}
 }
 
 
@@ -130,8 +181,8 @@ func (e *ValueError) Error() string {
 	return fmt.Sprintf("%s: %s", valueErrorToString[e.Result], e.Message)
 }
 
-func valuePanic(result C.UINT, message ...interface{}) {
-	panic(&ValueError{VALUE_RESULT(result), fmt.Sprint(message...)})
+func valuePanic(result VALUE_RESULT, message ...interface{}) {
+	panic(&ValueError{result, fmt.Sprint(message...)})
 }
 
 
@@ -149,14 +200,13 @@ func utf16ToString(s *uint16) string {
 		panic("null cstring")
 	}
 	us := make([]uint16, 0, 256)
-	for p := uintptr(unsafe.Pointer(s)); ; p += 2 {
-		u := *(*uint16)(unsafe.Pointer(p))
+	for i := uintptr(0); ; i += 2 {
+		u := *(*uint16)(unsafe.Pointer(uintptr(unsafe.Pointer(s)) + i))
 		if u == 0 {
 			return string(utf16.Decode(us))
 		}
 		us = append(us, u)
 	}
-	return ""
 }
 
 func utf16ToStringLength(s *uint16, length int) string {
@@ -164,13 +214,28 @@ func utf16ToStringLength(s *uint16, length int) string {
 		panic("null cstring")
 	}
 	us := make([]uint16, 0, 256)
-	for p, i := uintptr(unsafe.Pointer(s)), 0; i < length; p, i = p+2, i+1 {
-		u := *(*uint16)(unsafe.Pointer(p))
+	for i := 0; i < length; i++ {
+		u := *(*uint16)(unsafe.Pointer(uintptr(unsafe.Pointer(s)) + uintptr(i)*2))
 		us = append(us, u)
 	}
 	return string(utf16.Decode(us))
 }
 
+// Copies a null terminated utf8 string
+func cStringToString(s *byte) string {
+	if s == nil {
+		return ""
+	}
+	bytes := make([]byte, 0, 32)
+	for i := uintptr(0); ; i++ {
+		b := *(*byte)(unsafe.Pointer(uintptr(unsafe.Pointer(s)) + i))
+		if b == 0 {
+			return string(bytes)
+		}
+		bytes = append(bytes, b)
+	}
+}
+
 // Returns pointer to the utf-16 encoding of
 // the utf-8 string s, with a terminating NUL added.
 func stringToUtf16Ptr(s string) *uint16 {
@@ -178,14 +243,14 @@ func stringToUtf16Ptr(s string) *uint16 {
 }
 
 func use(handle HELEMENT) {
-	if dr := C.HTMLayout_UseElement(handle); dr != HLDOM_OK {
+	if dr := dom.UseElement(handle); dr != HLDOM_OK {
 		domPanic(dr, "UseElement")
 	}
 }
 
 func unuse(handle HELEMENT) {
-	if handle != nil {
-		if dr := C.HTMLayout_UnuseElement(handle); dr != HLDOM_OK {
+	if handle != BAD_HELEMENT {
+		if dr := dom.UnuseElement(handle); dr != HLDOM_OK {
 			domPanic(dr, "UnuseElement")
 		}
 	}
@@ -198,6 +263,13 @@ Represents a single DOM element, owns and manages a Handle
 */
 type Element struct {
 	handle HELEMENT
+
+	// The backend the handle came from.  Kept so that the finalizer never
+	// has to read the current backend from the runtime's goroutine.
+	backend Backend
+
+	// Set by Release and Delete, after which every method fails with it
+	released *ReleasedError
 }
 
 // Constructors
@@ -205,65 +277,107 @@ func NewElementFromHandle(h HELEMENT) *Element {
 	if h == BAD_HELEMENT {
 		panic("Nil helement")
 	}
-	e := &Element{BAD_HELEMENT}
+	e := &Element{handle: BAD_HELEMENT, backend: backend}
 	e.setHandle(h)
-	runtime.SetFinalizer(e, (*Element).finalize)
+	runtime.SetFinalizer(e, (*Element).enqueueRelease)
+	trackElement(e)
 	return e
 }
 
-func NewElement(tagName string) *Element {
-	var handle HELEMENT = BAD_HELEMENT
-	szName := C.CString(tagName)
-	defer C.free(unsafe.Pointer(szName))
-	if ret := C.HTMLayoutCreateElement((*C.CHAR)(szName), nil, (*C.HELEMENT)(&handle)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to create new element")
+func TryNewElement(tagName string) (*Element, error) {
+	handle, ret := dom.CreateElement(tagName)
+	if ret != HLDOM_OK {
+		return nil, domError(ret, "Failed to create new element")
 	}
-	return NewElementFromHandle(handle)
+	return NewElementFromHandle(handle), nil
+}
+
+func NewElement(tagName string) *Element {
+	e, err := TryNewElement(tagName)
+	mustSucceed(err)
+	return e
 }
 
-func RootElement(hwnd uint32) *Element {
-	var handle HELEMENT = BAD_HELEMENT
-	if ret := C.HTMLayoutGetRootElement(C.HWND(C.HANDLE(uintptr(hwnd))), (*C.HELEMENT)(&handle)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get root element")
+func TryRootElement(hwnd HWND) (*Element, error) {
+	handle, ret := dom.RootElement(hwnd)
+	if ret != HLDOM_OK {
+		return nil, domError(ret, "Failed to get root element")
 	}
-	return NewElementFromHandle(handle)
+	return NewElementFromHandle(handle), nil
+}
+
+func RootElement(hwnd HWND) *Element {
+	e, err := TryRootElement(hwnd)
+	mustSucceed(err)
+	return e
 }
 
-func FocusedElement(hwnd uint32) *Element {
-	var handle HELEMENT = BAD_HELEMENT
-	if ret := C.HTMLayoutGetFocusElement(C.HWND(C.HANDLE(uintptr(hwnd))), (*C.HELEMENT)(&handle)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get focus element")
+// Returns nil if no element has the focus
+func TryFocusedElement(hwnd HWND) (*Element, error) {
+	handle, ret := dom.FocusElement(hwnd)
+	if ret != HLDOM_OK {
+		return nil, domError(ret, "Failed to get focus element")
 	}
 	if handle != BAD_HELEMENT {
-		return NewElementFromHandle(handle)
+		return NewElementFromHandle(handle), nil
 	}
-	return nil
+	return nil, nil
 }
 
-// Finalizer method, only to be called from Release or by
-// the Go runtime
-func (e *Element) finalize() {
-	// Detach handlers
-	if attachedHandlers, hasHandlers := eventHandlers[e.handle]; hasHandlers {
-		for handler := range attachedHandlers {
-			tag := uintptr(unsafe.Pointer(handler))
-			C.HTMLayoutDetachEventHandler(e.handle, (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag))
-		}
-		delete(eventHandlers, e.handle)
-	}
+func FocusedElement(hwnd HWND) *Element {
+	e, err := TryFocusedElement(hwnd)
+	mustSucceed(err)
+	return e
+}
 
-	// Release the underlying htmlayout handle
-	unuse(e.handle)
+// Releases the handle immediately, only to be called from Release or Delete.
+// Elements collected by the Go runtime go through enqueueRelease instead.
+func (e *Element) finalize() {
+	untrackElement(e)
+	releaseHandle(e.backend, e.handle)
 	e.handle = BAD_HELEMENT
 }
 
 func (e *Element) Release() {
+	if e.released != nil {
+		return
+	}
+	description, _ := e.TryDescribe()
+	e.released = &ReleasedError{description, false}
+
 	// Unregister the finalizer so that it does not get called by Go
 	// and then explicitly finalize this element
 	runtime.SetFinalizer(e, nil)
 	e.finalize()
 }
 
+// Like Release, but only drops this Element's reference to the handle.  The
+// handlers attached to the element are left alone, for when something other
+// than the element's owner is letting go of it.
+func (e *Element) releaseReference() {
+	if e.released != nil {
+		return
+	}
+	description, _ := e.TryDescribe()
+	e.released = &ReleasedError{description, false}
+	runtime.SetFinalizer(e, nil)
+	untrackElement(e)
+	if e.handle != BAD_HELEMENT {
+		if dr := e.backend.UnuseElement(e.handle); dr != HLDOM_OK {
+			domPanic(dr, "UnuseElement")
+		}
+	}
+	e.handle = BAD_HELEMENT
+}
+
+// Returns a *ReleasedError once the element has been released or deleted
+func (e *Element) checkReleased() error {
+	if e.released != nil {
+		return e.released
+	}
+	return nil
+}
+
 func (e *Element) setHandle(h HELEMENT) {
 	use(h)
 	unuse(e.handle)
@@ -278,30 +392,25 @@ func (e *Element) Equals(other *Element) bool {
 	return other != nil && e.handle == other.handle
 }
 
-// This is the same as AttachHandler, except that behaviors are singleton instances stored
-// in a master map.  They may be shared among many elements since they have no state.
-// The only reason we keep a separate set of the behaviors is so that the event handler
-// dispatch method can tell if an event handler is a behavior or a regular handler.
+// This is the same as AttachHandler, except that the handler is not tracked as one of
+// the element's handlers.  The caller counts it in the window registry's behaviors
+// instead, which keeps it alive until BEHAVIOR_DETACH drops the count again.  The
+// handler is either shared, from a NotifyHandler's Behaviors, or belongs to an
+// instance a factory created for this element alone, carrying the element's behavior
+// args; see attachBehaviorInstance.
 func (e *Element) attachBehavior(handler *EventHandler) {
-	tag := uintptr(unsafe.Pointer(handler))
-	if subscription := handler.Subscription(); subscription == HANDLE_ALL {
-		if ret := C.HTMLayoutAttachEventHandler(e.handle, (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag)); ret != HLDOM_OK {
-			domPanic(ret, "Failed to attach event handler to element")
-		}
-	} else {
-		if ret := C.HTMLayoutAttachEventHandlerEx(e.handle, (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag), C.UINT(subscription)); ret != HLDOM_OK {
-			domPanic(ret, "Failed to attach event handler to element")
-		}
+	if ret := dom.AttachEventHandler(e.handle, handler, handler.Subscription()); ret != HLDOM_OK {
+		domPanic(ret, "Failed to attach event handler to element")
 	}
 }
 
-func (e *Element) AttachHandler(handler *EventHandler) {
-	attachedHandlers, hasAttachments := eventHandlers[e.handle]
-	if hasAttachments {
-		if _, exists := attachedHandlers[handler]; exists {
-			// This exact event handler is already attached to this exact element.
-			return
-		}
+func (e *Element) TryAttachHandler(handler *EventHandler) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	if findElementHandler(e.handle, handler) != nil {
+		// This exact event handler is already attached to this exact element.
+		return nil
 	}
 
 	// Don't let the caller disable ATTACH/DETACH events, otherwise we
@@ -309,41 +418,42 @@ func (e *Element) AttachHandler(handler *EventHandler) {
 	subscription := handler.Subscription()
 	subscription &= ^uint32(DISABLE_INITIALIZATION & 0xffffffff)
 
-	tag := uintptr(unsafe.Pointer(handler))
-	if subscription == HANDLE_ALL {
-		if ret := C.HTMLayoutAttachEventHandler(e.handle, (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag)); ret != HLDOM_OK {
-			domPanic(ret, "Failed to attach event handler to element")
-		}
-	} else {
-		if ret := C.HTMLayoutAttachEventHandlerEx(e.handle, (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag), C.UINT(subscription)); ret != HLDOM_OK {
-			domPanic(ret, "Failed to attach event handler to element")
-		}
+	if ret := dom.AttachEventHandler(e.handle, handler, subscription); ret != HLDOM_OK {
+		return domError(ret, "Failed to attach event handler to element")
 	}
 
-	if !hasAttachments {
-		eventHandlers[e.handle] = make(map[*EventHandler]bool, 8)
-	}
-	eventHandlers[e.handle][handler] = true
+	elementRegistry(e.handle, true).addElementHandler(e.handle, handler)
+	trackHandler(e.handle, handler)
+	return nil
 }
 
-func (e *Element) DetachHandler(handler *EventHandler) {
-	tag := uintptr(unsafe.Pointer(handler))
-	if attachedHandlers, exists := eventHandlers[e.handle]; exists {
-		if _, exists := attachedHandlers[handler]; exists {
-			if ret := C.HTMLayoutDetachEventHandler(e.handle, (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag)); ret != HLDOM_OK {
-				domPanic(ret, "Failed to detach event handler from element")
-			}
-			delete(attachedHandlers, handler)
-			if len(attachedHandlers) == 0 {
-				delete(eventHandlers, e.handle)
-			}
-			return
+func (e *Element) AttachHandler(handler *EventHandler) {
+	mustSucceed(e.TryAttachHandler(handler))
+}
+
+func (e *Element) TryDetachHandler(handler *EventHandler) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	if registry := findElementHandler(e.handle, handler); registry != nil {
+		if ret := dom.DetachEventHandler(e.handle, handler); ret != HLDOM_OK {
+			return domError(ret, "Failed to detach event handler from element")
 		}
+		registry.removeElementHandler(e.handle, handler)
+		untrackHandler(e.handle, handler)
+		return nil
 	}
-	panic("cannot detach, handler was not registered")
+	return errors.New("cannot detach, handler was not registered")
 }
 
-func (e *Element) Update(restyle, restyleDeep, remeasure, remeasureDeep, render bool) {
+func (e *Element) DetachHandler(handler *EventHandler) {
+	mustSucceed(e.TryDetachHandler(handler))
+}
+
+func (e *Element) TryUpdate(restyle, restyleDeep, remeasure, remeasureDeep, render bool) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
 	var flags uint32
 	if restyle {
 		if restyleDeep {
@@ -362,32 +472,58 @@ func (e *Element) Update(restyle, restyleDeep, remeasure, remeasureDeep, render
 	if render {
 		flags |= REDRAW_NOW
 	}
-	if ret := C.HTMLayoutUpdateElementEx(e.handle, C.UINT(flags)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to update element")
+	return domError(dom.UpdateElement(e.handle, flags), "Failed to update element")
+}
+
+func (e *Element) Update(restyle, restyleDeep, remeasure, remeasureDeep, render bool) {
+	mustSucceed(e.TryUpdate(restyle, restyleDeep, remeasure, remeasureDeep, render))
+}
+
+func (e *Element) TryCapture() error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.SetCapture(e.handle), "Failed to set capture for element")
 }
 
 func (e *Element) Capture() {
-	if ret := C.HTMLayoutSetCapture(e.handle); ret != HLDOM_OK {
-		domPanic(ret, "Failed to set capture for element")
+	mustSucceed(e.TryCapture())
+}
+
+func (e *Element) TryReleaseCapture() error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	if ok := dom.ReleaseCapture(); !ok {
+		return errors.New("Failed to release capture for element")
+	}
+	return nil
 }
 
 func (e *Element) ReleaseCapture() {
-	if ok := C.ReleaseCapture(); ok == 0 {
-		panic("Failed to release capture for element")
-	}
+	mustSucceed(e.TryReleaseCapture())
 }
 
 // Functions for querying elements
 
-func (e *Element) Select(selector string) []*Element {
-	szSelector := C.CString(selector)
-	defer C.free(unsafe.Pointer(szSelector))
+func (e *Element) TrySelect(selector string) ([]*Element, error) {
+	if err := e.checkReleased(); err != nil {
+		return nil, err
+	}
 	results := make([]*Element, 0, 32)
-	if ret := C.HTMLayoutSelectElements(e.handle, (*C.CHAR)(szSelector), (*[0]byte)(unsafe.Pointer(goSelectCallback)), C.LPVOID(unsafe.Pointer(&results))); ret != HLDOM_OK {
-		domPanic(ret, "Failed to select dom elements, selector: '", selector, "'")
+	collect := func(he HELEMENT) bool {
+		results = append(results, NewElementFromHandle(he))
+		return false
+	}
+	if ret := dom.SelectElements(e.handle, selector, collect); ret != HLDOM_OK {
+		return nil, domError(ret, "Failed to select dom elements, selector: '", selector, "'")
 	}
+	return results, nil
+}
+
+func (e *Element) Select(selector string) []*Element {
+	results, err := e.TrySelect(selector)
+	mustSucceed(err)
 	return results
 }
 
@@ -395,17 +531,28 @@ func (e *Element) Select(selector string) []*Element {
 // Includes the element in the search.  Depth indicates how far the search should progress.
 // Depth = 1 means only consider this element.  Depth = 0 means search all the way up to the
 // root.  Any other positive value of depth limits the length of the search.
-func (e *Element) SelectParentLimit(selector string, depth int) *Element {
-	szSelector := C.CString(selector)
-	defer C.free(unsafe.Pointer(szSelector))
-	var parent C.HELEMENT
-	if ret := C.HTMLayoutSelectParent(e.handle, (*C.CHAR)(szSelector), C.UINT(depth), &parent); ret != HLDOM_OK {
-		domPanic(ret, "Failed to select parent dom elements, selector: '", selector, "'")
+func (e *Element) TrySelectParentLimit(selector string, depth int) (*Element, error) {
+	if err := e.checkReleased(); err != nil {
+		return nil, err
 	}
-	if parent != nil {
-		return NewElementFromHandle(HELEMENT(parent))
+	parent, ret := dom.SelectParent(e.handle, selector, uint(depth))
+	if ret != HLDOM_OK {
+		return nil, domError(ret, "Failed to select parent dom elements, selector: '", selector, "'")
 	}
-	return nil
+	if parent != BAD_HELEMENT {
+		return NewElementFromHandle(parent), nil
+	}
+	return nil, nil
+}
+
+func (e *Element) SelectParentLimit(selector string, depth int) *Element {
+	parent, err := e.TrySelectParentLimit(selector, depth)
+	mustSucceed(err)
+	return parent
+}
+
+func (e *Element) TrySelectParent(selector string) (*Element, error) {
+	return e.TrySelectParentLimit(selector, 0)
 }
 
 func (e *Element) SelectParent(selector string) *Element {
@@ -414,213 +561,424 @@ func (e *Element) SelectParent(selector string) *Element {
 
 // For delivering programmatic events to this element.
 // Returns true if the event was handled, false otherwise
-func (e *Element) SendEvent(eventCode uint, source *Element, reason uint32) bool {
-	var handled C.BOOL = 0
-	if ret := C.HTMLayoutSendEvent(e.handle, C.UINT(eventCode), source.handle, C.UINT_PTR(reason), &handled); ret != HLDOM_OK {
-		domPanic(ret, "Failed to send event")
+func (e *Element) TrySendEvent(eventCode uint, source *Element, reason uint32) (bool, error) {
+	if err := e.checkReleased(); err != nil {
+		return false, err
+	}
+	if err := source.checkReleased(); err != nil {
+		return false, err
+	}
+	handled, ret := dom.SendEvent(e.handle, eventCode, source.handle, uintptr(reason))
+	if ret != HLDOM_OK {
+		return false, domError(ret, "Failed to send event")
 	}
-	return handled != 0
+	return handled, nil
+}
+
+func (e *Element) SendEvent(eventCode uint, source *Element, reason uint32) bool {
+	handled, err := e.TrySendEvent(eventCode, source, reason)
+	mustSucceed(err)
+	return handled
 }
 
 // For asynchronously delivering programmatic events to this element.
-func (e *Element) PostEvent(eventCode uint, source *Element, reason uint32) {
-	if ret := C.HTMLayoutPostEvent(e.handle, C.UINT(eventCode), source.handle, C.UINT(reason)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to post event")
+func (e *Element) TryPostEvent(eventCode uint, source *Element, reason uint32) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	if err := source.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.PostEvent(e.handle, eventCode, source.handle, reason), "Failed to post event")
+}
+
+func (e *Element) PostEvent(eventCode uint, source *Element, reason uint32) {
+	mustSucceed(e.TryPostEvent(eventCode, source, reason))
 }
 
 //
 // DOM structure accessors/modifiers:
 //
 
+func (e *Element) TryChildCount() (uint, error) {
+	if err := e.checkReleased(); err != nil {
+		return 0, err
+	}
+	count, ret := dom.ChildrenCount(e.handle)
+	if ret != HLDOM_OK {
+		return 0, domError(ret, "Failed to get child count")
+	}
+	return count, nil
+}
+
 func (e *Element) ChildCount() uint {
-	var count C.UINT
-	if ret := C.HTMLayoutGetChildrenCount(e.handle, &count); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get child count")
+	count, err := e.TryChildCount()
+	mustSucceed(err)
+	return count
+}
+
+func (e *Element) TryChild(index uint) (*Element, error) {
+	if err := e.checkReleased(); err != nil {
+		return nil, err
+	}
+	child, ret := dom.NthChild(e.handle, index)
+	if ret != HLDOM_OK {
+		return nil, domError(ret, "Failed to get child at index: ", index)
 	}
-	return uint(count)
+	return NewElementFromHandle(child), nil
 }
 
 func (e *Element) Child(index uint) *Element {
-	var child C.HELEMENT
-	if ret := C.HTMLayoutGetNthChild(e.handle, C.UINT(index), &child); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get child at index: ", index)
+	child, err := e.TryChild(index)
+	mustSucceed(err)
+	return child
+}
+
+func (e *Element) TryChildren() ([]*Element, error) {
+	count, err := e.TryChildCount()
+	if err != nil {
+		return nil, err
+	}
+	slice := make([]*Element, 0, count)
+	for i := uint(0); i < count; i++ {
+		child, err := e.TryChild(i)
+		if err != nil {
+			return nil, err
+		}
+		slice = append(slice, child)
 	}
-	return NewElementFromHandle(HELEMENT(child))
+	return slice, nil
 }
 
 func (e *Element) Children() []*Element {
-	slice := make([]*Element, 0, 32)
-	for i := uint(0); i < e.ChildCount(); i++ {
-		slice = append(slice, e.Child(i))
+	children, err := e.TryChildren()
+	mustSucceed(err)
+	return children
+}
+
+func (e *Element) TryIndex() (uint, error) {
+	if err := e.checkReleased(); err != nil {
+		return 0, err
 	}
-	return slice
+	index, ret := dom.ElementIndex(e.handle)
+	if ret != HLDOM_OK {
+		return 0, domError(ret, "Failed to get element's index")
+	}
+	return index, nil
 }
 
 func (e *Element) Index() uint {
-	var index C.UINT
-	if ret := C.HTMLayoutGetElementIndex(e.handle, &index); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get element's index")
+	index, err := e.TryIndex()
+	mustSucceed(err)
+	return index
+}
+
+// Returns nil for the root element
+func (e *Element) TryParent() (*Element, error) {
+	if err := e.checkReleased(); err != nil {
+		return nil, err
+	}
+	parent, ret := dom.ParentElement(e.handle)
+	if ret != HLDOM_OK {
+		return nil, domError(ret, "Failed to get parent")
 	}
-	return uint(index)
+	if parent != BAD_HELEMENT {
+		return NewElementFromHandle(parent), nil
+	}
+	return nil, nil
 }
 
 func (e *Element) Parent() *Element {
-	var parent C.HELEMENT
-	if ret := C.HTMLayoutGetParentElement(e.handle, &parent); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get parent")
+	parent, err := e.TryParent()
+	mustSucceed(err)
+	return parent
+}
+
+func (e *Element) TryInsertChild(child *Element, index uint) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
-	if parent != nil {
-		return NewElementFromHandle(HELEMENT(parent))
+	if err := child.checkReleased(); err != nil {
+		return err
 	}
-	return nil
+	return domError(dom.InsertElement(child.handle, e.handle, index), "Failed to insert child element at index: ", index)
 }
 
 func (e *Element) InsertChild(child *Element, index uint) {
-	if ret := C.HTMLayoutInsertElement(child.handle, e.handle, C.UINT(index)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to insert child element at index: ", index)
+	mustSucceed(e.TryInsertChild(child, index))
+}
+
+func (e *Element) TryAppendChild(child *Element) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	if err := child.checkReleased(); err != nil {
+		return err
 	}
+	count, err := e.TryChildCount()
+	if err != nil {
+		return err
+	}
+	return domError(dom.InsertElement(child.handle, e.handle, count), "Failed to append child element")
 }
 
 func (e *Element) AppendChild(child *Element) {
-	count := e.ChildCount()
-	if ret := C.HTMLayoutInsertElement(child.handle, e.handle, C.UINT(count)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to append child element")
+	mustSucceed(e.TryAppendChild(child))
+}
+
+func (e *Element) TryDetach() error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.DetachElement(e.handle), "Failed to detach element from dom")
 }
 
 func (e *Element) Detach() {
-	if ret := C.HTMLayoutDetachElement(e.handle); ret != HLDOM_OK {
-		domPanic(ret, "Failed to detach element from dom")
-	}
+	mustSucceed(e.TryDetach())
 }
 
-func (e *Element) Delete() {
-	if ret := C.HTMLayoutDeleteElement(e.handle); ret != HLDOM_OK {
-		domPanic(ret, "Failed to delete element from dom")
+func (e *Element) TryDelete() error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	description, _ := e.TryDescribe()
+	if ret := dom.DeleteElement(e.handle); ret != HLDOM_OK {
+		return domError(ret, "Failed to delete element from dom")
 	}
+	e.released = &ReleasedError{description, true}
 	e.finalize()
+	return nil
+}
+
+func (e *Element) Delete() {
+	mustSucceed(e.TryDelete())
 }
 
 // Makes a deep clone of the receiver, the resulting subtree is not attached to the dom.
+func (e *Element) TryClone() (*Element, error) {
+	if err := e.checkReleased(); err != nil {
+		return nil, err
+	}
+	clone, ret := dom.CloneElement(e.handle)
+	if ret != HLDOM_OK {
+		return nil, domError(ret, "Failed to clone element")
+	}
+	return NewElementFromHandle(clone), nil
+}
+
 func (e *Element) Clone() *Element {
-	var clone C.HELEMENT
-	if ret := C.HTMLayoutCloneElement(e.handle, &clone); ret != HLDOM_OK {
-		domPanic(ret, "Failed to clone element")
+	clone, err := e.TryClone()
+	mustSucceed(err)
+	return clone
+}
+
+func (e *Element) TrySwap(other *Element) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	if err := other.checkReleased(); err != nil {
+		return err
 	}
-	return NewElementFromHandle(HELEMENT(clone))
+	return domError(dom.SwapElements(e.handle, other.handle), "Failed to swap elements")
 }
 
 func (e *Element) Swap(other *Element) {
-	if ret := C.HTMLayoutSwapElements(e.handle, other.handle); ret != HLDOM_OK {
-		domPanic(ret, "Failed to swap elements")
-	}
+	mustSucceed(e.TrySwap(other))
 }
 
 // Sorts 'count' child elements starting at index 'start'.  Uses comparator to define the
 // order.  Comparator should return -1, or 0, or 1 to indicate less, equal or greater
-func (e *Element) SortChildrenRange(start, count uint, comparator func(*Element, *Element) int) {
+func (e *Element) TrySortChildrenRange(start, count uint, comparator func(*Element, *Element) int) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
 	end := start + count
-	arg := uintptr(unsafe.Pointer(&comparator))
-	if ret := C.HTMLayoutSortElements(e.handle, C.UINT(start), C.UINT(end), (*[0]byte)(unsafe.Pointer(goElementComparator)), C.LPVOID(arg)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to sort elements")
+	cmp := func(he1, he2 HELEMENT) int {
+		return comparator(NewElementFromHandle(he1), NewElementFromHandle(he2))
+	}
+	return domError(dom.SortElements(e.handle, start, end, cmp), "Failed to sort elements")
+}
+
+func (e *Element) SortChildrenRange(start, count uint, comparator func(*Element, *Element) int) {
+	mustSucceed(e.TrySortChildrenRange(start, count, comparator))
+}
+
+func (e *Element) TrySortChildren(comparator func(*Element, *Element) int) error {
+	count, err := e.TryChildCount()
+	if err != nil {
+		return err
 	}
+	return e.TrySortChildrenRange(0, count, comparator)
 }
 
 func (e *Element) SortChildren(comparator func(*Element, *Element) int) {
-	e.SortChildrenRange(0, e.ChildCount(), comparator)
+	mustSucceed(e.TrySortChildren(comparator))
 }
 
-func (e *Element) SetTimer(ms int) {
-	if ret := C.HTMLayoutSetTimer(e.handle, C.UINT(ms)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to set timer")
+func (e *Element) TrySetTimer(ms int) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.SetTimer(e.handle, uint(ms)), "Failed to set timer")
+}
+
+func (e *Element) SetTimer(ms int) {
+	mustSucceed(e.TrySetTimer(ms))
+}
+
+func (e *Element) TryCancelTimer() error {
+	return e.TrySetTimer(0)
 }
 
 func (e *Element) CancelTimer() {
 	e.SetTimer(0)
 }
 
-func (e *Element) Hwnd() uint32 {
-	var hwnd uint32
-	if ret := C.HTMLayoutGetElementHwnd(e.handle, (*C.HWND)(unsafe.Pointer(&hwnd)), 0); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get element's hwnd")
+func (e *Element) TryHwnd() (HWND, error) {
+	if err := e.checkReleased(); err != nil {
+		return 0, err
 	}
+	hwnd, ret := dom.ElementHwnd(e.handle, false)
+	if ret != HLDOM_OK {
+		return 0, domError(ret, "Failed to get element's hwnd")
+	}
+	return hwnd, nil
+}
+
+func (e *Element) Hwnd() HWND {
+	hwnd, err := e.TryHwnd()
+	mustSucceed(err)
 	return hwnd
 }
 
-func (e *Element) RootHwnd() uint32 {
-	var hwnd uint32
-	if ret := C.HTMLayoutGetElementHwnd(e.handle, (*C.HWND)(unsafe.Pointer(&hwnd)), 1); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get element's root hwnd")
+func (e *Element) TryRootHwnd() (HWND, error) {
+	if err := e.checkReleased(); err != nil {
+		return 0, err
+	}
+	hwnd, ret := dom.ElementHwnd(e.handle, true)
+	if ret != HLDOM_OK {
+		return 0, domError(ret, "Failed to get element's root hwnd")
 	}
+	return hwnd, nil
+}
+
+func (e *Element) RootHwnd() HWND {
+	hwnd, err := e.TryRootHwnd()
+	mustSucceed(err)
 	return hwnd
 }
 
+func (e *Element) TryHtml() (string, error) {
+	if err := e.checkReleased(); err != nil {
+		return "", err
+	}
+	html, ret := dom.ElementHtml(e.handle, false)
+	if ret != HLDOM_OK {
+		return "", domError(ret, "Failed to get inner html")
+	}
+	return html, nil
+}
+
 func (e *Element) Html() string {
-	var data *C.char
-	if ret := C.HTMLayoutGetElementHtml(e.handle, (*C.LPBYTE)(unsafe.Pointer(&data)), C.BOOL(0)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get inner html")
+	html, err := e.TryHtml()
+	mustSucceed(err)
+	return html
+}
+
+func (e *Element) TryOuterHtml() (string, error) {
+	if err := e.checkReleased(); err != nil {
+		return "", err
 	}
-	return C.GoString(data)
+	html, ret := dom.ElementHtml(e.handle, true)
+	if ret != HLDOM_OK {
+		return "", domError(ret, "Failed to get outer html")
+	}
+	return html, nil
 }
 
 func (e *Element) OuterHtml() string {
-	var data *C.char
-	if ret := C.HTMLayoutGetElementHtml(e.handle, (*C.LPBYTE)(unsafe.Pointer(&data)), C.BOOL(1)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get outer html")
+	html, err := e.TryOuterHtml()
+	mustSucceed(err)
+	return html
+}
+
+func (e *Element) TryType() (string, error) {
+	if err := e.checkReleased(); err != nil {
+		return "", err
 	}
-	return C.GoString(data)
+	tagName, ret := dom.ElementType(e.handle)
+	if ret != HLDOM_OK {
+		return "", domError(ret, "Failed to get element type")
+	}
+	return tagName, nil
 }
 
 func (e *Element) Type() string {
-	var data *C.char
-	if ret := C.HTMLayoutGetElementType(e.handle, (*C.LPCSTR)(unsafe.Pointer(&data))); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get element type")
+	tagName, err := e.TryType()
+	mustSucceed(err)
+	return tagName
+}
+
+func (e *Element) TrySetHtml(html string) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
-	return C.GoString(data)
+	return domError(dom.SetElementHtml(e.handle, html, SIH_REPLACE_CONTENT), "Failed to replace element's html")
 }
 
 func (e *Element) SetHtml(html string) {
-	szHtml := C.CString(html)
-	defer C.free(unsafe.Pointer(szHtml))
-	if ret := C.HTMLayoutSetElementHtml(e.handle, (*C.BYTE)(unsafe.Pointer(szHtml)), C.DWORD(len(html)), SIH_REPLACE_CONTENT); ret != HLDOM_OK {
-		domPanic(ret, "Failed to replace element's html")
+	mustSucceed(e.TrySetHtml(html))
+}
+
+func (e *Element) TryPrependHtml(prefix string) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.SetElementHtml(e.handle, prefix, SIH_INSERT_AT_START), "Failed to prepend to element's html")
 }
 
 func (e *Element) PrependHtml(prefix string) {
-	szHtml := C.CString(prefix)
-	defer C.free(unsafe.Pointer(szHtml))
-	if ret := C.HTMLayoutSetElementHtml(e.handle, (*C.BYTE)(unsafe.Pointer(szHtml)), C.DWORD(len(prefix)), SIH_INSERT_AT_START); ret != HLDOM_OK {
-		domPanic(ret, "Failed to prepend to element's html")
+	mustSucceed(e.TryPrependHtml(prefix))
+}
+
+func (e *Element) TryAppendHtml(suffix string) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.SetElementHtml(e.handle, suffix, SIH_APPEND_AFTER_LAST), "Failed to append to element's html")
 }
 
 func (e *Element) AppendHtml(suffix string) {
-	szHtml := C.CString(suffix)
-	defer C.free(unsafe.Pointer(szHtml))
-	if ret := C.HTMLayoutSetElementHtml(e.handle, (*C.BYTE)(unsafe.Pointer(szHtml)), C.DWORD(len(suffix)), SIH_APPEND_AFTER_LAST); ret != HLDOM_OK {
-		domPanic(ret, "Failed to append to element's html")
+	mustSucceed(e.TryAppendHtml(suffix))
+}
+
+func (e *Element) TrySetText(text string) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.SetElementInnerText(e.handle, text), "Failed to replace element's text")
 }
 
 func (e *Element) SetText(text string) {
-	szText := C.CString(text)
-	defer C.free(unsafe.Pointer(szText))
-	if ret := C.HTMLayoutSetElementInnerText(e.handle, (*C.BYTE)(unsafe.Pointer(szText)), C.UINT(len(text))); ret != HLDOM_OK {
-		domPanic(ret, "Failed to replace element's text")
-	}
+	mustSucceed(e.TrySetText(text))
 }
 
-func (e *Element) Text() string {
-	var data *C.char
-	if ret := C.HTMLayoutGetElementInnerText(e.handle, (*C.LPBYTE)(unsafe.Pointer(&data))); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get text")
+func (e *Element) TryText() (string, error) {
+	if err := e.checkReleased(); err != nil {
+		return "", err
 	}
-	return C.GoString(data)
+	text, ret := dom.ElementInnerText(e.handle)
+	if ret != HLDOM_OK {
+		return "", domError(ret, "Failed to get text")
+	}
+	return text, nil
 }
 
+func (e *Element) Text() string {
+	text, err := e.TryText()
+	mustSucceed(err)
+	return text
+}
 
 //
 // HTML attribute accessors/modifiers:
@@ -628,17 +986,47 @@ func (e *Element) Text() string {
 
 // Returns the value of attr and a boolean indicating whether or not that attr exists.
 // If the boolean is true, then the returned string is valid.
+func (e *Element) TryAttr(key string) (string, bool, error) {
+	if err := e.checkReleased(); err != nil {
+		return "", false, err
+	}
+	value, exists, ret := dom.AttributeByName(e.handle, key)
+	if ret != HLDOM_OK {
+		return "", false, domError(ret, "Failed to get attribute: ", key)
+	}
+	return value, exists, nil
+}
+
 func (e *Element) Attr(key string) (string, bool) {
-	szValue := (*C.WCHAR)(nil)
-	szKey := C.CString(key)
-	defer C.free(unsafe.Pointer(szKey))
-	if ret := C.HTMLayoutGetAttributeByName(e.handle, (*C.CHAR)(szKey), (*C.LPCWSTR)(&szValue)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get attribute: ", key)
+	value, exists, err := e.TryAttr(key)
+	mustSucceed(err)
+	return value, exists
+}
+
+// Dom errors are returned rather than raised, alongside parse errors
+func (e *Element) TryAttrAsFloat(key string) (float64, bool, error) {
+	var f float64
+	if s, exists, err := e.TryAttr(key); err != nil {
+		return 0.0, false, err
+	} else if !exists {
+		return 0.0, false, nil
+	} else if f, err = strconv.ParseFloat(s, 64); err != nil {
+		return 0.0, true, err
 	}
-	if szValue != nil {
-		return utf16ToString((*uint16)(szValue)), true
+	return float64(f), true, nil
+}
+
+// Dom errors are returned rather than raised, alongside parse errors
+func (e *Element) TryAttrAsInt(key string) (int, bool, error) {
+	var i int
+	if s, exists, err := e.TryAttr(key); err != nil {
+		return 0, false, err
+	} else if !exists {
+		return 0, false, nil
+	} else if i, err = strconv.Atoi(s); err != nil {
+		return 0, true, err
 	}
-	return "", false
+	return i, true, nil
 }
 
 func (e *Element) AttrAsFloat(key string) (float64, bool, error) {
@@ -663,52 +1051,86 @@ func (e *Element) AttrAsInt(key string) (int, bool, error) {
 	return i, true, nil
 }
 
-func (e *Element) SetAttr(key string, value interface{}) {
-	szKey := C.CString(key)
-	defer C.free(unsafe.Pointer(szKey))
-	var ret C.HLDOM_RESULT = HLDOM_OK
+// Formats the value passed to SetAttr/SetStyle.  A nil result means
+// the attribute or style should be removed.
+func formatValue(value interface{}) (*string, error) {
+	var s string
 	switch v := value.(type) {
 	case string:
-		ret = C.HTMLayoutSetAttributeByName(e.handle, (*C.CHAR)(szKey), (*C.WCHAR)(stringToUtf16Ptr(v)))
+		s = v
 	case float32:
-		ret = C.HTMLayoutSetAttributeByName(e.handle, (*C.CHAR)(szKey), (*C.WCHAR)(stringToUtf16Ptr(strconv.FormatFloat(float64(v), 'g', -1, 64))))
+		s = strconv.FormatFloat(float64(v), 'g', -1, 64)
 	case float64:
-		ret = C.HTMLayoutSetAttributeByName(e.handle, (*C.CHAR)(szKey), (*C.WCHAR)(stringToUtf16Ptr(strconv.FormatFloat(float64(v), 'g', -1, 64))))
+		s = strconv.FormatFloat(float64(v), 'g', -1, 64)
 	case int:
-		ret = C.HTMLayoutSetAttributeByName(e.handle, (*C.CHAR)(szKey), (*C.WCHAR)(stringToUtf16Ptr(strconv.Itoa(v))))
+		s = strconv.Itoa(v)
 	case int32:
-		ret = C.HTMLayoutSetAttributeByName(e.handle, (*C.CHAR)(szKey), (*C.WCHAR)(stringToUtf16Ptr(strconv.FormatInt(int64(v), 10))))
+		s = strconv.FormatInt(int64(v), 10)
 	case int64:
-		ret = C.HTMLayoutSetAttributeByName(e.handle, (*C.CHAR)(szKey), (*C.WCHAR)(stringToUtf16Ptr(strconv.FormatInt(v, 10))))
+		s = strconv.FormatInt(v, 10)
 	case nil:
-		ret = C.HTMLayoutSetAttributeByName(e.handle, (*C.CHAR)(szKey), nil)
+		return nil, nil
 	default:
-		panic(fmt.Sprintf("Don't know how to format this argument type: %s", reflect.TypeOf(v)))
+		return nil, fmt.Errorf("Don't know how to format this argument type: %s", reflect.TypeOf(v))
 	}
-	if ret != HLDOM_OK {
-		domPanic(ret, "Failed to set attribute: "+key)
+	return &s, nil
+}
+
+func (e *Element) TrySetAttr(key string, value interface{}) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	s, err := formatValue(value)
+	if err != nil {
+		return err
+	}
+	return domError(dom.SetAttributeByName(e.handle, key, s), "Failed to set attribute: "+key)
+}
+
+func (e *Element) SetAttr(key string, value interface{}) {
+	mustSucceed(e.TrySetAttr(key, value))
+}
+
+func (e *Element) TryRemoveAttr(key string) error {
+	return e.TrySetAttr(key, nil)
 }
 
 func (e *Element) RemoveAttr(key string) {
 	e.SetAttr(key, nil)
 }
 
+func (e *Element) TryAttrByIndex(index int) (string, string, error) {
+	if err := e.checkReleased(); err != nil {
+		return "", "", err
+	}
+	name, value, ret := dom.NthAttribute(e.handle, uint(index))
+	if ret != HLDOM_OK {
+		return "", "", domError(ret, fmt.Sprintf("Failed to get attribute by index: %d", index))
+	}
+	return name, value, nil
+}
+
 func (e *Element) AttrByIndex(index int) (string, string) {
-	szValue := (*C.WCHAR)(nil)
-	szName := (*C.CHAR)(nil)
-	if ret := C.HTMLayoutGetNthAttribute(e.handle, C.UINT(index), (*C.LPCSTR)(&szName), (*C.LPCWSTR)(&szValue)); ret != HLDOM_OK {
-		domPanic(ret, fmt.Sprintf("Failed to get attribute by index: %u", index))
+	name, value, err := e.TryAttrByIndex(index)
+	mustSucceed(err)
+	return name, value
+}
+
+func (e *Element) TryAttrCount() (uint, error) {
+	if err := e.checkReleased(); err != nil {
+		return 0, err
 	}
-	return C.GoString((*C.char)(szName)), utf16ToString((*uint16)(szValue))
+	count, ret := dom.AttributeCount(e.handle)
+	if ret != HLDOM_OK {
+		return 0, domError(ret, "Failed to get attribute count")
+	}
+	return count, nil
 }
 
 func (e *Element) AttrCount() uint {
-	var count C.UINT = 0
-	if ret := C.HTMLayoutGetAttributeCount(e.handle, &count); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get attribute count")
-	}
-	return uint(count)
+	count, err := e.TryAttrCount()
+	mustSucceed(err)
+	return count
 }
 
 //
@@ -717,56 +1139,55 @@ func (e *Element) AttrCount() uint {
 
 // Returns the value of the style and a boolean indicating whether or not that style exists.
 // If the boolean is true, then the returned string is valid.
-func (e *Element) Style(key string) (string, bool) {
-	szValue := (*C.WCHAR)(nil)
-	szKey := C.CString(key)
-	defer C.free(unsafe.Pointer(szKey))
-	if ret := C.HTMLayoutGetStyleAttribute(e.handle, (*C.CHAR)(szKey), (*C.LPCWSTR)(&szValue)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get style: "+key)
+func (e *Element) TryStyle(key string) (string, bool, error) {
+	if err := e.checkReleased(); err != nil {
+		return "", false, err
 	}
-	if szValue != nil {
-		return utf16ToString((*uint16)(szValue)), true
+	value, exists, ret := dom.StyleAttribute(e.handle, key)
+	if ret != HLDOM_OK {
+		return "", false, domError(ret, "Failed to get style: "+key)
 	}
-	return "", false
+	return value, exists, nil
 }
 
-func (e *Element) SetStyle(key string, value interface{}) {
-	szKey := C.CString(key)
-	defer C.free(unsafe.Pointer(szKey))
-	var valuePtr *uint16 = nil
+func (e *Element) Style(key string) (string, bool) {
+	value, exists, err := e.TryStyle(key)
+	mustSucceed(err)
+	return value, exists
+}
 
-	switch v := value.(type) {
-	case string:
-		valuePtr = stringToUtf16Ptr(v)
-	case float32:
-		valuePtr = stringToUtf16Ptr(strconv.FormatFloat(float64(v), 'g', -1, 64))
-	case float64:
-		valuePtr = stringToUtf16Ptr(strconv.FormatFloat(float64(v), 'g', -1, 64))
-	case int:
-		valuePtr = stringToUtf16Ptr(strconv.Itoa(v))
-	case int32:
-		valuePtr = stringToUtf16Ptr(strconv.FormatInt(int64(v), 10))
-	case int64:
-		valuePtr = stringToUtf16Ptr(strconv.FormatInt(v, 10))
-	case nil:
-		valuePtr = nil
-	default:
-		panic(fmt.Sprintf("Don't know how to format this argument type: %s", reflect.TypeOf(v)))
+func (e *Element) TrySetStyle(key string, value interface{}) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
-
-	if ret := C.HTMLayoutSetStyleAttribute(e.handle, (*C.CHAR)(szKey), (*C.WCHAR)(valuePtr)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to set style: "+key)
+	s, err := formatValue(value)
+	if err != nil {
+		return err
 	}
+	return domError(dom.SetStyleAttribute(e.handle, key, s), "Failed to set style: "+key)
+}
+
+func (e *Element) SetStyle(key string, value interface{}) {
+	mustSucceed(e.TrySetStyle(key, value))
+}
+
+func (e *Element) TryRemoveStyle(key string) error {
+	return e.TrySetStyle(key, nil)
 }
 
 func (e *Element) RemoveStyle(key string) {
 	e.SetStyle(key, nil)
 }
 
-func (e *Element) ClearStyles(key string) {
-	if ret := C.HTMLayoutSetStyleAttribute(e.handle, nil, nil); ret != HLDOM_OK {
-		domPanic(ret, "Failed to clear all styles")
+func (e *Element) TryClearStyles(key string) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.SetStyleAttribute(e.handle, "", nil), "Failed to clear all styles")
+}
+
+func (e *Element) ClearStyles(key string) {
+	mustSucceed(e.TryClearStyles(key))
 }
 
 //
@@ -774,29 +1195,50 @@ func (e *Element) ClearStyles(key string) {
 //
 
 // Gets the whole set of state flags for this element
-func (e *Element) StateFlags() uint32 {
-	var state C.UINT
-	if ret := C.HTMLayoutGetElementState(e.handle, &state); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get element state flags")
+func (e *Element) TryStateFlags() (uint32, error) {
+	if err := e.checkReleased(); err != nil {
+		return 0, err
+	}
+	state, ret := dom.ElementState(e.handle)
+	if ret != HLDOM_OK {
+		return 0, domError(ret, "Failed to get element state flags")
 	}
-	return uint32(state)
+	return state, nil
+}
+
+func (e *Element) StateFlags() uint32 {
+	state, err := e.TryStateFlags()
+	mustSucceed(err)
+	return state
 }
 
 // Replaces the whole set of state flags with the specified value
-func (e *Element) SetStateFlags(flags uint32) {
-	shouldUpdate := C.BOOL(1)
-	if ret := C.HTMLayoutSetElementState(e.handle, C.UINT(flags), C.UINT(^flags), shouldUpdate); ret != HLDOM_OK {
-		domPanic(ret, "Failed to set element state flags")
+func (e *Element) TrySetStateFlags(flags uint32) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.SetElementState(e.handle, flags, ^flags, true), "Failed to set element state flags")
+}
+
+func (e *Element) SetStateFlags(flags uint32) {
+	mustSucceed(e.TrySetStateFlags(flags))
 }
 
 // Returns true if the specified flag is "on"
+func (e *Element) TryState(flag uint32) (bool, error) {
+	state, err := e.TryStateFlags()
+	return state&flag != 0, err
+}
+
 func (e *Element) State(flag uint32) bool {
 	return e.StateFlags()&flag != 0
 }
 
 // Sets the specified flag to "on" or "off" according to the value of the provided boolean
-func (e *Element) SetState(flag uint32, on bool) {
+func (e *Element) TrySetState(flag uint32, on bool) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
 	addBits := uint32(0)
 	clearBits := uint32(0)
 	if on {
@@ -804,83 +1246,156 @@ func (e *Element) SetState(flag uint32, on bool) {
 	} else {
 		clearBits = flag
 	}
-	shouldUpdate := C.BOOL(1)
-	if ret := C.HTMLayoutSetElementState(e.handle, C.UINT(addBits), C.UINT(clearBits), shouldUpdate); ret != HLDOM_OK {
-		domPanic(ret, "Failed to set element state flag")
-	}
+	return domError(dom.SetElementState(e.handle, addBits, clearBits, true), "Failed to set element state flag")
+}
+
+func (e *Element) SetState(flag uint32, on bool) {
+	mustSucceed(e.TrySetState(flag, on))
 }
 
 //
 // Functions for retrieving/setting the various dimensions of an element
 //
 
+func (e *Element) TryMove(x, y int) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
+	return domError(dom.MoveElement(e.handle, x, y), "Failed to move element")
+}
+
 func (e *Element) Move(x, y int) {
-	if ret := C.HTMLayoutMoveElement(e.handle, C.INT(x), C.INT(y)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to move element")
+	mustSucceed(e.TryMove(x, y))
+}
+
+func (e *Element) TryResize(x, y, w, h int) error {
+	if err := e.checkReleased(); err != nil {
+		return err
 	}
+	return domError(dom.MoveElementEx(e.handle, x, y, w, h), "Failed to resize element")
 }
 
 func (e *Element) Resize(x, y, w, h int) {
-	if ret := C.HTMLayoutMoveElementEx(e.handle, C.INT(x), C.INT(y), C.INT(w), C.INT(h)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to resize element")
+	mustSucceed(e.TryResize(x, y, w, h))
+}
+
+func (e *Element) tryGetRect(rectTypeFlags uint32) (left, top, right, bottom int, err error) {
+	if err := e.checkReleased(); err != nil {
+		return 0, 0, 0, 0, err
 	}
+	r, ret := dom.ElementLocation(e.handle, rectTypeFlags)
+	if ret != HLDOM_OK {
+		return 0, 0, 0, 0, domError(ret, "Failed to get element rect")
+	}
+	return int(r.Left), int(r.Top), int(r.Right), int(r.Bottom), nil
 }
 
 func (e *Element) getRect(rectTypeFlags uint32) (left, top, right, bottom int) {
-	r := Rect{}
-	if ret := C.HTMLayoutGetElementLocation(e.handle, (C.LPRECT)(unsafe.Pointer(&r)), C.UINT(rectTypeFlags)); ret != HLDOM_OK {
-		domPanic(ret, "Failed to get element rect")
-	}
-	return int(r.Left), int(r.Top), int(r.Right), int(r.Bottom)
+	left, top, right, bottom, err := e.tryGetRect(rectTypeFlags)
+	mustSucceed(err)
+	return
+}
+
+func (e *Element) tryGetRectSize(rectTypeFlags uint32) (width, height int, err error) {
+	l, t, r, b, err := e.tryGetRect(rectTypeFlags)
+	return int(r - l), int(b - t), err
+}
+
+func (e *Element) TryContentBox() (left, top, right, bottom int, err error) {
+	return e.tryGetRect(CONTENT_BOX)
 }
 
 func (e *Element) ContentBox() (left, top, right, bottom int) {
 	return e.getRect(CONTENT_BOX)
 }
 
+func (e *Element) TryContentViewBox() (left, top, right, bottom int, err error) {
+	return e.tryGetRect(CONTENT_BOX | VIEW_RELATIVE)
+}
+
 func (e *Element) ContentViewBox() (left, top, right, bottom int) {
 	return e.getRect(CONTENT_BOX | VIEW_RELATIVE)
 }
 
+func (e *Element) TryContentBoxSize() (width, height int, err error) {
+	return e.tryGetRectSize(CONTENT_BOX)
+}
+
 func (e *Element) ContentBoxSize() (width, height int) {
 	l, t, r, b := e.getRect(CONTENT_BOX)
 	return int(r - l), int(b - t)
 }
 
+func (e *Element) TryPaddingBox() (left, top, right, bottom int, err error) {
+	return e.tryGetRect(PADDING_BOX)
+}
+
 func (e *Element) PaddingBox() (left, top, right, bottom int) {
 	return e.getRect(PADDING_BOX)
 }
 
+func (e *Element) TryPaddingViewBox() (left, top, right, bottom int, err error) {
+	return e.tryGetRect(PADDING_BOX | VIEW_RELATIVE)
+}
+
 func (e *Element) PaddingViewBox() (left, top, right, bottom int) {
 	return e.getRect(PADDING_BOX | VIEW_RELATIVE)
 }
 
+func (e *Element) TryPaddingBoxSize() (width, height int, err error) {
+	return e.tryGetRectSize(PADDING_BOX)
+}
+
 func (e *Element) PaddingBoxSize() (width, height int) {
 	l, t, r, b := e.getRect(PADDING_BOX)
 	return int(r - l), int(b - t)
 }
 
+func (e *Element) TryBorderBox() (left, top, right, bottom int, err error) {
+	return e.tryGetRect(BORDER_BOX)
+}
+
 func (e *Element) BorderBox() (left, top, right, bottom int) {
 	return e.getRect(BORDER_BOX)
 }
 
+func (e *Element) TryBorderViewBox() (left, top, right, bottom int, err error) {
+	return e.tryGetRect(BORDER_BOX | VIEW_RELATIVE)
+}
+
 func (e *Element) BorderViewBox() (left, top, right, bottom int) {
 	return e.getRect(BORDER_BOX | VIEW_RELATIVE)
 }
 
+func (e *Element) TryBorderBoxSize() (width, height int, err error) {
+	return e.tryGetRectSize(BORDER_BOX)
+}
+
 func (e *Element) BorderBoxSize() (width, height int) {
 	l, t, r, b := e.getRect(BORDER_BOX)
 	return int(r - l), int(b - t)
 }
 
+func (e *Element) TryMarginBox() (left, top, right, bottom int, err error) {
+	return e.tryGetRect(MARGIN_BOX)
+}
+
 func (e *Element) MarginBox() (left, top, right, bottom int) {
 	return e.getRect(MARGIN_BOX)
 }
 
+func (e *Element) TryMarginViewBox() (left, top, right, bottom int, err error) {
+	return e.tryGetRect(MARGIN_BOX | VIEW_RELATIVE)
+}
+
 func (e *Element) MarginViewBox() (left, top, right, bottom int) {
 	return e.getRect(MARGIN_BOX | VIEW_RELATIVE)
 }
 
+func (e *Element) TryMarginBoxSize() (width, height int, err error) {
+	return e.tryGetRectSize(MARGIN_BOX)
+}
+
 func (e *Element) MarginBoxSize() (width, height int) {
 	l, t, r, b := e.getRect(MARGIN_BOX)
 	return int(r - l), int(b - t)
@@ -892,15 +1407,29 @@ func (e *Element) MarginBoxSize() (width, height int) {
 // Functions for retrieving/setting the value in widget input controls
 //
 
-type textValueParams struct {
-	MethodId uint32
-	Text *uint16
-	Length uint32
+// Dom errors, including HLDOM_OK_NOT_HANDLED for elements that do not
+// provide a text value, are returned rather than raised
+func (e *Element) TryValueAsString() (string, error) {
+	if err := e.checkReleased(); err != nil {
+		return "", err
+	}
+	args := &TextValueParams{ MethodId: GET_TEXT_VALUE }
+	ret := dom.CallBehaviorMethod(e.handle, (*MethodParams)(unsafe.Pointer(args)))
+	if ret == HLDOM_OK_NOT_HANDLED {
+		return "", domError(ret, "This type of element does not provide data in this way.  Try a <widget>.")
+	} else if ret != HLDOM_OK {
+		return "", domError(ret, "Could not get text value")
+	}
+	if args.Text == nil {
+		return "", errors.New("Nil string pointer")
+	}
+	return utf16ToStringLength(args.Text, int(args.Length)), nil
 }
 
 func (e *Element) ValueAsString() (string, error) {
-	args := &textValueParams{ MethodId: GET_TEXT_VALUE }
-	ret := C.HTMLayoutCallBehaviorMethod(e.handle, (*C.METHOD_PARAMS)(unsafe.Pointer(args)))
+	mustSucceed(e.checkReleased())
+	args := &TextValueParams{MethodId: GET_TEXT_VALUE}
+	ret := dom.CallBehaviorMethod(e.handle, (*MethodParams)(unsafe.Pointer(args)))
 	if ret == HLDOM_OK_NOT_HANDLED {
 		domPanic(ret, "This type of element does not provide data in this way.  Try a <widget>.")
 	} else if ret != HLDOM_OK {
@@ -912,66 +1441,113 @@ func (e *Element) ValueAsString() (string, error) {
 	return utf16ToStringLength(args.Text, int(args.Length)), nil
 }
 
-func (e *Element) SetValue(value interface{}) {
+func (e *Element) TrySetValue(value interface{}) error {
+	if err := e.checkReleased(); err != nil {
+		return err
+	}
 	switch v := value.(type) {
 	case string:
-		args := &textValueParams{
+		args := &TextValueParams{
 			MethodId: SET_TEXT_VALUE,
 			Text: stringToUtf16Ptr(v),
 			Length: uint32(len(v)),
 		}
-		ret := C.HTMLayoutCallBehaviorMethod(e.handle, (*C.METHOD_PARAMS)(unsafe.Pointer(args)))
+		ret := dom.CallBehaviorMethod(e.handle, (*MethodParams)(unsafe.Pointer(args)))
 		if ret == HLDOM_OK_NOT_HANDLED {
-			domPanic(ret, "This type of element does not accept data in this way.  Try a <widget>.")
+			return domError(ret, "This type of element does not accept data in this way.  Try a <widget>.")
 		} else if ret != HLDOM_OK {
-			domPanic(ret, "Could not set text value")
+			return domError(ret, "Could not set text value")
 		}
 	default:
-		panic("Don't know how to set values of this type")
+		return errors.New("Don't know how to set values of this type")
 	}
+	return nil
+}
+
+func (e *Element) SetValue(value interface{}) {
+	mustSucceed(e.TrySetValue(value))
 }
 
 
 
 //
-// The following are not strictly wrappers of htmlayout functions, but rather convenience 
+// The following are not strictly wrappers of htmlayout functions, but rather convenience
 // functions that are helpful in common use cases
 //
 
-func (e *Element) Describe() string {
-	s := e.Type()
-	if value, exists := e.Attr("id"); exists {
+func (e *Element) TryDescribe() (string, error) {
+	s, err := e.TryType()
+	if err != nil {
+		return "", err
+	}
+	if value, exists, err := e.TryAttr("id"); err != nil {
+		return "", err
+	} else if exists {
 		s += "#" + value
 	}
-	if value, exists := e.Attr("class"); exists {
+	if value, exists, err := e.TryAttr("class"); err != nil {
+		return "", err
+	} else if exists {
 		values := strings.Split(value, " ")
 		for _, v := range values {
 			s += "." + v
 		}
 	}
+	return s, nil
+}
+
+func (e *Element) Describe() string {
+	s, err := e.TryDescribe()
+	mustSucceed(err)
 	return s
 }
 
+// Returns the first of the child elements matching the selector.  If no elements
+// match, an error is returned
+func (e *Element) TrySelectFirst(selector string) (*Element, error) {
+	results, err := e.TrySelect(selector)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No elements match selector '%s'", selector)
+	}
+	return results[0], nil
+}
+
 // Returns the first of the child elements matching the selector.  If no elements
 // match, the function panics
 func (e *Element) SelectFirst(selector string) *Element {
-	results := e.Select(selector)
+	result, err := e.TrySelectFirst(selector)
+	mustSucceed(err)
+	return result
+}
+
+// Returns the only child element that matches the selector.  If no elements match
+// or more than one element matches, an error is returned
+func (e *Element) TrySelectUnique(selector string) (*Element, error) {
+	results, err := e.TrySelect(selector)
+	if err != nil {
+		return nil, err
+	}
 	if len(results) == 0 {
-		panic(fmt.Sprintf("No elements match selector '%s'", selector))
+		return nil, fmt.Errorf("No elements match selector '%s'", selector)
+	} else if len(results) > 1 {
+		return nil, fmt.Errorf("More than one element match selector '%s'", selector)
 	}
-	return results[0]
+	return results[0], nil
 }
 
 // Returns the only child element that matches the selector.  If no elements match
 // or more than one element matches, the function panics
 func (e *Element) SelectUnique(selector string) *Element {
-	results := e.Select(selector)
-	if len(results) == 0 {
-		panic(fmt.Sprintf("No elements match selector '%s'", selector))
-	} else if len(results) > 1 {
-		panic(fmt.Sprintf("More than one element match selector '%s'", selector))
-	}
-	return results[0]
+	result, err := e.TrySelectUnique(selector)
+	mustSucceed(err)
+	return result
+}
+
+func (e *Element) TrySelectId(id string) (*Element, error) {
+	return e.TrySelectUnique(fmt.Sprintf("#%s", id))
 }
 
 // A wrapper of SelectUnique that auto-prepends a hash to the provided id.
@@ -986,52 +1562,70 @@ func (e *Element) SelectId(id string) *Element {
 //
 
 // Returns true if the specified class is among those listed in the "class" attribute.
-func (e *Element) HasClass(class string) bool {
-	if classList, exists := e.Attr("class"); !exists {
-		return false
+func (e *Element) TryHasClass(class string) (bool, error) {
+	if classList, exists, err := e.TryAttr("class"); err != nil || !exists {
+		return false, err
 	} else if classes := whitespaceSplitter.FindAllString(classList, -1); classes == nil {
-		return false
+		return false, nil
 	} else {
 		for _, item := range classes {
 			if class == item {
-				return true
+				return true, nil
 			}
 		}
 	}
-	return false
+	return false, nil
+}
+
+func (e *Element) HasClass(class string) bool {
+	has, err := e.TryHasClass(class)
+	mustSucceed(err)
+	return has
 }
 
 // Adds the specified class to the classes listed in the "class" attribute, or does nothing
 // if this class is already included in the list.
-func (e *Element) AddClass(class string) {
-	if classList, exists := e.Attr("class"); !exists {
-		e.SetAttr("class", class)
+func (e *Element) TryAddClass(class string) error {
+	if classList, exists, err := e.TryAttr("class"); err != nil {
+		return err
+	} else if !exists {
+		return e.TrySetAttr("class", class)
 	} else if classes := whitespaceSplitter.FindAllString(classList, -1); classes == nil {
-		e.SetAttr("class", class)
+		return e.TrySetAttr("class", class)
 	} else {
 		for _, item := range classes {
 			if class == item {
-				return
+				return nil
 			}
 		}
 		classes = append(classes, class)
-		e.SetAttr("class", strings.Join(classes, " "))
+		return e.TrySetAttr("class", strings.Join(classes, " "))
 	}
 }
 
+func (e *Element) AddClass(class string) {
+	mustSucceed(e.TryAddClass(class))
+}
+
 // Removes the specified class from the classes listed in the "class" attribute, or does nothing
 // if this class is not included in the list.
-func (e *Element) RemoveClass(class string) {
-	if classList, exists := e.Attr("class"); exists {
-		if classes := whitespaceSplitter.FindAllString(classList, -1); classes != nil {
-			for i, item := range classes {
-				if class == item {
-					// Delete the item from the list
-					classes = append(classes[:i], classes[i+1:]...)
-					e.SetAttr("class", strings.Join(classes, " "))
-					return
-				}
+func (e *Element) TryRemoveClass(class string) error {
+	classList, exists, err := e.TryAttr("class")
+	if err != nil || !exists {
+		return err
+	}
+	if classes := whitespaceSplitter.FindAllString(classList, -1); classes != nil {
+		for i, item := range classes {
+			if class == item {
+				// Delete the item from the list
+				classes = append(classes[:i], classes[i+1:]...)
+				return e.TrySetAttr("class", strings.Join(classes, " "))
 			}
 		}
 	}
+	return nil
+}
+
+func (e *Element) RemoveClass(class string) {
+	mustSucceed(e.TryRemoveClass(class))
 }
@@ -0,0 +1,171 @@
+package gohl
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDomErrorIs(t *testing.T) {
+	err := error(&DomError{HLDOM_INVALID_HANDLE, "Failed to get child count"})
+	if !errors.Is(err, ErrInvalidHandle) {
+		t.Fatal("Expected error to match ErrInvalidHandle")
+	}
+	if errors.Is(err, ErrInvalidParameter) {
+		t.Fatal("Error should not match a sentinel with a different result")
+	}
+	if errors.Is(errors.New("other"), ErrInvalidHandle) {
+		t.Fatal("Only DomErrors should match the sentinels")
+	}
+}
+
+func TestDomErrorNil(t *testing.T) {
+	if err := domError(HLDOM_OK, "unused"); err != nil {
+		t.Fatal("Expected nil error for HLDOM_OK")
+	}
+}
+
+func TestTryChild(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		if child, err := root.TryChild(0); err != nil {
+			t.Fatal(err)
+		} else if child.Type() != "div" {
+			t.Fatal("Unexpected child: ", child.Describe())
+		}
+		if _, err := root.TryChild(5); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatal("Expected an invalid parameter error, got: ", err)
+		}
+	})
+}
+
+func TestTryOnDeletedElement(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		inner := d.Child(0)
+		if err := inner.TryDelete(); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.TryAppendChild(inner); !errors.Is(err, ErrReleased) {
+			t.Fatal("Expected a released error, got: ", err)
+		}
+		if _, err := inner.TryHtml(); !errors.Is(err, ErrReleased) {
+			t.Fatal("Expected a released error, got: ", err)
+		}
+		if err := inner.TryAppendChild(d); err == nil || err.Error() != "element div#b has been deleted" {
+			t.Fatal("Error should describe the deleted element, got: ", err)
+		}
+	})
+}
+
+func TestReleasedElement(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		a := root.Child(0)
+		a.Release()
+		a.Release()
+
+		var released *ReleasedError
+		if _, err := a.TryType(); !errors.As(err, &released) {
+			t.Fatal("Expected a released error, got: ", err)
+		} else if released.Element != "div#a" || released.Deleted {
+			t.Fatal("Unexpected released error: ", released)
+		}
+		if _, _, _, _, err := a.TryContentBox(); !errors.Is(err, ErrReleased) {
+			t.Fatal("Expected a released error from a derived method, got: ", err)
+		}
+		if _, err := root.TrySendEvent(BUTTON_CLICK, a, 0); !errors.Is(err, ErrReleased) {
+			t.Fatal("Expected a released error for a released argument, got: ", err)
+		}
+		if errors.Is(error(&DomError{HLDOM_INVALID_HANDLE, ""}), ErrReleased) {
+			t.Fatal("A DomError should not match ErrReleased")
+		}
+
+		defer expectReleasedError()
+		a.Html()
+	})
+}
+
+func TestTrySetHtmlPassive(t *testing.T) {
+	testWithMemoryHtml(pages["empty"], func(mem *MemoryBackend, hwnd HWND) {
+		d, err := TryNewElement("div")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := d.TrySetHtml("<span></span>"); !errors.Is(err, ErrPassiveHandle) {
+			t.Fatal("Expected a passive handle error, got: ", err)
+		}
+	})
+}
+
+func TestTrySetAttrBadType(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		if err := d.TrySetAttr("x", struct{}{}); err == nil {
+			t.Fatal("Expected an error for an unformattable value")
+		}
+		if _, exists := d.Attr("x"); exists {
+			t.Fatal("Attribute should not have been set")
+		}
+	})
+}
+
+func TestTrySelectUnique(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		if e, err := root.TrySelectId("b"); err != nil {
+			t.Fatal(err)
+		} else if id, _ := e.Attr("id"); id != "b" {
+			t.Fatal("Selected the wrong element")
+		}
+		if _, err := root.TrySelectUnique("div"); err == nil {
+			t.Fatal("Expected an error when more than one element matches")
+		}
+		if _, err := root.TrySelectFirst("span"); err == nil {
+			t.Fatal("Expected an error when no element matches")
+		}
+		if _, err := root.TrySelect("div >"); !errors.Is(err, ErrInvalidParameter) {
+			t.Fatal("Expected an invalid parameter error, got: ", err)
+		}
+	})
+}
+
+func TestTryValueNotHandled(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		if _, err := d.TryValueAsString(); !errors.Is(err, ErrNotHandled) {
+			t.Fatal("Expected a not handled error, got: ", err)
+		}
+		if err := d.TrySetValue("x"); !errors.Is(err, ErrNotHandled) {
+			t.Fatal("Expected a not handled error, got: ", err)
+		}
+	})
+}
+
+func TestValueAsStringRaisesDomError(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		defer expectDomError(HLDOM_OK_NOT_HANDLED)
+		RootElement(hwnd).Child(0).ValueAsString()
+	})
+}
+
+func TestTryAttrAsOnDeletedElement(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		inner := RootElement(hwnd).Child(0).Child(0)
+		inner.Delete()
+		if _, _, err := inner.TryAttrAsFloat("x"); !errors.Is(err, ErrReleased) {
+			t.Fatal("Expected a released error, got: ", err)
+		}
+		if _, _, err := inner.TryAttrAsInt("x"); !errors.Is(err, ErrReleased) {
+			t.Fatal("Expected a released error, got: ", err)
+		}
+		defer expectReleasedError()
+		inner.AttrAsInt("x")
+	})
+}
+
+func TestPanickingWrapperRaisesDomError(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		defer expectDomError(HLDOM_INVALID_PARAMETER)
+		RootElement(hwnd).Child(5)
+	})
+}
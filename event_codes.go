@@ -0,0 +1,505 @@
+package gohl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Named types for the constant groups declared in htmlayout.go.  The constants
+// themselves stay untyped so they can be used with the raw uint32 fields of the
+// params structs; convert a field to one of these types to print it, e.g.
+// MouseEvent(params.Cmd).String() gives "MOUSE_DOWN|SINKING".
+
+type NotifyCode uint32
+type Phase uint32
+type EventGroups uint32
+type KeyboardStates uint32
+type InitializationEvent uint32
+type DraggingType uint32
+type MouseButtons uint32
+type MouseEvent uint32
+type CursorType uint32
+type KeyEvent uint32
+type FocusEvent uint32
+type FocusCause uint32
+type ScrollEvent uint32
+type GestureCmd uint32
+type GestureState uint32
+type GestureTypeFlags uint32
+type DrawEvent uint32
+type ExchangeEvent uint32
+type ExchangeDataType uint32
+type ExchangeCommands uint32
+type BehaviorEvent uint32
+type EventReason uint32
+type EventChangedReason uint32
+type BehaviorMethod uint32
+type InsertLocation uint32
+type BoxArea uint32
+type ValueType uint32
+
+// Flags that are or'ed with the event codes
+const phaseFlags = SINKING | HANDLED
+
+type codeName struct {
+	value uint32
+	name  string
+}
+
+// Names the value if it is one of the codes, otherwise formats it in hex
+func codeString(v uint32, names []codeName) string {
+	for _, n := range names {
+		if n.value == v {
+			return n.name
+		}
+	}
+	return fmt.Sprintf("0x%X", v)
+}
+
+// Names each of the flags set in v, joined with '|'.  An exact match wins, so
+// that zero values and masks like HANDLE_ALL keep their own name.  Bits that
+// don't belong to any flag are appended in hex.
+func flagsString(v uint32, names []codeName) string {
+	for _, n := range names {
+		if n.value == v {
+			return n.name
+		}
+	}
+	parts := make([]string, 0, 4)
+	for _, n := range names {
+		if n.value != 0 && v&n.value == n.value {
+			parts = append(parts, n.name)
+			v &^= n.value
+		}
+	}
+	if v != 0 || len(parts) == 0 {
+		parts = append(parts, fmt.Sprintf("0x%X", v))
+	}
+	return strings.Join(parts, "|")
+}
+
+// Names an event code followed by whichever of the flags in mask are or'ed into it
+func eventString(v, mask uint32, names []codeName) string {
+	s := codeString(v&^mask, names)
+	if flags := v & mask; flags != 0 {
+		s += "|" + flagsString(flags, eventFlagNames)
+	}
+	return s
+}
+
+var eventFlagNames = []codeName{
+	{DRAGGING, "DRAGGING"},
+	{SINKING, "SINKING"},
+	{HANDLED, "HANDLED"},
+}
+
+var notifyCodeNames = []codeName{
+	{HLN_CREATE_CONTROL, "HLN_CREATE_CONTROL"},
+	{HLN_LOAD_DATA, "HLN_LOAD_DATA"},
+	{HLN_CONTROL_CREATED, "HLN_CONTROL_CREATED"},
+	{HLN_DATA_LOADED, "HLN_DATA_LOADED"},
+	{HLN_DOCUMENT_COMPLETE, "HLN_DOCUMENT_COMPLETE"},
+	{HLN_UPDATE_UI, "HLN_UPDATE_UI"},
+	{HLN_DESTROY_CONTROL, "HLN_DESTROY_CONTROL"},
+	{HLN_ATTACH_BEHAVIOR, "HLN_ATTACH_BEHAVIOR"},
+	{HLN_BEHAVIOR_CHANGED, "HLN_BEHAVIOR_CHANGED"},
+	{HLN_DIALOG_CREATED, "HLN_DIALOG_CREATED"},
+	{HLN_DIALOG_CLOSE_RQ, "HLN_DIALOG_CLOSE_RQ"},
+	{HLN_DOCUMENT_LOADED, "HLN_DOCUMENT_LOADED"},
+}
+
+var eventGroupNames = []codeName{
+	{HANDLE_ALL, "HANDLE_ALL"},
+	{HANDLE_INITIALIZATION, "HANDLE_INITIALIZATION"},
+	{HANDLE_MOUSE, "HANDLE_MOUSE"},
+	{HANDLE_KEY, "HANDLE_KEY"},
+	{HANDLE_FOCUS, "HANDLE_FOCUS"},
+	{HANDLE_SCROLL, "HANDLE_SCROLL"},
+	{HANDLE_TIMER, "HANDLE_TIMER"},
+	{HANDLE_SIZE, "HANDLE_SIZE"},
+	{HANDLE_DRAW, "HANDLE_DRAW"},
+	{HANDLE_DATA_ARRIVED, "HANDLE_DATA_ARRIVED"},
+	{HANDLE_BEHAVIOR_EVENT, "HANDLE_BEHAVIOR_EVENT"},
+	{HANDLE_METHOD_CALL, "HANDLE_METHOD_CALL"},
+	{HANDLE_EXCHANGE, "HANDLE_EXCHANGE"},
+	{HANDLE_GESTURE, "HANDLE_GESTURE"},
+	{DISABLE_INITIALIZATION, "DISABLE_INITIALIZATION"},
+}
+
+var keyboardStateNames = []codeName{
+	{CONTROL_KEY_PRESSED, "CONTROL_KEY_PRESSED"},
+	{SHIFT_KEY_PRESSED, "SHIFT_KEY_PRESSED"},
+	{ALT_KEY_PRESSED, "ALT_KEY_PRESSED"},
+}
+
+var initializationEventNames = []codeName{
+	{BEHAVIOR_DETACH, "BEHAVIOR_DETACH"},
+	{BEHAVIOR_ATTACH, "BEHAVIOR_ATTACH"},
+}
+
+var draggingTypeNames = []codeName{
+	{NO_DRAGGING, "NO_DRAGGING"},
+	{DRAGGING_MOVE, "DRAGGING_MOVE"},
+	{DRAGGING_COPY, "DRAGGING_COPY"},
+}
+
+var mouseButtonNames = []codeName{
+	{MAIN_MOUSE_BUTTON, "MAIN_MOUSE_BUTTON"},
+	{PROP_MOUSE_BUTTON, "PROP_MOUSE_BUTTON"},
+	{MIDDLE_MOUSE_BUTTON, "MIDDLE_MOUSE_BUTTON"},
+	{X1_MOUSE_BUTTON, "X1_MOUSE_BUTTON"},
+	{X2_MOUSE_BUTTON, "X2_MOUSE_BUTTON"},
+}
+
+var mouseEventNames = []codeName{
+	{MOUSE_ENTER, "MOUSE_ENTER"},
+	{MOUSE_LEAVE, "MOUSE_LEAVE"},
+	{MOUSE_MOVE, "MOUSE_MOVE"},
+	{MOUSE_UP, "MOUSE_UP"},
+	{MOUSE_DOWN, "MOUSE_DOWN"},
+	{MOUSE_DCLICK, "MOUSE_DCLICK"},
+	{MOUSE_WHEEL, "MOUSE_WHEEL"},
+	{MOUSE_TICK, "MOUSE_TICK"},
+	{MOUSE_IDLE, "MOUSE_IDLE"},
+	{DROP, "DROP"},
+	{DRAG_ENTER, "DRAG_ENTER"},
+	{DRAG_LEAVE, "DRAG_LEAVE"},
+	{DRAG_REQUEST, "DRAG_REQUEST"},
+	{MOUSE_CLICK, "MOUSE_CLICK"},
+}
+
+var cursorTypeNames = []codeName{
+	{CURSOR_ARROW, "CURSOR_ARROW"},
+	{CURSOR_IBEAM, "CURSOR_IBEAM"},
+	{CURSOR_WAIT, "CURSOR_WAIT"},
+	{CURSOR_CROSS, "CURSOR_CROSS"},
+	{CURSOR_UPARROW, "CURSOR_UPARROW"},
+	{CURSOR_SIZENWSE, "CURSOR_SIZENWSE"},
+	{CURSOR_SIZENESW, "CURSOR_SIZENESW"},
+	{CURSOR_SIZEWE, "CURSOR_SIZEWE"},
+	{CURSOR_SIZENS, "CURSOR_SIZENS"},
+	{CURSOR_SIZEALL, "CURSOR_SIZEALL"},
+	{CURSOR_NO, "CURSOR_NO"},
+	{CURSOR_APPSTARTING, "CURSOR_APPSTARTING"},
+	{CURSOR_HELP, "CURSOR_HELP"},
+	{CURSOR_HAND, "CURSOR_HAND"},
+	{CURSOR_DRAG_MOVE, "CURSOR_DRAG_MOVE"},
+	{CURSOR_DRAG_COPY, "CURSOR_DRAG_COPY"},
+}
+
+var keyEventNames = []codeName{
+	{KEY_DOWN, "KEY_DOWN"},
+	{KEY_UP, "KEY_UP"},
+	{KEY_CHAR, "KEY_CHAR"},
+}
+
+var focusEventNames = []codeName{
+	{FOCUS_LOST, "FOCUS_LOST"},
+	{FOCUS_GOT, "FOCUS_GOT"},
+}
+
+var focusCauseNames = []codeName{
+	{BY_CODE, "BY_CODE"},
+	{BY_MOUSE, "BY_MOUSE"},
+	{BY_KEY_NEXT, "BY_KEY_NEXT"},
+	{BY_KEY_PREV, "BY_KEY_PREV"},
+}
+
+var scrollEventNames = []codeName{
+	{SCROLL_HOME, "SCROLL_HOME"},
+	{SCROLL_END, "SCROLL_END"},
+	{SCROLL_STEP_PLUS, "SCROLL_STEP_PLUS"},
+	{SCROLL_STEP_MINUS, "SCROLL_STEP_MINUS"},
+	{SCROLL_PAGE_PLUS, "SCROLL_PAGE_PLUS"},
+	{SCROLL_PAGE_MINUS, "SCROLL_PAGE_MINUS"},
+	{SCROLL_POS, "SCROLL_POS"},
+	{SCROLL_SLIDER_RELEASED, "SCROLL_SLIDER_RELEASED"},
+}
+
+var gestureCmdNames = []codeName{
+	{GESTURE_REQUEST, "GESTURE_REQUEST"},
+	{GESTURE_ZOOM, "GESTURE_ZOOM"},
+	{GESTURE_PAN, "GESTURE_PAN"},
+	{GESTURE_ROTATE, "GESTURE_ROTATE"},
+	{GESTURE_TAP1, "GESTURE_TAP1"},
+	{GESTURE_TAP2, "GESTURE_TAP2"},
+}
+
+var gestureStateNames = []codeName{
+	{GESTURE_STATE_BEGIN, "GESTURE_STATE_BEGIN"},
+	{GESTURE_STATE_INERTIA, "GESTURE_STATE_INERTIA"},
+	{GESTURE_STATE_END, "GESTURE_STATE_END"},
+}
+
+var gestureTypeFlagNames = []codeName{
+	{GESTURE_FLAGS_ALL, "GESTURE_FLAGS_ALL"},
+	{GESTURE_FLAG_ZOOM, "GESTURE_FLAG_ZOOM"},
+	{GESTURE_FLAG_ROTATE, "GESTURE_FLAG_ROTATE"},
+	{GESTURE_FLAG_PAN_VERTICAL, "GESTURE_FLAG_PAN_VERTICAL"},
+	{GESTURE_FLAG_PAN_HORIZONTAL, "GESTURE_FLAG_PAN_HORIZONTAL"},
+	{GESTURE_FLAG_TAP1, "GESTURE_FLAG_TAP1"},
+	{GESTURE_FLAG_TAP2, "GESTURE_FLAG_TAP2"},
+	{GESTURE_FLAG_PAN_WITH_GUTTER, "GESTURE_FLAG_PAN_WITH_GUTTER"},
+	{GESTURE_FLAG_PAN_WITH_INERTIA, "GESTURE_FLAG_PAN_WITH_INERTIA"},
+}
+
+var drawEventNames = []codeName{
+	{DRAW_BACKGROUND, "DRAW_BACKGROUND"},
+	{DRAW_CONTENT, "DRAW_CONTENT"},
+	{DRAW_FOREGROUND, "DRAW_FOREGROUND"},
+}
+
+var exchangeEventNames = []codeName{
+	{X_DRAG_ENTER, "X_DRAG_ENTER"},
+	{X_DRAG_LEAVE, "X_DRAG_LEAVE"},
+	{X_DRAG, "X_DRAG"},
+	{X_DROP, "X_DROP"},
+}
+
+var exchangeDataTypeNames = []codeName{
+	{EXF_UNDEFINED, "EXF_UNDEFINED"},
+	{EXF_TEXT, "EXF_TEXT"},
+	{EXF_HTML, "EXF_HTML"},
+	{EXF_HYPERLINK, "EXF_HYPERLINK"},
+	{EXF_JSON, "EXF_JSON"},
+	{EXF_FILE, "EXF_FILE"},
+}
+
+var exchangeCommandNames = []codeName{
+	{EXC_NONE, "EXC_NONE"},
+	{EXC_COPY, "EXC_COPY"},
+	{EXC_MOVE, "EXC_MOVE"},
+	{EXC_LINK, "EXC_LINK"},
+}
+
+// DO_SWITCH_TAB shares its value with ACTIVATE_CHILD, so it is never printed
+var behaviorEventNames = []codeName{
+	{BUTTON_CLICK, "BUTTON_CLICK"},
+	{BUTTON_PRESS, "BUTTON_PRESS"},
+	{BUTTON_STATE_CHANGED, "BUTTON_STATE_CHANGED"},
+	{EDIT_VALUE_CHANGING, "EDIT_VALUE_CHANGING"},
+	{EDIT_VALUE_CHANGED, "EDIT_VALUE_CHANGED"},
+	{SELECT_SELECTION_CHANGED, "SELECT_SELECTION_CHANGED"},
+	{SELECT_STATE_CHANGED, "SELECT_STATE_CHANGED"},
+	{POPUP_REQUEST, "POPUP_REQUEST"},
+	{POPUP_READY, "POPUP_READY"},
+	{POPUP_DISMISSED, "POPUP_DISMISSED"},
+	{MENU_ITEM_ACTIVE, "MENU_ITEM_ACTIVE"},
+	{MENU_ITEM_CLICK, "MENU_ITEM_CLICK"},
+	{CONTEXT_MENU_SETUP, "CONTEXT_MENU_SETUP"},
+	{CONTEXT_MENU_REQUEST, "CONTEXT_MENU_REQUEST"},
+	{VISIUAL_STATUS_CHANGED, "VISIUAL_STATUS_CHANGED"},
+	{DISABLED_STATUS_CHANGED, "DISABLED_STATUS_CHANGED"},
+	{POPUP_DISMISSING, "POPUP_DISMISSING"},
+	{HYPERLINK_CLICK, "HYPERLINK_CLICK"},
+	{TABLE_HEADER_CLICK, "TABLE_HEADER_CLICK"},
+	{TABLE_ROW_CLICK, "TABLE_ROW_CLICK"},
+	{TABLE_ROW_DBL_CLICK, "TABLE_ROW_DBL_CLICK"},
+	{ELEMENT_COLLAPSED, "ELEMENT_COLLAPSED"},
+	{ELEMENT_EXPANDED, "ELEMENT_EXPANDED"},
+	{ACTIVATE_CHILD, "ACTIVATE_CHILD"},
+	{DO_SWITCH_TAB, "DO_SWITCH_TAB"},
+	{INIT_DATA_VIEW, "INIT_DATA_VIEW"},
+	{ROWS_DATA_REQUEST, "ROWS_DATA_REQUEST"},
+	{UI_STATE_CHANGED, "UI_STATE_CHANGED"},
+	{FORM_SUBMIT, "FORM_SUBMIT"},
+	{FORM_RESET, "FORM_RESET"},
+	{DOCUMENT_COMPLETE, "DOCUMENT_COMPLETE"},
+	{HISTORY_PUSH, "HISTORY_PUSH"},
+	{HISTORY_DROP, "HISTORY_DROP"},
+	{HISTORY_PRIOR, "HISTORY_PRIOR"},
+	{HISTORY_NEXT, "HISTORY_NEXT"},
+	{HISTORY_STATE_CHANGED, "HISTORY_STATE_CHANGED"},
+	{CLOSE_POPUP, "CLOSE_POPUP"},
+	{REQUEST_TOOLTIP, "REQUEST_TOOLTIP"},
+	{ANIMATION, "ANIMATION"},
+	{FIRST_APPLICATION_EVENT_CODE, "FIRST_APPLICATION_EVENT_CODE"},
+}
+
+var eventReasonNames = []codeName{
+	{BY_MOUSE_CLICK, "BY_MOUSE_CLICK"},
+	{BY_KEY_CLICK, "BY_KEY_CLICK"},
+	{SYNTHESIZED, "SYNTHESIZED"},
+}
+
+var eventChangedReasonNames = []codeName{
+	{BY_INS_CHAR, "BY_INS_CHAR"},
+	{BY_INS_CHARS, "BY_INS_CHARS"},
+	{BY_DEL_CHAR, "BY_DEL_CHAR"},
+	{BY_DEL_CHARS, "BY_DEL_CHARS"},
+}
+
+var behaviorMethodNames = []codeName{
+	{DO_CLICK, "DO_CLICK"},
+	{GET_TEXT_VALUE, "GET_TEXT_VALUE"},
+	{SET_TEXT_VALUE, "SET_TEXT_VALUE"},
+	{TEXT_EDIT_GET_SELECTION, "TEXT_EDIT_GET_SELECTION"},
+	{TEXT_EDIT_SET_SELECTION, "TEXT_EDIT_SET_SELECTION"},
+	{TEXT_EDIT_REPLACE_SELECTION, "TEXT_EDIT_REPLACE_SELECTION"},
+	{SCROLL_BAR_GET_VALUE, "SCROLL_BAR_GET_VALUE"},
+	{SCROLL_BAR_SET_VALUE, "SCROLL_BAR_SET_VALUE"},
+	{TEXT_EDIT_GET_CARET_POSITION, "TEXT_EDIT_GET_CARET_POSITION"},
+	{TEXT_EDIT_GET_SELECTION_TEXT, "TEXT_EDIT_GET_SELECTION_TEXT"},
+	{TEXT_EDIT_GET_SELECTION_HTML, "TEXT_EDIT_GET_SELECTION_HTML"},
+	{TEXT_EDIT_CHAR_POS_AT_XY, "TEXT_EDIT_CHAR_POS_AT_XY"},
+	{IS_EMPTY, "IS_EMPTY"},
+	{GET_VALUE, "GET_VALUE"},
+	{SET_VALUE, "SET_VALUE"},
+	{XCALL, "XCALL"},
+	{FIRST_APPLICATION_METHOD_ID, "FIRST_APPLICATION_METHOD_ID"},
+}
+
+var insertLocationNames = []codeName{
+	{SIH_REPLACE_CONTENT, "SIH_REPLACE_CONTENT"},
+	{SIH_INSERT_AT_START, "SIH_INSERT_AT_START"},
+	{SIH_APPEND_AFTER_LAST, "SIH_APPEND_AFTER_LAST"},
+	{SOH_REPLACE, "SOH_REPLACE"},
+	{SOH_INSERT_BEFORE, "SOH_INSERT_BEFORE"},
+	{SOH_INSERT_AFTER, "SOH_INSERT_AFTER"},
+}
+
+var boxNames = []codeName{
+	{CONTENT_BOX, "CONTENT_BOX"},
+	{PADDING_BOX, "PADDING_BOX"},
+	{BORDER_BOX, "BORDER_BOX"},
+	{MARGIN_BOX, "MARGIN_BOX"},
+}
+
+var boxRelationNames = []codeName{
+	{ROOT_RELATIVE, "ROOT_RELATIVE"},
+	{SELF_RELATIVE, "SELF_RELATIVE"},
+	{CONTAINER_RELATIVE, "CONTAINER_RELATIVE"},
+	{VIEW_RELATIVE, "VIEW_RELATIVE"},
+}
+
+var valueTypeNames = []codeName{
+	{T_UNDEFINED, "T_UNDEFINED"},
+	{T_NULL, "T_NULL"},
+	{T_BOOL, "T_BOOL"},
+	{T_INT, "T_INT"},
+	{T_FLOAT, "T_FLOAT"},
+	{T_STRING, "T_STRING"},
+	{T_DATE, "T_DATE"},
+	{T_CURRENCY, "T_CURRENCY"},
+	{T_LENGTH, "T_LENGTH"},
+	{T_ARRAY, "T_ARRAY"},
+	{T_MAP, "T_MAP"},
+	{T_FUNCTION, "T_FUNCTION"},
+	{T_BYTES, "T_BYTES"},
+	{T_OBJECT, "T_OBJECT"},
+	{T_DOM_OBJECT, "T_DOM_OBJECT"},
+}
+
+func (c NotifyCode) String() string          { return codeString(uint32(c), notifyCodeNames) }
+func (c EventGroups) String() string         { return flagsString(uint32(c), eventGroupNames) }
+func (c KeyboardStates) String() string      { return flagsString(uint32(c), keyboardStateNames) }
+func (c InitializationEvent) String() string { return codeString(uint32(c), initializationEventNames) }
+func (c DraggingType) String() string        { return codeString(uint32(c), draggingTypeNames) }
+func (c MouseButtons) String() string        { return flagsString(uint32(c), mouseButtonNames) }
+func (c CursorType) String() string          { return codeString(uint32(c), cursorTypeNames) }
+func (c FocusCause) String() string          { return codeString(uint32(c), focusCauseNames) }
+func (c GestureState) String() string        { return flagsString(uint32(c), gestureStateNames) }
+func (c GestureTypeFlags) String() string    { return flagsString(uint32(c), gestureTypeFlagNames) }
+func (c DrawEvent) String() string           { return codeString(uint32(c), drawEventNames) }
+func (c ExchangeDataType) String() string    { return flagsString(uint32(c), exchangeDataTypeNames) }
+func (c ExchangeCommands) String() string    { return flagsString(uint32(c), exchangeCommandNames) }
+func (c EventReason) String() string         { return codeString(uint32(c), eventReasonNames) }
+func (c EventChangedReason) String() string  { return codeString(uint32(c), eventChangedReasonNames) }
+func (c InsertLocation) String() string      { return codeString(uint32(c), insertLocationNames) }
+func (c ValueType) String() string           { return codeString(uint32(c), valueTypeNames) }
+
+func (p Phase) String() string {
+	if p&Phase(SINKING) != 0 {
+		return "SINKING"
+	}
+	return "BUBBLING"
+}
+
+// The event codes below may have the phase flags or'ed in, and mouse events
+// may additionally have DRAGGING
+func (c MouseEvent) String() string {
+	return eventString(uint32(c), phaseFlags|DRAGGING, mouseEventNames)
+}
+
+func (c KeyEvent) String() string {
+	return eventString(uint32(c), phaseFlags, keyEventNames)
+}
+
+func (c FocusEvent) String() string {
+	return eventString(uint32(c), phaseFlags, focusEventNames)
+}
+
+func (c ScrollEvent) String() string {
+	return eventString(uint32(c), phaseFlags, scrollEventNames)
+}
+
+func (c GestureCmd) String() string {
+	return eventString(uint32(c), phaseFlags, gestureCmdNames)
+}
+
+func (c ExchangeEvent) String() string {
+	return eventString(uint32(c), phaseFlags, exchangeEventNames)
+}
+
+// Codes above FIRST_APPLICATION_EVENT_CODE are printed with the name they
+// were registered under, or relative to FIRST_APPLICATION_EVENT_CODE
+func (c BehaviorEvent) String() string {
+	code := uint32(c) &^ phaseFlags
+	if code <= FIRST_APPLICATION_EVENT_CODE {
+		return eventString(uint32(c), phaseFlags, behaviorEventNames)
+	}
+	s, registered := AppEventName(code)
+	if !registered {
+		s = fmt.Sprintf("FIRST_APPLICATION_EVENT_CODE+%d", code-FIRST_APPLICATION_EVENT_CODE)
+	}
+	if flags := uint32(c) & phaseFlags; flags != 0 {
+		s += "|" + flagsString(flags, eventFlagNames)
+	}
+	return s
+}
+
+// Ids above FIRST_APPLICATION_METHOD_ID are printed relative to it
+func (c BehaviorMethod) String() string {
+	if id := uint32(c); id > FIRST_APPLICATION_METHOD_ID {
+		return fmt.Sprintf("FIRST_APPLICATION_METHOD_ID+%d", id-FIRST_APPLICATION_METHOD_ID)
+	}
+	return codeString(uint32(c), behaviorMethodNames)
+}
+
+// A box type or'ed with the coordinate system it is relative to
+func (c BoxArea) String() string {
+	s := codeString(uint32(c)&0xF0, boxNames)
+	if relation := uint32(c) & 0x0F; relation != 0 {
+		s += "|" + codeString(relation, boxRelationNames)
+	}
+	return s
+}
+
+// Accessors that decode the flags or'ed into the Cmd field.  Event() returns
+// the bare event code.
+
+func (p *MouseParams) Phase() Phase            { return Phase(p.Cmd & SINKING) }
+func (p *MouseParams) IsHandled() bool         { return p.Cmd&HANDLED != 0 }
+func (p *MouseParams) IsDragging() bool        { return p.Cmd&DRAGGING != 0 }
+func (p *MouseParams) Event() MouseEvent       { return MouseEvent(p.Cmd &^ (phaseFlags | DRAGGING)) }
+func (p *KeyParams) Phase() Phase              { return Phase(p.Cmd & SINKING) }
+func (p *KeyParams) IsHandled() bool           { return p.Cmd&HANDLED != 0 }
+func (p *KeyParams) Event() KeyEvent           { return KeyEvent(p.Cmd &^ phaseFlags) }
+func (p *FocusParams) Phase() Phase            { return Phase(p.Cmd & SINKING) }
+func (p *FocusParams) IsHandled() bool         { return p.Cmd&HANDLED != 0 }
+func (p *FocusParams) Event() FocusEvent       { return FocusEvent(p.Cmd &^ phaseFlags) }
+func (p *ScrollParams) Phase() Phase           { return Phase(p.Cmd & SINKING) }
+func (p *ScrollParams) IsHandled() bool        { return p.Cmd&HANDLED != 0 }
+func (p *ScrollParams) Event() ScrollEvent     { return ScrollEvent(p.Cmd &^ phaseFlags) }
+func (p *GestureParams) Phase() Phase          { return Phase(p.Cmd & SINKING) }
+func (p *GestureParams) IsHandled() bool       { return p.Cmd&HANDLED != 0 }
+func (p *GestureParams) Event() GestureCmd     { return GestureCmd(p.Cmd &^ phaseFlags) }
+func (p *ExchangeParams) Phase() Phase         { return Phase(p.Cmd & SINKING) }
+func (p *ExchangeParams) IsHandled() bool      { return p.Cmd&HANDLED != 0 }
+func (p *ExchangeParams) Event() ExchangeEvent { return ExchangeEvent(p.Cmd &^ phaseFlags) }
+
+// Behavior events have no DRAGGING flag; application codes start at the same
+// value, so they are left intact
+func (p *BehaviorEventParams) Phase() Phase         { return Phase(p.Cmd & SINKING) }
+func (p *BehaviorEventParams) IsHandled() bool      { return p.Cmd&HANDLED != 0 }
+func (p *BehaviorEventParams) Event() BehaviorEvent { return BehaviorEvent(p.Cmd &^ phaseFlags) }
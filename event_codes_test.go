@@ -0,0 +1,127 @@
+package gohl
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCodeStrings(t *testing.T) {
+	isolateAppEvents(t)
+	tests := []struct {
+		code   fmt.Stringer
+		expect string
+	}{
+		{MouseEvent(MOUSE_DOWN), "MOUSE_DOWN"},
+		{MouseEvent(MOUSE_DOWN | SINKING), "MOUSE_DOWN|SINKING"},
+		{MouseEvent(MOUSE_ENTER | HANDLED), "MOUSE_ENTER|HANDLED"},
+		{MouseEvent(MOUSE_MOVE | DRAGGING | SINKING | HANDLED), "MOUSE_MOVE|DRAGGING|SINKING|HANDLED"},
+		{MouseEvent(MOUSE_CLICK), "MOUSE_CLICK"},
+		{MouseEvent(0x42), "0x42"},
+		{KeyEvent(KEY_CHAR | SINKING), "KEY_CHAR|SINKING"},
+		{FocusEvent(FOCUS_GOT), "FOCUS_GOT"},
+		{ScrollEvent(SCROLL_POS | HANDLED), "SCROLL_POS|HANDLED"},
+		{GestureCmd(GESTURE_PAN), "GESTURE_PAN"},
+		{ExchangeEvent(X_DROP | SINKING), "X_DROP|SINKING"},
+		{BehaviorEvent(BUTTON_CLICK), "BUTTON_CLICK"},
+		{BehaviorEvent(HYPERLINK_CLICK | SINKING), "HYPERLINK_CLICK|SINKING"},
+		{BehaviorEvent(DO_SWITCH_TAB), "ACTIVATE_CHILD"},
+		{BehaviorEvent(FIRST_APPLICATION_EVENT_CODE), "FIRST_APPLICATION_EVENT_CODE"},
+		{BehaviorEvent(FIRST_APPLICATION_EVENT_CODE + 5), "FIRST_APPLICATION_EVENT_CODE+5"},
+		{BehaviorEvent((FIRST_APPLICATION_EVENT_CODE + 1) | SINKING | HANDLED), "FIRST_APPLICATION_EVENT_CODE+1|SINKING|HANDLED"},
+		{Phase(BUBBLING), "BUBBLING"},
+		{Phase(SINKING), "SINKING"},
+		{EventGroups(HANDLE_MOUSE | HANDLE_KEY), "HANDLE_MOUSE|HANDLE_KEY"},
+		{EventGroups(HANDLE_INITIALIZATION), "HANDLE_INITIALIZATION"},
+		{EventGroups(HANDLE_ALL), "HANDLE_ALL"},
+		{EventGroups(HANDLE_ALL | DISABLE_INITIALIZATION), "HANDLE_ALL|DISABLE_INITIALIZATION"},
+		{EventGroups(HANDLE_GESTURE | 0x10000), "HANDLE_GESTURE|0x10000"},
+		{KeyboardStates(CONTROL_KEY_PRESSED | ALT_KEY_PRESSED), "CONTROL_KEY_PRESSED|ALT_KEY_PRESSED"},
+		{KeyboardStates(0), "0x0"},
+		{MouseButtons(MAIN_MOUSE_BUTTON), "MAIN_MOUSE_BUTTON"},
+		{GestureTypeFlags(GESTURE_FLAGS_ALL), "GESTURE_FLAGS_ALL"},
+		{GestureTypeFlags(GESTURE_FLAG_PAN_VERTICAL | GESTURE_FLAG_PAN_WITH_INERTIA), "GESTURE_FLAG_PAN_VERTICAL|GESTURE_FLAG_PAN_WITH_INERTIA"},
+		{GestureState(GESTURE_STATE_END), "GESTURE_STATE_END"},
+		{ExchangeDataType(EXF_UNDEFINED), "EXF_UNDEFINED"},
+		{ExchangeDataType(EXF_TEXT | EXF_FILE), "EXF_TEXT|EXF_FILE"},
+		{ExchangeCommands(EXC_COPY | EXC_MOVE), "EXC_COPY|EXC_MOVE"},
+		{NotifyCode(HLN_ATTACH_BEHAVIOR), "HLN_ATTACH_BEHAVIOR"},
+		{InitializationEvent(BEHAVIOR_ATTACH), "BEHAVIOR_ATTACH"},
+		{DraggingType(DRAGGING_COPY), "DRAGGING_COPY"},
+		{CursorType(CURSOR_HAND), "CURSOR_HAND"},
+		{FocusCause(BY_KEY_PREV), "BY_KEY_PREV"},
+		{DrawEvent(DRAW_CONTENT), "DRAW_CONTENT"},
+		{EventReason(SYNTHESIZED), "SYNTHESIZED"},
+		{EventChangedReason(BY_DEL_CHARS), "BY_DEL_CHARS"},
+		{BehaviorMethod(XCALL), "XCALL"},
+		{BehaviorMethod(FIRST_APPLICATION_METHOD_ID + 2), "FIRST_APPLICATION_METHOD_ID+2"},
+		{InsertLocation(SOH_INSERT_AFTER), "SOH_INSERT_AFTER"},
+		{BoxArea(CONTENT_BOX), "CONTENT_BOX"},
+		{BoxArea(BORDER_BOX | VIEW_RELATIVE), "BORDER_BOX|VIEW_RELATIVE"},
+		{ValueType(T_MAP), "T_MAP"},
+		{ValueType(99), "0x63"},
+	}
+	for _, test := range tests {
+		if s := test.code.String(); s != test.expect {
+			t.Errorf("Expected %s but got %s", test.expect, s)
+		}
+	}
+}
+
+func TestParamsDecoding(t *testing.T) {
+	mouseTests := []struct {
+		cmd      uint32
+		event    MouseEvent
+		phase    Phase
+		handled  bool
+		dragging bool
+	}{
+		{MOUSE_DOWN, MOUSE_DOWN, Phase(BUBBLING), false, false},
+		{MOUSE_DOWN | SINKING, MOUSE_DOWN, Phase(SINKING), false, false},
+		{MOUSE_UP | HANDLED, MOUSE_UP, Phase(BUBBLING), true, false},
+		{MOUSE_MOVE | DRAGGING | SINKING, MOUSE_MOVE, Phase(SINKING), false, true},
+		{MOUSE_CLICK | DRAGGING | SINKING | HANDLED, MOUSE_CLICK, Phase(SINKING), true, true},
+	}
+	for _, test := range mouseTests {
+		p := &MouseParams{Cmd: test.cmd}
+		if p.Event() != test.event || p.Phase() != test.phase || p.IsHandled() != test.handled || p.IsDragging() != test.dragging {
+			t.Errorf("Decoded %s as %s, %s, handled %v, dragging %v", MouseEvent(test.cmd), p.Event(), p.Phase(), p.IsHandled(), p.IsDragging())
+		}
+		if p.Cmd != test.cmd {
+			t.Error("Decoding should not modify the params")
+		}
+	}
+
+	behaviorTests := []struct {
+		cmd     uint32
+		event   BehaviorEvent
+		phase   Phase
+		handled bool
+	}{
+		{BUTTON_CLICK, BUTTON_CLICK, Phase(BUBBLING), false},
+		{EDIT_VALUE_CHANGED | SINKING, EDIT_VALUE_CHANGED, Phase(SINKING), false},
+		{FORM_SUBMIT | HANDLED, FORM_SUBMIT, Phase(BUBBLING), true},
+		{(FIRST_APPLICATION_EVENT_CODE + 3) | SINKING, FIRST_APPLICATION_EVENT_CODE + 3, Phase(SINKING), false},
+	}
+	for _, test := range behaviorTests {
+		p := &BehaviorEventParams{Cmd: test.cmd}
+		if p.Event() != test.event || p.Phase() != test.phase || p.IsHandled() != test.handled {
+			t.Errorf("Decoded %s as %s, %s, handled %v", BehaviorEvent(test.cmd), p.Event(), p.Phase(), p.IsHandled())
+		}
+	}
+
+	if p := (&KeyParams{Cmd: KEY_UP | SINKING | HANDLED}); p.Event() != KEY_UP || p.Phase() != Phase(SINKING) || !p.IsHandled() {
+		t.Error("Failed to decode key params")
+	}
+	if p := (&FocusParams{Cmd: FOCUS_LOST | HANDLED}); p.Event() != FOCUS_LOST || p.Phase() != Phase(BUBBLING) || !p.IsHandled() {
+		t.Error("Failed to decode focus params")
+	}
+	if p := (&ScrollParams{Cmd: SCROLL_END | SINKING}); p.Event() != SCROLL_END || p.Phase() != Phase(SINKING) {
+		t.Error("Failed to decode scroll params")
+	}
+	if p := (&GestureParams{Cmd: GESTURE_ZOOM | HANDLED}); p.Event() != GESTURE_ZOOM || !p.IsHandled() {
+		t.Error("Failed to decode gesture params")
+	}
+	if p := (&ExchangeParams{Cmd: X_DRAG | SINKING}); p.Event() != X_DRAG || p.Phase() != Phase(SINKING) {
+		t.Error("Failed to decode exchange params")
+	}
+}
@@ -1,5 +1,10 @@
 package gohl
 
+import (
+	"fmt"
+	"unsafe"
+)
+
 type EventHandler struct {
 	OnAttached      func(he HELEMENT)
 	OnDetached      func(he HELEMENT)
@@ -15,29 +20,134 @@ type EventHandler struct {
 	OnScroll        func(he HELEMENT, params *ScrollParams) bool
 	OnExchange      func(he HELEMENT, params *ExchangeParams) bool
 	OnGesture       func(he HELEMENT, params *GestureParams) bool
+
+	// Added with Use
+	middleware []Middleware
 }
 
 func (e *EventHandler) Subscription() uint32 {
 	var subscription uint32 = 0
-	add := func(f interface{}, flag uint32) {
-		if f != nil {
+	add := func(set bool, flag uint32) {
+		if set {
 			subscription |= flag
 		}
 	}
 
 	// OnAttached and OnDetached purposely omitted, since we must receive these events
-	add(e.OnMouse, HANDLE_MOUSE)
-	add(e.OnKey, HANDLE_KEY)
-	add(e.OnFocus, HANDLE_FOCUS)
-	add(e.OnDraw, HANDLE_DRAW)
-	add(e.OnTimer, HANDLE_TIMER)
-	add(e.OnBehaviorEvent, HANDLE_BEHAVIOR_EVENT)
-	add(e.OnMethodCall, HANDLE_METHOD_CALL)
-	add(e.OnDataArrived, HANDLE_DATA_ARRIVED)
-	add(e.OnSize, HANDLE_SIZE)
-	add(e.OnScroll, HANDLE_SCROLL)
-	add(e.OnExchange, HANDLE_EXCHANGE)
-	add(e.OnGesture, HANDLE_GESTURE)
+	add(e.OnMouse != nil, HANDLE_MOUSE)
+	add(e.OnKey != nil, HANDLE_KEY)
+	add(e.OnFocus != nil, HANDLE_FOCUS)
+	add(e.OnDraw != nil, HANDLE_DRAW)
+	add(e.OnTimer != nil, HANDLE_TIMER)
+	add(e.OnBehaviorEvent != nil, HANDLE_BEHAVIOR_EVENT)
+	add(e.OnMethodCall != nil, HANDLE_METHOD_CALL)
+	add(e.OnDataArrived != nil, HANDLE_DATA_ARRIVED)
+	add(e.OnSize != nil, HANDLE_SIZE)
+	add(e.OnScroll != nil, HANDLE_SCROLL)
+	add(e.OnExchange != nil, HANDLE_EXCHANGE)
+	add(e.OnGesture != nil, HANDLE_GESTURE)
 
 	return subscription
 }
+
+// Dispatches a single event to the appropriate callback, by way of the
+// middleware if there is any.  Returns true if the event was handled.
+func (handler *EventHandler) handleEvent(he HELEMENT, evtg uint32, params unsafe.Pointer) bool {
+	if evtg == HANDLE_BEHAVIOR_EVENT {
+		notePayloadDelivered((*BehaviorEventParams)(params))
+	}
+	if evtg == HANDLE_INITIALIZATION || len(handler.middleware) == 0 {
+		return handler.dispatchEvent(he, evtg, params)
+	}
+	return runMiddleware(handler.middleware, &Event{he, evtg, params}, func() bool {
+		return handler.dispatchEvent(he, evtg, params)
+	})
+}
+
+func (handler *EventHandler) dispatchEvent(he HELEMENT, evtg uint32, params unsafe.Pointer) bool {
+	handled := false
+
+	switch evtg {
+	case HANDLE_INITIALIZATION:
+		if p := (*InitializationParams)(params); p.Cmd == BEHAVIOR_ATTACH {
+			//log.Print("Attach event handler to ", NewElementFromHandle(he).Describe())
+			if handler.OnAttached != nil {
+				handler.OnAttached(he)
+			}
+		} else if p.Cmd == BEHAVIOR_DETACH {
+			//log.Print("Detach event handler from ", NewElementFromHandle(he).Describe())
+			if handler.OnDetached != nil {
+				handler.OnDetached(he)
+			}
+
+			// If this was a behavior detaching, decrement the reference count and stop tracking
+			// the pointer if the ref count has been exhausted
+			forgetBehavior(he, handler)
+		}
+		handled = true
+	case HANDLE_MOUSE:
+		if handler.OnMouse != nil {
+			p := (*MouseParams)(params)
+			handled = handler.OnMouse(he, p)
+		}
+	case HANDLE_KEY:
+		if handler.OnKey != nil {
+			p := (*KeyParams)(params)
+			handled = handler.OnKey(he, p)
+		}
+	case HANDLE_FOCUS:
+		if handler.OnFocus != nil {
+			p := (*FocusParams)(params)
+			handled = handler.OnFocus(he, p)
+		}
+	case HANDLE_DRAW:
+		if handler.OnDraw != nil {
+			p := (*DrawParams)(params)
+			handled = handler.OnDraw(he, p)
+		}
+	case HANDLE_TIMER:
+		if handler.OnTimer != nil {
+			p := (*TimerParams)(params)
+			handled = handler.OnTimer(he, p)
+		}
+	case HANDLE_BEHAVIOR_EVENT:
+		if handler.OnBehaviorEvent != nil {
+			p := (*BehaviorEventParams)(params)
+			handled = handler.OnBehaviorEvent(he, p)
+		}
+	case HANDLE_METHOD_CALL:
+		if handler.OnMethodCall != nil {
+			p := (*MethodParams)(params)
+			handled = handler.OnMethodCall(he, p)
+		}
+	case HANDLE_DATA_ARRIVED:
+		if handler.OnDataArrived != nil {
+			p := (*DataArrivedParams)(params)
+			handled = handler.OnDataArrived(he, p)
+		}
+	case HANDLE_SIZE:
+		if handler.OnSize != nil {
+			handler.OnSize(he)
+		}
+	case HANDLE_SCROLL:
+		if handler.OnScroll != nil {
+			p := (*ScrollParams)(params)
+			handled = handler.OnScroll(he, p)
+		}
+	case HANDLE_EXCHANGE:
+		if handler.OnExchange != nil {
+			p := (*ExchangeParams)(params)
+			handled = handler.OnExchange(he, p)
+		}
+	case HANDLE_GESTURE:
+		if handler.OnGesture != nil {
+			p := (*GestureParams)(params)
+			handled = handler.OnGesture(he, p)
+		}
+	default:
+		// Reaches the panic handler by way of handleEventSafely
+		panic(fmt.Sprint("Unhandled htmlayout event group: ", evtg))
+	}
+
+	return handled
+}
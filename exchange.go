@@ -0,0 +1,130 @@
+package gohl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Returned by ExchangeParams.DecodeJson when the drag source offers no JSON
+var ErrNoExchangeData = errors.New("no data of the requested type in the exchange")
+
+// Fetches the raw payload of a drag and drop exchange.  Replaced in tests,
+// where there is no drag source to call back into.
+var fetchExchangeData = htmlayoutFetchExchangeData
+
+// Strips the null terminator, if the source included one
+func trimExchangeString(data []byte) string {
+	return string(bytes.TrimRight(data, "\x00"))
+}
+
+// Splits a payload of null separated strings, dropping the empty entries left
+// by a double null terminator
+func splitExchangeStrings(data []byte) []string {
+	parts := bytes.Split(data, []byte{0})
+	strs := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if len(part) > 0 {
+			strs = append(strs, string(part))
+		}
+	}
+	return strs
+}
+
+// Splits an EXF_HYPERLINK payload, url\0caption, into its url and caption.
+// The caption is empty if the source didn't provide one.
+func parseExchangeHyperlink(data []byte) (url, caption string) {
+	data = bytes.TrimRight(data, "\x00")
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return string(data[:i]), string(data[i+1:])
+	}
+	return string(data), ""
+}
+
+// True if the drag source offers data of the EXF_* type
+func (p *ExchangeParams) HasData(dataType uint32) bool {
+	return p.DataTypes&dataType != 0
+}
+
+// Returns the raw payload for the EXF_* type, or false if the source can't
+// provide it
+func (p *ExchangeParams) Data(dataType uint32) ([]byte, bool) {
+	if !p.HasData(dataType) {
+		return nil, false
+	}
+	return fetchExchangeData(p, dataType)
+}
+
+// The EXF_TEXT payload
+func (p *ExchangeParams) Text() (string, bool) {
+	data, ok := p.Data(EXF_TEXT)
+	return trimExchangeString(data), ok
+}
+
+// The EXF_HTML payload
+func (p *ExchangeParams) Html() (string, bool) {
+	data, ok := p.Data(EXF_HTML)
+	return trimExchangeString(data), ok
+}
+
+// The url and caption of the EXF_HYPERLINK payload
+func (p *ExchangeParams) Hyperlink() (url, caption string, ok bool) {
+	data, ok := p.Data(EXF_HYPERLINK)
+	if !ok {
+		return "", "", false
+	}
+	url, caption = parseExchangeHyperlink(data)
+	return url, caption, true
+}
+
+// The file paths in the EXF_FILE payload
+func (p *ExchangeParams) Files() ([]string, bool) {
+	data, ok := p.Data(EXF_FILE)
+	if !ok {
+		return nil, false
+	}
+	return splitExchangeStrings(data), true
+}
+
+// Decodes the EXF_JSON payload into v, as json.Unmarshal does
+func (p *ExchangeParams) DecodeJson(v interface{}) error {
+	data, ok := p.Data(EXF_JSON)
+	if !ok {
+		return ErrNoExchangeData
+	}
+	if err := json.Unmarshal(bytes.TrimRight(data, "\x00"), v); err != nil {
+		return fmt.Errorf("decoding dropped json: %s", err)
+	}
+	return nil
+}
+
+// True if the drag source allows the EXC_* command
+func (p *ExchangeParams) Allows(cmd uint32) bool {
+	return p.DragCmd&cmd != 0
+}
+
+/*
+Accept
+
+Accepts the drag with one of the EXC_COPY, EXC_MOVE or EXC_LINK commands, for
+use as the return value of an OnExchange handler:
+
+	case X_DRAG_ENTER, X_DRAG:
+		return params.Accept(EXC_COPY)
+
+Returns false, rejecting the drag, if the source doesn't allow the command.
+*/
+func (p *ExchangeParams) Accept(cmd uint32) bool {
+	if !p.Allows(cmd) {
+		return p.Reject()
+	}
+	p.DragCmd = cmd
+	return true
+}
+
+// Rejects the drag.  Always returns false, so it can be returned from the handler.
+func (p *ExchangeParams) Reject() bool {
+	p.DragCmd = EXC_NONE
+	return false
+}
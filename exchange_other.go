@@ -0,0 +1,8 @@
+//go:build !windows
+
+package gohl
+
+// There is no drag source to ask outside of Windows
+func htmlayoutFetchExchangeData(p *ExchangeParams, dataType uint32) ([]byte, bool) {
+	return nil, false
+}
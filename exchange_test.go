@@ -0,0 +1,125 @@
+package gohl
+
+import (
+	"testing"
+)
+
+// Serves the exchange payloads from a map in place of the drag source
+func withExchangeData(payloads map[uint32]string, test func(p *ExchangeParams)) {
+	fetchExchangeData = func(p *ExchangeParams, dataType uint32) ([]byte, bool) {
+		data, ok := payloads[dataType]
+		return []byte(data), ok
+	}
+	defer func() { fetchExchangeData = htmlayoutFetchExchangeData }()
+	p := &ExchangeParams{Cmd: X_DROP, DragCmd: EXC_COPY | EXC_LINK}
+	for dataType := range payloads {
+		p.DataTypes |= dataType
+	}
+	test(p)
+}
+
+func TestParseExchangePayloads(t *testing.T) {
+	if s := trimExchangeString([]byte("hello\x00")); s != "hello" {
+		t.Error("Unexpected string: ", s)
+	}
+	fileTests := []struct {
+		data   string
+		expect []string
+	}{
+		{"", []string{}},
+		{"C:\\a.txt", []string{"C:\\a.txt"}},
+		{"C:\\a.txt\x00D:\\b c\\d.png\x00\x00", []string{"C:\\a.txt", "D:\\b c\\d.png"}},
+	}
+	for _, test := range fileTests {
+		files := splitExchangeStrings([]byte(test.data))
+		if len(files) != len(test.expect) {
+			t.Errorf("Expected %q but got %q", test.expect, files)
+			continue
+		}
+		for i := range files {
+			if files[i] != test.expect[i] {
+				t.Errorf("Expected %q but got %q", test.expect, files)
+			}
+		}
+	}
+	linkTests := []struct {
+		data, url, caption string
+	}{
+		{"http://a.com/\x00A site\x00", "http://a.com/", "A site"},
+		{"http://a.com/\x00", "http://a.com/", ""},
+		{"http://a.com/", "http://a.com/", ""},
+	}
+	for _, test := range linkTests {
+		if url, caption := parseExchangeHyperlink([]byte(test.data)); url != test.url || caption != test.caption {
+			t.Errorf("Parsed %q as %q, %q", test.data, url, caption)
+		}
+	}
+}
+
+func TestExchangeData(t *testing.T) {
+	payloads := map[uint32]string{
+		EXF_TEXT:      "plain\x00",
+		EXF_HTML:      "<b>bold</b>",
+		EXF_HYPERLINK: "http://a.com/\x00A site",
+		EXF_JSON:      `{"id": 3, "tags": ["x", "y"]}`,
+		EXF_FILE:      "a.txt\x00b.txt\x00\x00",
+	}
+	withExchangeData(payloads, func(p *ExchangeParams) {
+		if text, ok := p.Text(); !ok || text != "plain" {
+			t.Error("Unexpected text: ", text)
+		}
+		if html, ok := p.Html(); !ok || html != "<b>bold</b>" {
+			t.Error("Unexpected html: ", html)
+		}
+		if url, caption, ok := p.Hyperlink(); !ok || url != "http://a.com/" || caption != "A site" {
+			t.Error("Unexpected hyperlink: ", url, caption)
+		}
+		if files, ok := p.Files(); !ok || len(files) != 2 || files[0] != "a.txt" || files[1] != "b.txt" {
+			t.Error("Unexpected files: ", files)
+		}
+		var item struct {
+			Id   int
+			Tags []string
+		}
+		if err := p.DecodeJson(&item); err != nil || item.Id != 3 || len(item.Tags) != 2 {
+			t.Error("Unexpected json: ", item, err)
+		}
+	})
+
+	withExchangeData(map[uint32]string{EXF_TEXT: "only text", EXF_JSON: "{"}, func(p *ExchangeParams) {
+		if _, ok := p.Html(); ok {
+			t.Error("Expected no html")
+		}
+		if _, _, ok := p.Hyperlink(); ok {
+			t.Error("Expected no hyperlink")
+		}
+		if _, ok := p.Files(); ok {
+			t.Error("Expected no files")
+		}
+		var v interface{}
+		if err := p.DecodeJson(&v); err == nil || err == ErrNoExchangeData {
+			t.Error("Expected a decoding error, got: ", err)
+		}
+		p.DataTypes &^= EXF_JSON
+		if err := p.DecodeJson(&v); err != ErrNoExchangeData {
+			t.Error("Expected ErrNoExchangeData, got: ", err)
+		}
+	})
+}
+
+func TestExchangeAccept(t *testing.T) {
+	p := &ExchangeParams{Cmd: X_DRAG_ENTER, DragCmd: EXC_COPY | EXC_LINK}
+	if !p.Allows(EXC_COPY) || p.Allows(EXC_MOVE) {
+		t.Error("Unexpected allowed commands: ", ExchangeCommands(p.DragCmd))
+	}
+	if p.Accept(EXC_MOVE) || p.DragCmd != EXC_NONE {
+		t.Error("Should not accept a command the source doesn't allow")
+	}
+	p.DragCmd = EXC_COPY | EXC_LINK
+	if !p.Accept(EXC_LINK) || p.DragCmd != EXC_LINK {
+		t.Error("Expected the drag to be accepted as a link, got: ", ExchangeCommands(p.DragCmd))
+	}
+	if p.Reject() || p.DragCmd != EXC_NONE {
+		t.Error("Expected the drag to be rejected")
+	}
+}
@@ -0,0 +1,54 @@
+package gohl
+
+import (
+	"log"
+	"regexp"
+)
+
+func looseEqual(a, b string) bool {
+	if re, err := regexp.Compile(`\s+`); err != nil {
+		log.Panic(err)
+	} else {
+		a = re.ReplaceAllLiteralString(a, "")
+		b = re.ReplaceAllLiteralString(b, "")
+	}
+	return a == b
+}
+
+func expectDomError(code HLDOM_RESULT) {
+	if err := recover(); err == nil {
+		log.Panic("Expected a DomError but got no error")
+	} else if de, ok := err.(*DomError); !ok {
+		log.Panic("Expected DomError, instead got: ", err)
+	} else if de.Result != code {
+		log.Panicf("Expected DomError with code %s, but got code %s instead ", domResultAsString(code), domResultAsString(de.Result))
+	}
+}
+
+func expectReleasedError() {
+	if err := recover(); err == nil {
+		log.Panic("Expected a ReleasedError but got no error")
+	} else if _, ok := err.(*ReleasedError); !ok {
+		log.Panic("Expected ReleasedError, instead got: ", err)
+	}
+}
+
+func expectPanic() {
+	if err := recover(); err == nil {
+		log.Panic("Expected a panic but didn't get one")
+	}
+}
+
+// Page templates used for various tests
+var pages = map[string]string{
+	"empty":       ``,
+	"page":        `<html><body></body></html>`,
+	"one-div":     `<div id="a"></div>`,
+	"two-divs":    `<div id="a"></div><div id="b"></div>`,
+	"three-divs":  `<div id="a"></div><div id="b"></div><div id="c"></div>`,
+	"nested-divs": `<div id="a"><div id="b"></div></div>`,
+	"attr":        `<div id="a" first="5" second="5.1" third="yes"></div>`,
+	"css":         `<div style="left:10; opacity:0.5; text-align:center;"></div>`,
+	"classes":     `<div class="one  two three"></div><div></div>`,
+	"input":       `<widget type="text" value="test string"></widget>`,
+}
@@ -0,0 +1,312 @@
+//go:build windows
+
+package gohl
+
+/*
+#cgo CFLAGS: -I./htmlayout/include
+#cgo LDFLAGS: ./htmlayout/lib/HTMLayout.lib
+
+#include <stdlib.h>
+#include <htmlayout.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// The default Backend, a thin layer over the HTMLayout dll
+type htmlayoutBackend struct{}
+
+func defaultBackend() Backend {
+	return htmlayoutBackend{}
+}
+
+// HELEMENT is the C handle carried in a uintptr, so the two convert by
+// reinterpreting the bits
+func cHandle(he HELEMENT) C.HELEMENT {
+	return *(*C.HELEMENT)(unsafe.Pointer(&he))
+}
+
+func goHandle(he C.HELEMENT) HELEMENT {
+	return *(*HELEMENT)(unsafe.Pointer(&he))
+}
+
+func (b htmlayoutBackend) UseElement(he HELEMENT) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayout_UseElement(cHandle(he)))
+}
+
+func (b htmlayoutBackend) UnuseElement(he HELEMENT) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayout_UnuseElement(cHandle(he)))
+}
+
+func (b htmlayoutBackend) RootElement(hwnd HWND) (HELEMENT, HLDOM_RESULT) {
+	var handle HELEMENT = BAD_HELEMENT
+	ret := C.HTMLayoutGetRootElement(C.HWND(C.HANDLE(uintptr(hwnd))), (*C.HELEMENT)(unsafe.Pointer(&handle)))
+	return handle, HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) FocusElement(hwnd HWND) (HELEMENT, HLDOM_RESULT) {
+	var handle HELEMENT = BAD_HELEMENT
+	ret := C.HTMLayoutGetFocusElement(C.HWND(C.HANDLE(uintptr(hwnd))), (*C.HELEMENT)(unsafe.Pointer(&handle)))
+	return handle, HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) CreateElement(tagName string) (HELEMENT, HLDOM_RESULT) {
+	var handle HELEMENT = BAD_HELEMENT
+	szName := C.CString(tagName)
+	defer C.free(unsafe.Pointer(szName))
+	ret := C.HTMLayoutCreateElement((*C.CHAR)(szName), nil, (*C.HELEMENT)(unsafe.Pointer(&handle)))
+	return handle, HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) CloneElement(he HELEMENT) (HELEMENT, HLDOM_RESULT) {
+	var clone C.HELEMENT
+	ret := C.HTMLayoutCloneElement(cHandle(he), &clone)
+	return goHandle(clone), HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) ChildrenCount(he HELEMENT) (uint, HLDOM_RESULT) {
+	var count C.UINT
+	ret := C.HTMLayoutGetChildrenCount(cHandle(he), &count)
+	return uint(count), HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) NthChild(he HELEMENT, index uint) (HELEMENT, HLDOM_RESULT) {
+	var child C.HELEMENT
+	ret := C.HTMLayoutGetNthChild(cHandle(he), C.UINT(index), &child)
+	return goHandle(child), HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) ElementIndex(he HELEMENT) (uint, HLDOM_RESULT) {
+	var index C.UINT
+	ret := C.HTMLayoutGetElementIndex(cHandle(he), &index)
+	return uint(index), HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) ParentElement(he HELEMENT) (HELEMENT, HLDOM_RESULT) {
+	var parent C.HELEMENT
+	ret := C.HTMLayoutGetParentElement(cHandle(he), &parent)
+	return goHandle(parent), HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) InsertElement(he, parent HELEMENT, index uint) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutInsertElement(cHandle(he), cHandle(parent), C.UINT(index)))
+}
+
+func (b htmlayoutBackend) DetachElement(he HELEMENT) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutDetachElement(cHandle(he)))
+}
+
+func (b htmlayoutBackend) DeleteElement(he HELEMENT) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutDeleteElement(cHandle(he)))
+}
+
+func (b htmlayoutBackend) SwapElements(he1, he2 HELEMENT) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutSwapElements(cHandle(he1), cHandle(he2)))
+}
+
+func (b htmlayoutBackend) SortElements(he HELEMENT, start, end uint, comparator func(HELEMENT, HELEMENT) int) HLDOM_RESULT {
+	arg := uintptr(unsafe.Pointer(&comparator))
+	return HLDOM_RESULT(C.HTMLayoutSortElements(cHandle(he), C.UINT(start), C.UINT(end), (*[0]byte)(unsafe.Pointer(goElementComparator)), C.LPVOID(arg)))
+}
+
+func (b htmlayoutBackend) SelectElements(he HELEMENT, selector string, callback func(HELEMENT) bool) HLDOM_RESULT {
+	szSelector := C.CString(selector)
+	defer C.free(unsafe.Pointer(szSelector))
+	arg := uintptr(unsafe.Pointer(&callback))
+	return HLDOM_RESULT(C.HTMLayoutSelectElements(cHandle(he), (*C.CHAR)(szSelector), (*[0]byte)(unsafe.Pointer(goSelectCallback)), C.LPVOID(arg)))
+}
+
+func (b htmlayoutBackend) SelectParent(he HELEMENT, selector string, depth uint) (HELEMENT, HLDOM_RESULT) {
+	szSelector := C.CString(selector)
+	defer C.free(unsafe.Pointer(szSelector))
+	var parent C.HELEMENT
+	ret := C.HTMLayoutSelectParent(cHandle(he), (*C.CHAR)(szSelector), C.UINT(depth), &parent)
+	return goHandle(parent), HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) ElementType(he HELEMENT) (string, HLDOM_RESULT) {
+	var data *C.char
+	if ret := C.HTMLayoutGetElementType(cHandle(he), (*C.LPCSTR)(unsafe.Pointer(&data))); ret != HLDOM_OK {
+		return "", HLDOM_RESULT(ret)
+	}
+	return C.GoString(data), HLDOM_OK
+}
+
+func (b htmlayoutBackend) ElementHtml(he HELEMENT, outer bool) (string, HLDOM_RESULT) {
+	var data *C.char
+	var cOuter C.BOOL = 0
+	if outer {
+		cOuter = 1
+	}
+	if ret := C.HTMLayoutGetElementHtml(cHandle(he), (*C.LPBYTE)(unsafe.Pointer(&data)), cOuter); ret != HLDOM_OK {
+		return "", HLDOM_RESULT(ret)
+	}
+	return C.GoString(data), HLDOM_OK
+}
+
+func (b htmlayoutBackend) SetElementHtml(he HELEMENT, html string, where uint) HLDOM_RESULT {
+	szHtml := C.CString(html)
+	defer C.free(unsafe.Pointer(szHtml))
+	return HLDOM_RESULT(C.HTMLayoutSetElementHtml(cHandle(he), (*C.BYTE)(unsafe.Pointer(szHtml)), C.DWORD(len(html)), C.UINT(where)))
+}
+
+func (b htmlayoutBackend) ElementInnerText(he HELEMENT) (string, HLDOM_RESULT) {
+	var data *C.char
+	if ret := C.HTMLayoutGetElementInnerText(cHandle(he), (*C.LPBYTE)(unsafe.Pointer(&data))); ret != HLDOM_OK {
+		return "", HLDOM_RESULT(ret)
+	}
+	return C.GoString(data), HLDOM_OK
+}
+
+func (b htmlayoutBackend) SetElementInnerText(he HELEMENT, text string) HLDOM_RESULT {
+	szText := C.CString(text)
+	defer C.free(unsafe.Pointer(szText))
+	return HLDOM_RESULT(C.HTMLayoutSetElementInnerText(cHandle(he), (*C.BYTE)(unsafe.Pointer(szText)), C.UINT(len(text))))
+}
+
+func (b htmlayoutBackend) AttributeCount(he HELEMENT) (uint, HLDOM_RESULT) {
+	var count C.UINT = 0
+	ret := C.HTMLayoutGetAttributeCount(cHandle(he), &count)
+	return uint(count), HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) NthAttribute(he HELEMENT, index uint) (string, string, HLDOM_RESULT) {
+	szValue := (*C.WCHAR)(nil)
+	szName := (*C.CHAR)(nil)
+	if ret := C.HTMLayoutGetNthAttribute(cHandle(he), C.UINT(index), (*C.LPCSTR)(&szName), (*C.LPCWSTR)(&szValue)); ret != HLDOM_OK {
+		return "", "", HLDOM_RESULT(ret)
+	}
+	return C.GoString((*C.char)(szName)), utf16ToString((*uint16)(szValue)), HLDOM_OK
+}
+
+func (b htmlayoutBackend) AttributeByName(he HELEMENT, name string) (string, bool, HLDOM_RESULT) {
+	szValue := (*C.WCHAR)(nil)
+	szName := C.CString(name)
+	defer C.free(unsafe.Pointer(szName))
+	if ret := C.HTMLayoutGetAttributeByName(cHandle(he), (*C.CHAR)(szName), (*C.LPCWSTR)(&szValue)); ret != HLDOM_OK {
+		return "", false, HLDOM_RESULT(ret)
+	}
+	if szValue != nil {
+		return utf16ToString((*uint16)(szValue)), true, HLDOM_OK
+	}
+	return "", false, HLDOM_OK
+}
+
+func (b htmlayoutBackend) SetAttributeByName(he HELEMENT, name string, value *string) HLDOM_RESULT {
+	szName := C.CString(name)
+	defer C.free(unsafe.Pointer(szName))
+	var valuePtr *uint16 = nil
+	if value != nil {
+		valuePtr = stringToUtf16Ptr(*value)
+	}
+	return HLDOM_RESULT(C.HTMLayoutSetAttributeByName(cHandle(he), (*C.CHAR)(szName), (*C.WCHAR)(valuePtr)))
+}
+
+func (b htmlayoutBackend) StyleAttribute(he HELEMENT, name string) (string, bool, HLDOM_RESULT) {
+	szValue := (*C.WCHAR)(nil)
+	szName := C.CString(name)
+	defer C.free(unsafe.Pointer(szName))
+	if ret := C.HTMLayoutGetStyleAttribute(cHandle(he), (*C.CHAR)(szName), (*C.LPCWSTR)(&szValue)); ret != HLDOM_OK {
+		return "", false, HLDOM_RESULT(ret)
+	}
+	if szValue != nil {
+		return utf16ToString((*uint16)(szValue)), true, HLDOM_OK
+	}
+	return "", false, HLDOM_OK
+}
+
+func (b htmlayoutBackend) SetStyleAttribute(he HELEMENT, name string, value *string) HLDOM_RESULT {
+	if name == "" && value == nil {
+		return HLDOM_RESULT(C.HTMLayoutSetStyleAttribute(cHandle(he), nil, nil))
+	}
+	szName := C.CString(name)
+	defer C.free(unsafe.Pointer(szName))
+	var valuePtr *uint16 = nil
+	if value != nil {
+		valuePtr = stringToUtf16Ptr(*value)
+	}
+	return HLDOM_RESULT(C.HTMLayoutSetStyleAttribute(cHandle(he), (*C.CHAR)(szName), (*C.WCHAR)(valuePtr)))
+}
+
+func (b htmlayoutBackend) ElementState(he HELEMENT) (uint32, HLDOM_RESULT) {
+	var state C.UINT
+	ret := C.HTMLayoutGetElementState(cHandle(he), &state)
+	return uint32(state), HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) SetElementState(he HELEMENT, bitsToSet, bitsToClear uint32, update bool) HLDOM_RESULT {
+	var shouldUpdate C.BOOL = 0
+	if update {
+		shouldUpdate = 1
+	}
+	return HLDOM_RESULT(C.HTMLayoutSetElementState(cHandle(he), C.UINT(bitsToSet), C.UINT(bitsToClear), shouldUpdate))
+}
+
+func (b htmlayoutBackend) ElementLocation(he HELEMENT, areas uint32) (Rect, HLDOM_RESULT) {
+	r := Rect{}
+	ret := C.HTMLayoutGetElementLocation(cHandle(he), (C.LPRECT)(unsafe.Pointer(&r)), C.UINT(areas))
+	return r, HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) MoveElement(he HELEMENT, x, y int) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutMoveElement(cHandle(he), C.INT(x), C.INT(y)))
+}
+
+func (b htmlayoutBackend) MoveElementEx(he HELEMENT, x, y, w, h int) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutMoveElementEx(cHandle(he), C.INT(x), C.INT(y), C.INT(w), C.INT(h)))
+}
+
+func (b htmlayoutBackend) UpdateElement(he HELEMENT, flags uint32) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutUpdateElementEx(cHandle(he), C.UINT(flags)))
+}
+
+func (b htmlayoutBackend) ElementHwnd(he HELEMENT, root bool) (HWND, HLDOM_RESULT) {
+	var hwnd HWND
+	var rootWindow C.BOOL = 0
+	if root {
+		rootWindow = 1
+	}
+	ret := C.HTMLayoutGetElementHwnd(cHandle(he), (*C.HWND)(unsafe.Pointer(&hwnd)), rootWindow)
+	return hwnd, HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) SetCapture(he HELEMENT) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutSetCapture(cHandle(he)))
+}
+
+func (b htmlayoutBackend) ReleaseCapture() bool {
+	return C.ReleaseCapture() != 0
+}
+
+func (b htmlayoutBackend) SetTimer(he HELEMENT, ms uint) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutSetTimer(cHandle(he), C.UINT(ms)))
+}
+
+func (b htmlayoutBackend) SendEvent(he HELEMENT, eventCode uint, source HELEMENT, reason uintptr) (bool, HLDOM_RESULT) {
+	var handled C.BOOL = 0
+	ret := C.HTMLayoutSendEvent(cHandle(he), C.UINT(eventCode), cHandle(source), C.UINT_PTR(reason), &handled)
+	return handled != 0, HLDOM_RESULT(ret)
+}
+
+func (b htmlayoutBackend) PostEvent(he HELEMENT, eventCode uint, source HELEMENT, reason uint32) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutPostEvent(cHandle(he), C.UINT(eventCode), cHandle(source), C.UINT(reason)))
+}
+
+func (b htmlayoutBackend) CallBehaviorMethod(he HELEMENT, params *MethodParams) HLDOM_RESULT {
+	return HLDOM_RESULT(C.HTMLayoutCallBehaviorMethod(cHandle(he), (*C.METHOD_PARAMS)(unsafe.Pointer(params))))
+}
+
+func (b htmlayoutBackend) AttachEventHandler(he HELEMENT, handler *EventHandler, subscription uint32) HLDOM_RESULT {
+	tag := uintptr(unsafe.Pointer(handler))
+	if subscription == HANDLE_ALL {
+		return HLDOM_RESULT(C.HTMLayoutAttachEventHandler(cHandle(he), (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag)))
+	}
+	return HLDOM_RESULT(C.HTMLayoutAttachEventHandlerEx(cHandle(he), (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag), C.UINT(subscription)))
+}
+
+func (b htmlayoutBackend) DetachEventHandler(he HELEMENT, handler *EventHandler) HLDOM_RESULT {
+	tag := uintptr(unsafe.Pointer(handler))
+	return HLDOM_RESULT(C.HTMLayoutDetachEventHandler(cHandle(he), (*[0]byte)(unsafe.Pointer(goElementProc)), C.LPVOID(tag)))
+}
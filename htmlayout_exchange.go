@@ -0,0 +1,36 @@
+//go:build windows
+
+package gohl
+
+/*
+#cgo CFLAGS: -I./htmlayout/include
+
+#include <stdint.h>
+#include <htmlayout.h>
+
+// Go can't call through a C function pointer directly
+static BOOL fetch_exchange_data(uintptr_t fn, EXCHANGE_PARAMS* params, UINT data_type, LPCBYTE* data, UINT* length) {
+	return ((FETCH_EXCHANGE_DATA*)fn)(params, data_type, data, length);
+}
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// Asks the drag source for the data through the params' FetchData callback.
+// The params must be the ones HTMLayout passed to the handler, since the
+// callback is handed them back.
+func htmlayoutFetchExchangeData(p *ExchangeParams, dataType uint32) ([]byte, bool) {
+	if p.FetchData == 0 {
+		return nil, false
+	}
+	var data C.LPCBYTE
+	var length C.UINT
+	ok := C.fetch_exchange_data(C.uintptr_t(p.FetchData), (*C.EXCHANGE_PARAMS)(unsafe.Pointer(p)), C.UINT(dataType), &data, &length)
+	if ok == 0 || data == nil {
+		return nil, false
+	}
+	return C.GoBytes(unsafe.Pointer(data), C.int(length)), true
+}
@@ -1,9 +1,10 @@
+//go:build windows
+
 package gohl
 
 import (
 	"log"
 	"math"
-	"regexp"
 	"strconv"
 	"syscall"
 	"testing"
@@ -37,7 +38,7 @@ var (
 
 	registeredClasses = make(map[string]bool, 4)
 	testPages         = make(chan string, 1)
-	testFuncs         = make(chan func(uint32), 1)
+	testFuncs         = make(chan func(HWND), 1)
 )
 
 type Wndclassex struct {
@@ -46,20 +47,20 @@ type Wndclassex struct {
 	WndProc    uintptr
 	ClsExtra   int32
 	WndExtra   int32
-	Instance   uint32
-	Icon       uint32
-	Cursor     uint32
-	Background uint32
+	Instance   uintptr
+	Icon       uintptr
+	Cursor     uintptr
+	Background uintptr
 	MenuName   *uint16
 	ClassName  *uint16
-	IconSm     uint32
+	IconSm     uintptr
 }
 
 type Msg struct {
-	Hwnd    uint32
+	Hwnd    HWND
 	Message uint32
-	Wparam  int32
-	Lparam  int32
+	Wparam  uintptr
+	Lparam  uintptr
 	Time    uint32
 	Pt      Point
 }
@@ -77,9 +78,9 @@ func RegisterClassEx(wndclass *Wndclassex) (atom uint16, err syscall.Errno) {
 	return
 }
 
-func CreateWindowEx(exstyle uint32, classname *uint16, windowname *uint16, style uint32, x int32, y int32, width int32, height int32, wndparent uint32, menu uint32, instance uint32, param uintptr) (hwnd uint32, err syscall.Errno) {
+func CreateWindowEx(exstyle uint32, classname *uint16, windowname *uint16, style uint32, x int32, y int32, width int32, height int32, wndparent HWND, menu uintptr, instance uintptr, param uintptr) (hwnd HWND, err syscall.Errno) {
 	r0, _, e1 := syscall.Syscall12(procCreateWindowExW.Addr(), 12, uintptr(exstyle), uintptr(unsafe.Pointer(classname)), uintptr(unsafe.Pointer(windowname)), uintptr(style), uintptr(x), uintptr(y), uintptr(width), uintptr(height), uintptr(wndparent), uintptr(menu), uintptr(instance), uintptr(param))
-	hwnd = uint32(r0)
+	hwnd = HWND(r0)
 	if hwnd == 0 {
 		if e1 != 0 {
 			err = syscall.Errno(e1)
@@ -90,13 +91,13 @@ func CreateWindowEx(exstyle uint32, classname *uint16, windowname *uint16, style
 	return
 }
 
-func DefWindowProc(hwnd uint32, msg uint32, wparam uintptr, lparam uintptr) (lresult int32) {
+func DefWindowProc(hwnd HWND, msg uint32, wparam uintptr, lparam uintptr) (lresult uintptr) {
 	r0, _, _ := syscall.Syscall6(procDefWindowProcW.Addr(), 4, uintptr(hwnd), uintptr(msg), uintptr(wparam), uintptr(lparam), 0, 0)
-	lresult = int32(r0)
+	lresult = r0
 	return
 }
 
-func DestroyWindow(hwnd uint32) (err syscall.Errno) {
+func DestroyWindow(hwnd HWND) (err syscall.Errno) {
 	r1, _, e1 := syscall.Syscall(procDestroyWindow.Addr(), 1, uintptr(hwnd), 0, 0)
 	if int(r1) == 0 {
 		if e1 != 0 {
@@ -113,7 +114,7 @@ func PostQuitMessage(exitcode int32) {
 	return
 }
 
-func GetMessage(msg *Msg, hwnd uint32, MsgFilterMin uint32, MsgFilterMax uint32) (ret int32, err syscall.Errno) {
+func GetMessage(msg *Msg, hwnd HWND, MsgFilterMin uint32, MsgFilterMax uint32) (ret int32, err syscall.Errno) {
 	r0, _, e1 := syscall.Syscall6(procGetMessageW.Addr(), 4, uintptr(unsafe.Pointer(msg)), uintptr(hwnd), uintptr(MsgFilterMin), uintptr(MsgFilterMax), 0, 0)
 	ret = int32(r0)
 	if ret == -1 {
@@ -138,13 +139,13 @@ func DispatchMessage(msg *Msg) (ret int32) {
 	return
 }
 
-func SendMessage(hwnd uint32, msg uint32, wparam uintptr, lparam uintptr) (lresult int32) {
+func SendMessage(hwnd HWND, msg uint32, wparam uintptr, lparam uintptr) (lresult uintptr) {
 	r0, _, _ := syscall.Syscall6(procSendMessageW.Addr(), 4, uintptr(hwnd), uintptr(msg), uintptr(wparam), uintptr(lparam), 0, 0)
-	lresult = int32(r0)
+	lresult = r0
 	return
 }
 
-func PostMessage(hwnd uint32, msg uint32, wparam uintptr, lparam uintptr) (err syscall.Errno) {
+func PostMessage(hwnd HWND, msg uint32, wparam uintptr, lparam uintptr) (err syscall.Errno) {
 	r1, _, e1 := syscall.Syscall6(procPostMessageW.Addr(), 4, uintptr(hwnd), uintptr(msg), uintptr(wparam), uintptr(lparam), 0, 0)
 	if int(r1) == 0 {
 		if e1 != 0 {
@@ -160,7 +161,7 @@ func PostMessage(hwnd uint32, msg uint32, wparam uintptr, lparam uintptr) (err s
 
 func registerWindow(callbacks MsgHandlerMap, windowName string) {
 
-	wproc := syscall.NewCallback(func(hwnd, msg uint32, wparam uintptr, lparam uintptr) uintptr {
+	wproc := syscall.NewCallback(func(hwnd HWND, msg uint32, wparam uintptr, lparam uintptr) uintptr {
 		if result, handled := ProcNoDefault(hwnd, msg, wparam, lparam); handled {
 			return result
 		}
@@ -200,7 +201,7 @@ func registerWindow(callbacks MsgHandlerMap, windowName string) {
 	}
 }
 
-func createWindow(windowName string) uint32 {
+func createWindow(windowName string) HWND {
 	wcname := stringToUtf16Ptr(windowName)
 	hwnd, errno := CreateWindowEx(
 		0,
@@ -228,39 +229,13 @@ func pump() {
 	}
 }
 
-func looseEqual(a, b string) bool {
-	if re, err := regexp.Compile(`\s+`); err != nil {
-		log.Panic(err)
-	} else {
-		a = re.ReplaceAllLiteralString(a, "")
-		b = re.ReplaceAllLiteralString(b, "")
-	}
-	return a == b
-}
-
-func expectDomError(code HLDOM_RESULT) {
-	if err := recover(); err == nil {
-		log.Panic("Expected a DomError but got no error")
-	} else if de, ok := err.(*DomError); !ok {
-		log.Panic("Expected DomError, instead got: ", err)
-	} else if de.Result != code {
-		log.Panicf("Expected DomError with code %s, but got code %s instead ", domResultAsString(code), domResultAsString(de.Result))
-	}
-}
-
-func expectPanic() {
-	if err := recover(); err == nil {
-		log.Panic("Expected a panic but didn't get one")
-	}
-}
-
-func testWithHtml(html string, test func(hwnd uint32)) {
+func testWithHtml(html string, test func(hwnd HWND)) {
 	if !registeredClasses["html"] {
 		m := make(MsgHandlerMap, 32)
 		for k, v := range defaultHandlerMap {
 			m[k] = v
 		}
-		m[WM_CREATE] = func(hwnd uint32) interface{} {
+		m[WM_CREATE] = func(hwnd HWND) interface{} {
 			ret := defaultHandlerMap[WM_CREATE](hwnd)
 			if err := LoadHtml(hwnd, []byte(<-testPages), ""); err != nil {
 				log.Panic(err)
@@ -280,51 +255,37 @@ func testWithHtml(html string, test func(hwnd uint32)) {
 
 // Variables and types for testing
 
-type MsgHandler func(uint32) interface{}
+type MsgHandler func(HWND) interface{}
 type MsgHandlerMap map[uint32]MsgHandler
 
 var defaultHandlerMap = MsgHandlerMap{
-	WM_CREATE: func(hwnd uint32) interface{} {
+	WM_CREATE: func(hwnd HWND) interface{} {
 		//log.Print("WM_CREATE, hwnd = ", hwnd)
 		AttachNotifyHandler(hwnd, notifyHandler)
 		AttachWindowEventHandler(hwnd, windowEventHandler)
 		return 0
 	},
-	WM_SHOWWINDOW: func(hwnd uint32) interface{} {
+	WM_SHOWWINDOW: func(hwnd HWND) interface{} {
 		return 0
 	},
-	WM_ERASEBKGND: func(hwnd uint32) interface{} {
+	WM_ERASEBKGND: func(hwnd HWND) interface{} {
 		return 0
 	},
-	WM_CLOSE: func(hwnd uint32) interface{} {
+	WM_CLOSE: func(hwnd HWND) interface{} {
 		//log.Print("WM_CLOSE, hwnd = ", hwnd)
 		DetachWindowEventHandler(hwnd)
 		DetachNotifyHandler(hwnd)
 		DestroyWindow(hwnd)
 		return nil
 	},
-	WM_DESTROY: func(hwnd uint32) interface{} {
+	WM_DESTROY: func(hwnd HWND) interface{} {
 		//log.Print("WM_DESTROY, hwnd = ", hwnd)
-		//DumpObjectCounts()
+		//log.Printf("%+v", GlobalStats())
 		PostQuitMessage(0)
 		return 0
 	},
 }
 
-// Page templates used for various tests
-var pages = map[string]string{
-	"empty":       ``,
-	"page":        `<html><body></body></html>`,
-	"one-div":     `<div id="a"></div>`,
-	"two-divs":    `<div id="a"></div><div id="b"></div>`,
-	"three-divs":  `<div id="a"></div><div id="b"></div><div id="c"></div>`,
-	"nested-divs": `<div id="a"><div id="b"></div></div>`,
-	"attr":        `<div id="a" first="5" second="5.1" third="yes"></div>`,
-	"css":         `<div style="left:10; opacity:0.5; text-align:center;"></div>`,
-	"classes":     `<div class="one  two three"></div><div></div>`,
-	"input":	   `<widget type="text" value="test string"></widget>`,
-}
-
 // Notify handler deals with WM_NOTIFY messages sent by htmlayout
 var notifyHandler = &NotifyHandler{}
 
@@ -335,7 +296,7 @@ var windowEventHandler = &EventHandler{}
 
 func TestBasicWindow(t *testing.T) {
 	// A channel to receive the hwnd once the window is created
-	created := make(chan uint32)
+	created := make(chan HWND)
 
 	// Wait until the window is created, then post a message to close it
 	go func() {
@@ -349,7 +310,7 @@ func TestBasicWindow(t *testing.T) {
 		for k, v := range defaultHandlerMap {
 			handler[k] = v
 		}
-		handler[WM_CREATE] = func(hwnd uint32) interface{} {
+		handler[WM_CREATE] = func(hwnd HWND) interface{} {
 			created <- hwnd
 			return defaultHandlerMap[WM_CREATE](hwnd)
 		}
@@ -360,11 +321,11 @@ func TestBasicWindow(t *testing.T) {
 }
 
 func TestLoadHtml(t *testing.T) {
-	testWithHtml(pages["page"], func(hwnd uint32) {})
+	testWithHtml(pages["page"], func(hwnd HWND) {})
 }
 
 func TestRootElement(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		if e := RootElement(hwnd); e == nil {
 			t.Fatal("Could not get root elem")
 		}
@@ -372,26 +333,26 @@ func TestRootElement(t *testing.T) {
 }
 
 func TestHandle(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		e := RootElement(hwnd)
-		if h := e.Handle(); h == nil {
+		if h := e.Handle(); h == BAD_HELEMENT {
 			t.Fatal("Handle was nil")
 		}
 	})
 }
 
 func TestRelease(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		e := RootElement(hwnd)
 		e.Release()
-		if h := e.Handle(); h != nil {
+		if h := e.Handle(); h != BAD_HELEMENT {
 			t.Fatal("Released but handle is not nil, finalizer not called?")
 		}
 	})
 }
 
 func TestChildCount(t *testing.T) {
-	testWithHtml(pages["two-divs"], func(hwnd uint32) {
+	testWithHtml(pages["two-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		if count := root.ChildCount(); count != 2 {
 			t.Fatal("Expected two divs as children")
@@ -400,7 +361,7 @@ func TestChildCount(t *testing.T) {
 }
 
 func TestChildCount2(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		if count := root.ChildCount(); count != 1 {
 			t.Fatal("Expected one divs as child")
@@ -409,7 +370,7 @@ func TestChildCount2(t *testing.T) {
 }
 
 func TestChild(t *testing.T) {
-	testWithHtml(pages["two-divs"], func(hwnd uint32) {
+	testWithHtml(pages["two-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d1 := root.Child(0)
 		d2 := root.Child(1)
@@ -420,7 +381,7 @@ func TestChild(t *testing.T) {
 }
 
 func TestIndex(t *testing.T) {
-	testWithHtml(pages["two-divs"], func(hwnd uint32) {
+	testWithHtml(pages["two-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d1 := root.Child(0)
 		d2 := root.Child(1)
@@ -434,7 +395,7 @@ func TestIndex(t *testing.T) {
 }
 
 func TestEquals(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d1 := root.Child(0)
 		if root.Equals(d1) {
@@ -447,7 +408,7 @@ func TestEquals(t *testing.T) {
 }
 
 func TestParent(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d1 := root.Child(0)
 		d2 := d1.Child(0)
@@ -465,7 +426,7 @@ func TestParent(t *testing.T) {
 }
 
 func TestSelect(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		results := root.Select("div > div")
 		if len(results) != 1 {
@@ -479,7 +440,7 @@ func TestSelect(t *testing.T) {
 }
 
 func TestSelectParent(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		if result := root.SelectParent("*:has-child-of-type(div):has-child-of-type(div)"); !result.Equals(root) {
 			t.Fatal("Expected to match root element")
@@ -488,7 +449,7 @@ func TestSelectParent(t *testing.T) {
 }
 
 func TestSelectParentLimit(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d1 := root.Child(0)
 		d2 := d1.Child(0)
@@ -511,7 +472,7 @@ func TestSelectParentLimit(t *testing.T) {
 }
 
 func TestType(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		elemType := root.Type()
 		if elemType != "html" {
@@ -525,7 +486,7 @@ func TestType(t *testing.T) {
 }
 
 func TestOuterHtml(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		expected := "<html>" + pages["nested-divs"] + "</html>"
 		if !looseEqual(expected, root.OuterHtml()) {
@@ -535,7 +496,7 @@ func TestOuterHtml(t *testing.T) {
 }
 
 func TestHtml(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		if !looseEqual(root.Html(), pages["nested-divs"]) {
 			t.Fatal("Inner html of root elem should match original html")
@@ -554,7 +515,7 @@ func TestHtml(t *testing.T) {
 }
 
 func TestInsertChild(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		e := NewElement("div")
@@ -571,7 +532,7 @@ func TestInsertChild(t *testing.T) {
 }
 
 func TestAppendChild(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		e := NewElement("div")
@@ -588,7 +549,7 @@ func TestAppendChild(t *testing.T) {
 }
 
 func TestDetach(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		inner := d.Child(0)
@@ -608,7 +569,7 @@ func TestDetach(t *testing.T) {
 }
 
 func TestDelete(t *testing.T) {
-	testWithHtml(pages["nested-divs"], func(hwnd uint32) {
+	testWithHtml(pages["nested-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		inner := d.Child(0)
@@ -618,16 +579,16 @@ func TestDelete(t *testing.T) {
 			t.Fatal("Element should not have any contents after detaching its only child")
 		}
 
-		// Should not be able to put the deleted element back in, should get invalid handle error
+		// Should not be able to put the deleted element back in
 		func() {
-			defer expectDomError(HLDOM_INVALID_HANDLE)
+			defer expectReleasedError()
 			d.AppendChild(inner)
 		}()
 	})
 }
 
 func TestClone(t *testing.T) {
-	testWithHtml(`<div>a</div>`, func(hwnd uint32) {
+	testWithHtml(`<div>a</div>`, func(hwnd HWND) {
 		root := RootElement(hwnd)
 		clone := root.Child(0).Clone()
 		if clone.Type() != "div" {
@@ -640,7 +601,7 @@ func TestClone(t *testing.T) {
 }
 
 func TestSwap(t *testing.T) {
-	testWithHtml(pages["two-divs"], func(hwnd uint32) {
+	testWithHtml(pages["two-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		a := root.Child(0)
 		b := root.Child(1)
@@ -662,7 +623,7 @@ func TestSortChildren(t *testing.T) {
 		}
 		return -1
 	}
-	testWithHtml(`<div>c</div><div>b</div><div>a</div>`, func(hwnd uint32) {
+	testWithHtml(`<div>c</div><div>b</div><div>a</div>`, func(hwnd HWND) {
 		root := RootElement(hwnd)
 		root.SortChildren(cmp)
 		if !looseEqual(root.Html(), `<div>a</div><div>b</div><div>c</div>`) {
@@ -682,7 +643,7 @@ func TestSortChildrenRange(t *testing.T) {
 		}
 		return -1
 	}
-	testWithHtml(`<div>c</div><div>b</div><div>a</div>`, func(hwnd uint32) {
+	testWithHtml(`<div>c</div><div>b</div><div>a</div>`, func(hwnd HWND) {
 		root := RootElement(hwnd)
 		root.SortChildrenRange(0, 2, cmp)
 		if !looseEqual(root.Html(), `<div>b</div><div>c</div><div>a</div>`) {
@@ -692,7 +653,7 @@ func TestSortChildrenRange(t *testing.T) {
 }
 
 func TestHwnd(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		if root.Hwnd() != hwnd {
 			t.Fatal("Root should report the same hwnd it was created with")
@@ -705,7 +666,7 @@ func TestHwnd(t *testing.T) {
 
 // TODO: Figure out how this test should differ from the test for Hwnd()
 func TestRootHwnd(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		if root.RootHwnd() != hwnd {
 			t.Fatal("Root should report the same root hwnd it was created with")
@@ -725,7 +686,7 @@ func TestSetHtml(t *testing.T) {
 		d.SetHtml("<span></span>")
 	}()
 
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		d.SetHtml("<span></span>")
@@ -736,7 +697,7 @@ func TestSetHtml(t *testing.T) {
 }
 
 func TestPrependHtml(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		root.PrependHtml("<span></span>")
 		if !looseEqual(root.Html(), `<span></span><div id="a"></div>`) {
@@ -746,7 +707,7 @@ func TestPrependHtml(t *testing.T) {
 }
 
 func TestAppendHtml(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		root.AppendHtml("<span></span>")
 		if !looseEqual(root.Html(), `<div id="a"></div><span></span>`) {
@@ -756,7 +717,7 @@ func TestAppendHtml(t *testing.T) {
 }
 
 func TestSetText(t *testing.T) {
-	testWithHtml(pages["one-div"], func(hwnd uint32) {
+	testWithHtml(pages["one-div"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		root.SetText("Hi")
 		if !looseEqual(root.Html(), `Hi`) {
@@ -766,7 +727,7 @@ func TestSetText(t *testing.T) {
 }
 
 func TestAttrCount(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		if count := d.AttrCount(); count != 4 {
@@ -776,7 +737,7 @@ func TestAttrCount(t *testing.T) {
 }
 
 func TestAttrByIndex(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		expectedKeys := []string{"id", "first", "second", "third"}
@@ -791,7 +752,7 @@ func TestAttrByIndex(t *testing.T) {
 }
 
 func TestAttr(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -820,7 +781,7 @@ func TestAttr(t *testing.T) {
 }
 
 func TestAttrAsFloatOnInt(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -835,7 +796,7 @@ func TestAttrAsFloatOnInt(t *testing.T) {
 }
 
 func TestAttrAsFloatOnFloat(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -850,7 +811,7 @@ func TestAttrAsFloatOnFloat(t *testing.T) {
 }
 
 func TestAttrAsFloatOnString(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -865,7 +826,7 @@ func TestAttrAsFloatOnString(t *testing.T) {
 }
 
 func TestAttrAsFloatOnInvalid(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -876,7 +837,7 @@ func TestAttrAsFloatOnInvalid(t *testing.T) {
 }
 
 func TestAttrAsIntOnInt(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -891,7 +852,7 @@ func TestAttrAsIntOnInt(t *testing.T) {
 }
 
 func TestAttrAsIntOnFloat(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -906,7 +867,7 @@ func TestAttrAsIntOnFloat(t *testing.T) {
 }
 
 func TestAttrAsIntOnString(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -921,7 +882,7 @@ func TestAttrAsIntOnString(t *testing.T) {
 }
 
 func TestAttrAsIntOnInvalid(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -932,7 +893,7 @@ func TestAttrAsIntOnInvalid(t *testing.T) {
 }
 
 func TestRemoveAttr(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		if _, exists := d.Attr("second"); !exists {
@@ -949,7 +910,7 @@ func TestRemoveAttr(t *testing.T) {
 }
 
 func TestSetAttrFloat32(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		pi := 3.14159
@@ -966,7 +927,7 @@ func TestSetAttrFloat32(t *testing.T) {
 }
 
 func TestSetAttrFloat64(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		pi := 3.14159
@@ -983,7 +944,7 @@ func TestSetAttrFloat64(t *testing.T) {
 }
 
 func TestSetAttrString(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		d.SetAttr("myString", "hello")
@@ -997,7 +958,7 @@ func TestSetAttrString(t *testing.T) {
 }
 
 func TestSetAttrInt(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		d.SetAttr("myInt", 9)
@@ -1011,7 +972,7 @@ func TestSetAttrInt(t *testing.T) {
 }
 
 func TestSetAttrOverwrite(t *testing.T) {
-	testWithHtml(pages["attr"], func(hwnd uint32) {
+	testWithHtml(pages["attr"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -1026,7 +987,7 @@ func TestSetAttrOverwrite(t *testing.T) {
 }
 
 func TestHasClass(t *testing.T) {
-	testWithHtml(pages["classes"], func(hwnd uint32) {
+	testWithHtml(pages["classes"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		d2 := root.Child(1)
@@ -1044,7 +1005,7 @@ func TestHasClass(t *testing.T) {
 }
 
 func TestAddClass(t *testing.T) {
-	testWithHtml(pages["classes"], func(hwnd uint32) {
+	testWithHtml(pages["classes"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		d2 := root.Child(1)
@@ -1079,7 +1040,7 @@ func TestAddClass(t *testing.T) {
 }
 
 func TestRemoveClass(t *testing.T) {
-	testWithHtml(pages["classes"], func(hwnd uint32) {
+	testWithHtml(pages["classes"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 		d2 := root.Child(1)
@@ -1119,7 +1080,7 @@ func TestRemoveClass(t *testing.T) {
 }
 
 func TestStyle(t *testing.T) {
-	testWithHtml(pages["css"], func(hwnd uint32) {
+	testWithHtml(pages["css"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -1150,7 +1111,7 @@ func TestStyle(t *testing.T) {
 }
 
 func TestSetStyleFloat32(t *testing.T) {
-	testWithHtml(pages["css"], func(hwnd uint32) {
+	testWithHtml(pages["css"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -1167,7 +1128,7 @@ func TestSetStyleFloat32(t *testing.T) {
 }
 
 func TestSetStyleFloat64(t *testing.T) {
-	testWithHtml(pages["css"], func(hwnd uint32) {
+	testWithHtml(pages["css"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -1183,7 +1144,7 @@ func TestSetStyleFloat64(t *testing.T) {
 }
 
 func TestSetStyleString(t *testing.T) {
-	testWithHtml(pages["css"], func(hwnd uint32) {
+	testWithHtml(pages["css"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -1197,7 +1158,7 @@ func TestSetStyleString(t *testing.T) {
 }
 
 func TestSetStyleInt(t *testing.T) {
-	testWithHtml(pages["css"], func(hwnd uint32) {
+	testWithHtml(pages["css"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -1211,7 +1172,7 @@ func TestSetStyleInt(t *testing.T) {
 }
 
 func TestSetStyleOverwrite(t *testing.T) {
-	testWithHtml(pages["css"], func(hwnd uint32) {
+	testWithHtml(pages["css"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d := root.Child(0)
 
@@ -1226,7 +1187,7 @@ func TestSetStyleOverwrite(t *testing.T) {
 }
 
 func TestAttachSameHandlerToTwoElements(t *testing.T) {
-	testWithHtml(pages["two-divs"], func(hwnd uint32) {
+	testWithHtml(pages["two-divs"], func(hwnd HWND) {
 		root := RootElement(hwnd)
 		d1 := root.Child(0)
 		d2 := root.Child(1)
@@ -1240,7 +1201,7 @@ func TestAttachSameHandlerToTwoElements(t *testing.T) {
 }
 
 // func TestControlValueType(t *testing.T) {
-// 	testWithHtml(pages["input"], func(hwnd uint32) {
+// 	testWithHtml(pages["input"], func(hwnd HWND) {
 // 		input := RootElement(hwnd).Child(0)
 // 		if dataType := input.ValueType(); dataType != T_STRING {
 // 			t.Fatal("Value type should be string")
@@ -1249,7 +1210,7 @@ func TestAttachSameHandlerToTwoElements(t *testing.T) {
 // }
 
 func TestValueAsString(t *testing.T) {
-	testWithHtml(pages["input"], func(hwnd uint32) {
+	testWithHtml(pages["input"], func(hwnd HWND) {
 		input := RootElement(hwnd).Child(0)
 		if s, err := input.ValueAsString(); err != nil {
 			t.Fatal(err)
@@ -1260,7 +1221,7 @@ func TestValueAsString(t *testing.T) {
 }
 
 func TestSetValueString(t *testing.T) {
-	testWithHtml(pages["input"], func(hwnd uint32) {
+	testWithHtml(pages["input"], func(hwnd HWND) {
 		input := RootElement(hwnd).Child(0)
 		input.SetValue("woohoo")
 		if s, err := input.ValueAsString(); err != nil {
@@ -0,0 +1,118 @@
+//go:build windows
+
+package gohl
+
+/*
+#cgo CFLAGS: -I./htmlayout/include
+
+#include <htmlayout.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+)
+
+// The value functions of the HTMLayout dll
+type htmlayoutValues struct{}
+
+var values valueApi = htmlayoutValues{}
+
+func cValue(v *JsonValue) *C.VALUE {
+	return (*C.VALUE)(unsafe.Pointer(v))
+}
+
+func (htmlayoutValues) Init(v *JsonValue) {
+	C.ValueInit(cValue(v))
+}
+
+func (htmlayoutValues) Clear(v *JsonValue) {
+	C.ValueClear(cValue(v))
+}
+
+func (htmlayoutValues) Copy(dst, src *JsonValue) VALUE_RESULT {
+	return VALUE_RESULT(C.ValueCopy(cValue(dst), cValue(src)))
+}
+
+func (htmlayoutValues) IntData(v *JsonValue) (int, VALUE_RESULT) {
+	var i C.INT
+	ret := C.ValueIntData(cValue(v), &i)
+	return int(i), VALUE_RESULT(ret)
+}
+
+func (htmlayoutValues) SetIntData(v *JsonValue, i int, t, units uint32) VALUE_RESULT {
+	return VALUE_RESULT(C.ValueIntDataSet(cValue(v), C.INT(i), C.UINT(t), C.UINT(units)))
+}
+
+func (htmlayoutValues) Int64Data(v *JsonValue) (int64, VALUE_RESULT) {
+	var i C.INT64
+	ret := C.ValueInt64Data(cValue(v), &i)
+	return int64(i), VALUE_RESULT(ret)
+}
+
+func (htmlayoutValues) SetInt64Data(v *JsonValue, i int64, t, units uint32) VALUE_RESULT {
+	return VALUE_RESULT(C.ValueInt64DataSet(cValue(v), C.INT64(i), C.UINT(t), C.UINT(units)))
+}
+
+func (htmlayoutValues) FloatData(v *JsonValue) (float64, VALUE_RESULT) {
+	var f C.FLOAT_VALUE
+	ret := C.ValueFloatData(cValue(v), &f)
+	return float64(f), VALUE_RESULT(ret)
+}
+
+func (htmlayoutValues) SetFloatData(v *JsonValue, f float64, t, units uint32) VALUE_RESULT {
+	return VALUE_RESULT(C.ValueFloatDataSet(cValue(v), C.FLOAT_VALUE(f), C.UINT(t), C.UINT(units)))
+}
+
+func (htmlayoutValues) StringData(v *JsonValue) (string, VALUE_RESULT) {
+	var chars C.LPCWSTR
+	var length C.UINT
+	if ret := C.ValueStringData(cValue(v), &chars, &length); ret != HV_OK || length == 0 {
+		return "", VALUE_RESULT(ret)
+	}
+	return utf16ToStringLength((*uint16)(unsafe.Pointer(chars)), int(length)), HV_OK
+}
+
+func (htmlayoutValues) SetStringData(v *JsonValue, s string, units uint32) VALUE_RESULT {
+	chars := stringToUtf16(s)
+	return VALUE_RESULT(C.ValueStringDataSet(cValue(v), (C.LPCWSTR)(unsafe.Pointer(&chars[0])), C.UINT(len(chars)-1), C.UINT(units)))
+}
+
+func (htmlayoutValues) BinaryData(v *JsonValue) ([]byte, VALUE_RESULT) {
+	var bytes C.LPCBYTE
+	var length C.UINT
+	if ret := C.ValueBinaryData(cValue(v), &bytes, &length); ret != HV_OK {
+		return nil, VALUE_RESULT(ret)
+	}
+	return C.GoBytes(unsafe.Pointer(bytes), C.int(length)), HV_OK
+}
+
+func (htmlayoutValues) SetBinaryData(v *JsonValue, b []byte, t, units uint32) VALUE_RESULT {
+	var bytes C.LPCBYTE
+	if len(b) > 0 {
+		bytes = (C.LPCBYTE)(unsafe.Pointer(&b[0]))
+	}
+	return VALUE_RESULT(C.ValueBinaryDataSet(cValue(v), bytes, C.UINT(len(b)), C.UINT(t), C.UINT(units)))
+}
+
+func (htmlayoutValues) ElementsCount(v *JsonValue) (int, VALUE_RESULT) {
+	var n C.INT
+	ret := C.ValueElementsCount(cValue(v), &n)
+	return int(n), VALUE_RESULT(ret)
+}
+
+func (htmlayoutValues) NthElementValue(v *JsonValue, n int, item *JsonValue) VALUE_RESULT {
+	return VALUE_RESULT(C.ValueNthElementValue(cValue(v), C.INT(n), cValue(item)))
+}
+
+func (htmlayoutValues) NthElementKey(v *JsonValue, n int, key *JsonValue) VALUE_RESULT {
+	return VALUE_RESULT(C.ValueNthElementKey(cValue(v), C.INT(n), cValue(key)))
+}
+
+func (htmlayoutValues) SetNthElementValue(v *JsonValue, n int, item *JsonValue) VALUE_RESULT {
+	return VALUE_RESULT(C.ValueNthElementValueSet(cValue(v), C.INT(n), cValue(item)))
+}
+
+func (htmlayoutValues) SetValueToKey(v *JsonValue, key, item *JsonValue) VALUE_RESULT {
+	return VALUE_RESULT(C.ValueSetValueToKey(cValue(v), cValue(key), cValue(item)))
+}
@@ -0,0 +1,462 @@
+package gohl
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// The HTMLayout value functions that JsonValue is built on.  Each method
+// mirrors one of them and reports failure the same way they do, with a
+// VALUE_RESULT.  Outside of Windows, values are kept in Go.  The setters
+// replace whatever the value held.
+type valueApi interface {
+	Init(v *JsonValue)
+	Clear(v *JsonValue)
+	Copy(dst, src *JsonValue) VALUE_RESULT
+
+	IntData(v *JsonValue) (int, VALUE_RESULT)
+	SetIntData(v *JsonValue, i int, t, units uint32) VALUE_RESULT
+	Int64Data(v *JsonValue) (int64, VALUE_RESULT)
+	SetInt64Data(v *JsonValue, i int64, t, units uint32) VALUE_RESULT
+	FloatData(v *JsonValue) (float64, VALUE_RESULT)
+	SetFloatData(v *JsonValue, f float64, t, units uint32) VALUE_RESULT
+	StringData(v *JsonValue) (string, VALUE_RESULT)
+	SetStringData(v *JsonValue, s string, units uint32) VALUE_RESULT
+	BinaryData(v *JsonValue) ([]byte, VALUE_RESULT)
+	SetBinaryData(v *JsonValue, b []byte, t, units uint32) VALUE_RESULT
+
+	// Arrays and maps.  The items set are copied.
+	ElementsCount(v *JsonValue) (int, VALUE_RESULT)
+	NthElementValue(v *JsonValue, n int, item *JsonValue) VALUE_RESULT
+	NthElementKey(v *JsonValue, n int, key *JsonValue) VALUE_RESULT
+	SetNthElementValue(v *JsonValue, n int, item *JsonValue) VALUE_RESULT
+	SetValueToKey(v *JsonValue, key, item *JsonValue) VALUE_RESULT
+}
+
+func valueError(ret VALUE_RESULT, message ...interface{}) error {
+	if ret == HV_OK {
+		return nil
+	}
+	return &ValueError{ret, fmt.Sprint(message...)}
+}
+
+func (v *JsonValue) Type() ValueType { return ValueType(v.T) }
+
+// Prepares a JsonValue declared in Go for use.  Values received from HTMLayout
+// are already initialized.
+func (v *JsonValue) Init() {
+	values.Init(v)
+}
+
+// Frees whatever the value holds and makes it undefined, releasing the
+// references to elements that SetValue took for it.  Values that HTMLayout
+// hands out and clears itself, such as an event's Data, are not for Clear.
+func (v *JsonValue) Clear() {
+	v.releaseOwned()
+	values.Clear(v)
+}
+
+// The references to elements that SetValue took, by the value it took them
+// for.  Elements in values filled in by HTMLayout belong to HTMLayout, so only
+// these are ever released.
+var (
+	ownedElementsMutex sync.Mutex
+	ownedElements      = make(map[*JsonValue][]HELEMENT, 16)
+)
+
+// Makes the references taken for v its own, to be released by releaseOwned
+func (v *JsonValue) own(handles []HELEMENT) {
+	if len(handles) == 0 {
+		return
+	}
+	ownedElementsMutex.Lock()
+	ownedElements[v] = append(ownedElements[v], handles...)
+	ownedElementsMutex.Unlock()
+}
+
+// Drops the references v owns, however deeply the elements are nested
+func (v *JsonValue) releaseOwned() {
+	ownedElementsMutex.Lock()
+	handles := ownedElements[v]
+	delete(ownedElements, v)
+	ownedElementsMutex.Unlock()
+	for _, he := range handles {
+		unuse(he)
+	}
+}
+
+// The U field of a T_DATE value
+const (
+	DT_HAS_DATE    = 0x01
+	DT_HAS_TIME    = 0x02
+	DT_HAS_SECONDS = 0x04
+	DT_UTC         = 0x10
+)
+
+// The number of 100ns FILETIME ticks between 1601 and the unix epoch
+const filetimeUnixOffset = 116444736000000000
+
+// Converts a T_DATE value's FILETIME.  Dates without DT_UTC hold the local
+// wall clock time.
+func filetimeToTime(ft int64, units uint32) time.Time {
+	d := ft - filetimeUnixOffset
+	t := time.Unix(d/1e7, d%1e7*100).UTC()
+	if units&DT_UTC == 0 {
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.Local)
+	}
+	return t
+}
+
+// Returns the FILETIME of a T_DATE value holding t in UTC, and its units
+func timeToFiletime(t time.Time) (int64, uint32) {
+	ft := t.Unix()*1e7 + int64(t.Nanosecond()/100) + filetimeUnixOffset
+	return ft, DT_HAS_DATE | DT_HAS_TIME | DT_HAS_SECONDS | DT_UTC
+}
+
+// A T_CURRENCY amount, in ten thousandths
+type Currency int64
+
+func CurrencyFromFloat(f float64) Currency {
+	return Currency(math.Round(f * 10000))
+}
+
+func (c Currency) Float() float64 {
+	return float64(c) / 10000
+}
+
+// Formats the amount with four decimal places
+func (c Currency) String() string {
+	sign, u := "", uint64(c)
+	if c < 0 {
+		sign, u = "-", uint64(-c)
+	}
+	return fmt.Sprintf("%s%d.%04d", sign, u/10000, u%10000)
+}
+
+// The U field of a T_LENGTH value
+type LengthUnit uint32
+
+const (
+	UT_EM  = 1 // height of the element's font
+	UT_EX  = 2 // height of letter 'x'
+	UT_PR  = 3 // percent
+	UT_SP  = 4 // spring, a fraction of the free space
+	UT_PX  = 7 // pixels
+	UT_IN  = 8 // inches
+	UT_CM  = 9
+	UT_MM  = 10
+	UT_PT  = 11 // points, 1/72 of an inch
+	UT_PC  = 12 // picas, 12 points
+	UT_DIP = 13 // device independent pixels, 1/96 of an inch
+)
+
+var lengthUnitSuffixes = []codeName{
+	{UT_EM, "em"},
+	{UT_EX, "ex"},
+	{UT_PR, "%"},
+	{UT_SP, "*"},
+	{UT_PX, "px"},
+	{UT_IN, "in"},
+	{UT_CM, "cm"},
+	{UT_MM, "mm"},
+	{UT_PT, "pt"},
+	{UT_PC, "pc"},
+	{UT_DIP, "dip"},
+}
+
+// Returns the unit as written in css, as in "px"
+func (u LengthUnit) String() string { return codeString(uint32(u), lengthUnitSuffixes) }
+
+// A T_LENGTH value
+type Length struct {
+	Value float64
+	Unit  LengthUnit
+}
+
+func (l Length) String() string {
+	for _, n := range lengthUnitSuffixes {
+		if n.value == uint32(l.Unit) {
+			return fmt.Sprintf("%g%s", l.Value, n.name)
+		}
+	}
+	return fmt.Sprintf("%g(%s)", l.Value, l.Unit)
+}
+
+// Reports whether JsonValue holds values of v's type as they are, rather than
+// as the bools, numbers, strings, arrays and maps they are made of
+func isNativeValue(v interface{}) bool {
+	switch v := v.(type) {
+	case time.Time, Currency, Length, []byte:
+		return true
+	case *Element:
+		return v != nil
+	}
+	return false
+}
+
+// Converts the nth item, or the nth key of a map, of an array or map value
+func (v *JsonValue) nthValue(n int, key bool) (interface{}, error) {
+	var item JsonValue
+	item.Init()
+
+	// The copy shares the references of the value it came from
+	defer values.Clear(&item)
+	var ret VALUE_RESULT
+	if key {
+		ret = values.NthElementKey(v, n, &item)
+	} else {
+		ret = values.NthElementValue(v, n, &item)
+	}
+	if err := valueError(ret, "Failed to read element ", n); err != nil {
+		return nil, err
+	}
+	return item.Value()
+}
+
+/*
+Value
+
+Converts the value to Go:
+
+	T_UNDEFINED, T_NULL   nil
+	T_BOOL                bool
+	T_INT                 int
+	T_FLOAT               float64
+	T_STRING              string
+	T_DATE                time.Time
+	T_CURRENCY            Currency
+	T_LENGTH              Length
+	T_ARRAY               []interface{}
+	T_MAP                 map[string]interface{}
+	T_BYTES               []byte
+	T_DOM_OBJECT          *Element, or nil for a null element
+
+Functions and script objects fail with a ValueError of HV_INCOMPATIBLE_TYPE.
+An *Element must be released when it is no longer needed.
+*/
+func (v *JsonValue) Value() (interface{}, error) {
+	switch v.T {
+	case T_UNDEFINED, T_NULL:
+		return nil, nil
+	case T_BOOL:
+		i, ret := values.IntData(v)
+		return i != 0, valueError(ret, "Failed to read bool data")
+	case T_INT:
+		i, ret := values.IntData(v)
+		return i, valueError(ret, "Failed to read int data")
+	case T_FLOAT:
+		f, ret := values.FloatData(v)
+		return f, valueError(ret, "Failed to read float data")
+	case T_STRING:
+		s, ret := values.StringData(v)
+		return s, valueError(ret, "Failed to read string data")
+	case T_DATE:
+		ft, ret := values.Int64Data(v)
+		if err := valueError(ret, "Failed to read date data"); err != nil {
+			return nil, err
+		}
+		return filetimeToTime(ft, v.U), nil
+	case T_CURRENCY:
+		i, ret := values.Int64Data(v)
+		return Currency(i), valueError(ret, "Failed to read currency data")
+	case T_LENGTH:
+		f, ret := values.FloatData(v)
+		return Length{f, LengthUnit(v.U)}, valueError(ret, "Failed to read length data")
+	case T_ARRAY:
+		n, ret := values.ElementsCount(v)
+		if err := valueError(ret, "Failed to count elements"); err != nil {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			var err error
+			if items[i], err = v.nthValue(i, false); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	case T_MAP:
+		n, ret := values.ElementsCount(v)
+		if err := valueError(ret, "Failed to count elements"); err != nil {
+			return nil, err
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := v.nthValue(i, true)
+			if err != nil {
+				return nil, err
+			}
+			item, err := v.nthValue(i, false)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(key)] = item
+		}
+		return m, nil
+	case T_BYTES:
+		b, ret := values.BinaryData(v)
+		if err := valueError(ret, "Failed to read binary data"); err != nil {
+			return nil, err
+		}
+		return b, nil
+	case T_DOM_OBJECT:
+		i, ret := values.Int64Data(v)
+		if err := valueError(ret, "Failed to read dom object data"); err != nil {
+			return nil, err
+		}
+		if HELEMENT(i) == BAD_HELEMENT {
+			return nil, nil
+		}
+		return NewElementFromHandle(HELEMENT(i)), nil
+	}
+	return nil, &ValueError{HV_INCOMPATIBLE_TYPE, fmt.Sprint("Cannot convert a value of type ", v.Type(), " to Go")}
+}
+
+// Like Value, failing with a ValueError of HV_INCOMPATIBLE_TYPE if the value
+// isn't of type t
+func (v *JsonValue) ValueOfType(t ValueType) (interface{}, error) {
+	if v.Type() != t {
+		return nil, &ValueError{HV_INCOMPATIBLE_TYPE, fmt.Sprint("Expected a value of type ", t, ", got ", v.Type())}
+	}
+	return v.Value()
+}
+
+/*
+SetValue
+
+Replaces the value with the conversion of x, which may be of any type Value
+returns.  Other numbers are stored as T_INT if they fit in 32 bits, otherwise
+as T_FLOAT, and other slices, maps, structs and pointers are converted as
+FunctionRegistry converts results.  Types that can't be converted fail with a
+ValueError of HV_INCOMPATIBLE_TYPE.  A nil slice, map or pointer is stored as
+T_NULL.
+
+An *Element is stored as T_DOM_OBJECT along with a reference of the value's
+own, so the *Element may be released while the value is in use.  The value
+owns that reference, and Clear, or setting it again, releases it.  Setting a
+value releases only the references SetValue took for it, so it is safe on
+values filled in by HTMLayout; the elements HTMLayout put in them are left
+alone.
+*/
+func (v *JsonValue) SetValue(x interface{}) error {
+	v.releaseOwned()
+	var taken []HELEMENT
+	err := v.setValue(x, &taken)
+	v.own(taken)
+	return err
+}
+
+// Does the work of SetValue, adding the elements it takes a reference to to
+// taken
+func (v *JsonValue) setValue(x interface{}, taken *[]HELEMENT) error {
+	switch x := x.(type) {
+	case nil:
+		return valueError(values.SetIntData(v, 0, T_NULL, 0), "Failed to set null data")
+	case bool:
+		b := 0
+		if x {
+			b = 1
+		}
+		return valueError(values.SetIntData(v, b, T_BOOL, 0), "Failed to set bool data")
+	case int:
+		if x < math.MinInt32 || x > math.MaxInt32 {
+			return valueError(values.SetFloatData(v, float64(x), T_FLOAT, 0), "Failed to set float data")
+		}
+		return valueError(values.SetIntData(v, x, T_INT, 0), "Failed to set int data")
+	case float64:
+		return valueError(values.SetFloatData(v, x, T_FLOAT, 0), "Failed to set float data")
+	case string:
+		return valueError(values.SetStringData(v, x, 0), "Failed to set string data")
+	case time.Time:
+		ft, units := timeToFiletime(x)
+		return valueError(values.SetInt64Data(v, ft, T_DATE, units), "Failed to set date data")
+	case Currency:
+		return valueError(values.SetInt64Data(v, int64(x), T_CURRENCY, 0), "Failed to set currency data")
+	case Length:
+		return valueError(values.SetFloatData(v, x.Value, T_LENGTH, uint32(x.Unit)), "Failed to set length data")
+	case []interface{}:
+		if x == nil {
+			return valueError(values.SetIntData(v, 0, T_NULL, 0), "Failed to set null data")
+		}
+		if err := valueError(values.SetIntData(v, len(x), T_ARRAY, 0), "Failed to set array data"); err != nil {
+			return err
+		}
+		for i, item := range x {
+			var elem JsonValue
+			elem.Init()
+			err := elem.setValue(item, taken)
+			if err == nil {
+				err = valueError(values.SetNthElementValue(v, i, &elem), "Failed to set element ", i)
+			}
+			// The references taken for the item are v's
+			values.Clear(&elem)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		if x == nil {
+			return valueError(values.SetIntData(v, 0, T_NULL, 0), "Failed to set null data")
+		}
+		if err := valueError(values.SetIntData(v, 0, T_MAP, 0), "Failed to set map data"); err != nil {
+			return err
+		}
+		for key, item := range x {
+			var k, elem JsonValue
+			k.Init()
+			elem.Init()
+			err := k.setValue(key, taken)
+			if err == nil {
+				err = elem.setValue(item, taken)
+			}
+			if err == nil {
+				err = valueError(values.SetValueToKey(v, &k, &elem), "Failed to set key ", key)
+			}
+			values.Clear(&k)
+			values.Clear(&elem)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case []byte:
+		if x == nil {
+			return valueError(values.SetIntData(v, 0, T_NULL, 0), "Failed to set null data")
+		}
+		return valueError(values.SetBinaryData(v, x, T_BYTES, 0), "Failed to set binary data")
+	case *Element:
+		if x == nil {
+			return valueError(values.SetIntData(v, 0, T_NULL, 0), "Failed to set null data")
+		}
+		if err := x.checkReleased(); err != nil {
+			return err
+		}
+		if err := valueError(values.SetInt64Data(v, int64(x.handle), T_DOM_OBJECT, 0), "Failed to set dom object data"); err != nil {
+			return err
+		}
+		use(x.handle)
+		*taken = append(*taken, x.handle)
+		return nil
+	}
+	exported, err := exportValue(reflect.ValueOf(x))
+	if err != nil {
+		return &ValueError{HV_INCOMPATIBLE_TYPE, err.Error()}
+	}
+	return v.setValue(exported, taken)
+}
+
+// Converts the data that came with the event, such as the fields of a
+// FORM_SUBMIT.  See JsonValue.Value.
+func (p *BehaviorEventParams) DataValue() (interface{}, error) {
+	return p.Data.Value()
+}
+
+// Replaces the data sent with the event.  See JsonValue.SetValue; elements
+// HTMLayout put in the data are not released.
+func (p *BehaviorEventParams) SetDataValue(x interface{}) error {
+	return p.Data.SetValue(x)
+}
@@ -0,0 +1,256 @@
+package gohl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFiletimeConversion(t *testing.T) {
+	when := time.Date(2012, 3, 4, 5, 6, 7, 800, time.UTC)
+	ft, units := timeToFiletime(when)
+	if ft != 129753111670000008 {
+		t.Error("Unexpected filetime: ", ft)
+	}
+	if units&DT_UTC == 0 || units&DT_HAS_DATE == 0 {
+		t.Error("Unexpected units: ", units)
+	}
+	if back := filetimeToTime(ft, units); !back.Equal(when) || back.Location() != time.UTC {
+		t.Error("Unexpected round trip: ", back)
+	}
+	if epoch := filetimeToTime(0, DT_UTC); !epoch.Equal(time.Date(1601, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Unexpected time for the FILETIME epoch: ", epoch)
+	}
+
+	local := filetimeToTime(ft, DT_HAS_DATE|DT_HAS_TIME)
+	if local.Location() != time.Local || local.Hour() != 5 || local.Minute() != 6 {
+		t.Error("Expected the wall clock time in the local zone, got ", local)
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		f      float64
+		c      Currency
+		expect string
+	}{
+		{12.34, 123400, "12.3400"},
+		{-0.5, -5000, "-0.5000"},
+		{0.00005, 1, "0.0001"},
+		{0, 0, "0.0000"},
+	}
+	for _, test := range tests {
+		c := CurrencyFromFloat(test.f)
+		if c != test.c || c.String() != test.expect {
+			t.Errorf("%g converted to %d, %s", test.f, c, c)
+		}
+	}
+	if f := Currency(123400).Float(); f != 12.34 {
+		t.Error("Unexpected float: ", f)
+	}
+}
+
+func TestLength(t *testing.T) {
+	tests := []struct {
+		l      Length
+		expect string
+	}{
+		{Length{12, UT_PX}, "12px"},
+		{Length{1.5, UT_EM}, "1.5em"},
+		{Length{50, UT_PR}, "50%"},
+		{Length{3, 99}, "3(0x63)"},
+	}
+	for _, test := range tests {
+		if s := test.l.String(); s != test.expect {
+			t.Errorf("Expected %s, got %s", test.expect, s)
+		}
+	}
+}
+
+func TestNativeValuesPassThrough(t *testing.T) {
+	when := time.Date(2012, 3, 4, 0, 0, 0, 0, time.UTC)
+	e := &Element{}
+	for _, v := range []interface{}{when, Currency(5), Length{1, UT_PX}, []byte("ab"), e} {
+		exported, err := exportValue(reflect.ValueOf(v))
+		if err != nil || !reflect.DeepEqual(exported, v) {
+			t.Errorf("Exported %#v as %#v, %v", v, exported, err)
+		}
+		converted, err := convertArg(v, reflect.TypeOf(v))
+		if err != nil || !reflect.DeepEqual(converted.Interface(), v) {
+			t.Errorf("Converted %#v to %#v, %v", v, converted, err)
+		}
+	}
+
+	var nilElement *Element
+	if exported, err := exportValue(reflect.ValueOf(nilElement)); err != nil || exported != nil {
+		t.Errorf("Exported a nil element as %#v, %v", exported, err)
+	}
+	exported, err := exportValue(reflect.ValueOf(struct {
+		When  time.Time
+		Price Currency
+	}{when, 7}))
+	if err != nil || !reflect.DeepEqual(exported, map[string]interface{}{"When": when, "Price": Currency(7)}) {
+		t.Errorf("Exported a struct as %#v, %v", exported, err)
+	}
+}
+
+func TestValueRoundTrip(t *testing.T) {
+	when := time.Date(2012, 3, 4, 5, 6, 7, 0, time.UTC)
+	tests := []struct {
+		value  interface{}
+		t      ValueType
+		expect interface{}
+	}{
+		{nil, T_NULL, nil},
+		{true, T_BOOL, true},
+		{false, T_BOOL, false},
+		{-12, T_INT, -12},
+		{1 << 40, T_FLOAT, float64(1 << 40)},
+		{1.5, T_FLOAT, 1.5},
+		{"héllo", T_STRING, "héllo"},
+		{"", T_STRING, ""},
+		{when, T_DATE, when},
+		{Currency(12345), T_CURRENCY, Currency(12345)},
+		{Length{2.5, UT_EM}, T_LENGTH, Length{2.5, UT_EM}},
+		{[]byte("ab"), T_BYTES, []byte("ab")},
+		{[]byte(nil), T_NULL, nil},
+		{[]interface{}{1, "two", []interface{}{3.5}}, T_ARRAY, []interface{}{1, "two", []interface{}{3.5}}},
+		{[]interface{}{}, T_ARRAY, []interface{}{}},
+		{[]interface{}(nil), T_NULL, nil},
+		{map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": true}}, T_MAP, map[string]interface{}{"a": 1, "b": map[string]interface{}{"c": true}}},
+		{map[string]interface{}(nil), T_NULL, nil},
+		{[]string{"x", "y"}, T_ARRAY, []interface{}{"x", "y"}},
+		{(*Element)(nil), T_NULL, nil},
+	}
+	for _, test := range tests {
+		var v JsonValue
+		v.Init()
+		if err := v.SetValue(test.value); err != nil {
+			t.Errorf("Failed to set %#v: %v", test.value, err)
+			continue
+		}
+		if v.Type() != test.t {
+			t.Errorf("Set %#v as %s, expected %s", test.value, v.Type(), test.t)
+		}
+		back, err := v.Value()
+		if err != nil || !reflect.DeepEqual(back, test.expect) {
+			t.Errorf("Round tripped %#v to %#v, %v", test.value, back, err)
+		}
+		v.Clear()
+		if v.Type() != T_UNDEFINED {
+			t.Errorf("Expected %#v to be undefined after Clear, got %s", test.value, v.Type())
+		}
+	}
+
+	var undefined JsonValue
+	undefined.Init()
+	if back, err := undefined.Value(); err != nil || back != nil {
+		t.Errorf("Expected undefined to convert to nil, got %#v, %v", back, err)
+	}
+	for _, unsupported := range []uint32{T_FUNCTION, T_OBJECT} {
+		v := JsonValue{T: unsupported}
+		if _, err := v.Value(); err == nil || err.(*ValueError).Result != HV_INCOMPATIBLE_TYPE {
+			t.Errorf("Expected %s to fail, got %v", v.Type(), err)
+		}
+	}
+	if err := (&JsonValue{}).SetValue(func() {}); err == nil {
+		t.Error("Expected a func to be refused")
+	}
+}
+
+func TestSetDataValueKeepsEngineElements(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		a := RootElement(hwnd).Select("#a")[0]
+		h := a.Handle()
+		before := refCount(h)
+
+		// Data as HTMLayout would fill it in, without a reference of ours
+		params := &BehaviorEventParams{Cmd: BUTTON_CLICK, Target: h}
+		values.SetInt64Data(&params.Data, int64(h), T_DOM_OBJECT, 0)
+		if err := params.SetDataValue("replaced"); err != nil {
+			t.Fatal(err)
+		}
+		if refCount(h) != before {
+			t.Fatal("Expected the engine's element to be left alone, ref count: ", refCount(h))
+		}
+
+		// Only the reference SetDataValue took is released again
+		if err := params.SetDataValue(a); err != nil {
+			t.Fatal(err)
+		}
+		if err := params.SetDataValue([]interface{}{1}); err != nil {
+			t.Fatal(err)
+		}
+		if refCount(h) != before {
+			t.Fatal("Expected the reference SetDataValue took to be released, ref count: ", refCount(h))
+		}
+		values.Clear(&params.Data)
+
+		nested := JsonValue{T: T_DOM_OBJECT, D: uint64(h)}
+		nested.Clear()
+		if refCount(h) != before {
+			t.Fatal("Clear should not release references it didn't take, ref count: ", refCount(h))
+		}
+	})
+}
+
+func TestDomObjectValue(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		a := RootElement(hwnd).Select("#a")[0]
+		h := a.Handle()
+		before := refCount(h)
+
+		var v JsonValue
+		v.Init()
+		e := NewElementFromHandle(h)
+		if err := v.SetValue(e); err != nil || v.Type() != T_DOM_OBJECT {
+			t.Fatal("Failed to set an element: ", err)
+		}
+		e.Release()
+		if refCount(h) != before+1 {
+			t.Fatal("Expected the value to hold its own reference to the element")
+		}
+		back, err := v.Value()
+		if err != nil || back.(*Element).Handle() != h {
+			t.Fatal("Unexpected element: ", back, err)
+		}
+		back.(*Element).Release()
+		v.Clear()
+		if refCount(h) != before {
+			t.Fatal("Expected Clear to release the value's reference, ref count: ", refCount(h))
+		}
+
+		// Elements nested in arrays and maps are released along with them
+		if err := v.SetValue([]interface{}{a, map[string]interface{}{"a": a}}); err != nil {
+			t.Fatal(err)
+		}
+		if refCount(h) != before+2 {
+			t.Fatal("Expected a reference for each nested element, ref count: ", refCount(h))
+		}
+		back, err = v.Value()
+		items, _ := back.([]interface{})
+		if err != nil || len(items) != 2 || items[0].(*Element).Handle() != h || items[1].(map[string]interface{})["a"].(*Element).Handle() != h {
+			t.Fatalf("Unexpected nested elements: %#v, %v", back, err)
+		}
+		nested := []*Element{items[0].(*Element), items[1].(map[string]interface{})["a"].(*Element)}
+		for _, e := range nested {
+			e.Release()
+		}
+		v.SetValue(nil)
+		if refCount(h) != before {
+			t.Fatal("Expected setting the value again to release the nested elements, ref count: ", refCount(h))
+		}
+
+		released := NewElementFromHandle(h)
+		released.Release()
+		if err := v.SetValue(released); err == nil {
+			t.Fatal("Expected a released element to be refused")
+		}
+
+		// A null element converts to nil rather than failing
+		null := JsonValue{T: T_DOM_OBJECT}
+		if back, err := null.Value(); err != nil || back != nil {
+			t.Fatalf("Expected a null element to convert to nil, got %#v, %v", back, err)
+		}
+	})
+}
@@ -0,0 +1,58 @@
+package gohl
+
+import (
+	"testing"
+	"unicode"
+)
+
+func TestKeyCodeStrings(t *testing.T) {
+	tests := []struct {
+		code   KeyCode
+		expect string
+	}{
+		{VK_RETURN, "VK_RETURN"},
+		{VK_ESCAPE, "VK_ESCAPE"},
+		{VK_F12, "VK_F12"},
+		{VK_NUMPAD5, "VK_NUMPAD5"},
+		{VK_OEM_PLUS, "VK_OEM_PLUS"},
+		{VK_A, "VK_A"},
+		{VK_Z, "VK_Z"},
+		{VK_7, "VK_7"},
+		{0x07, "0x7"},
+	}
+	for _, test := range tests {
+		if s := test.code.String(); s != test.expect {
+			t.Errorf("Expected %s but got %s", test.expect, s)
+		}
+	}
+}
+
+func TestKeyParamsHelpers(t *testing.T) {
+	p := &KeyParams{Cmd: KEY_DOWN | SINKING, KeyCode: VK_DELETE, AltState: CONTROL_KEY_PRESSED | ALT_KEY_PRESSED | 0x100}
+	if p.Key() != VK_DELETE {
+		t.Error("Unexpected key: ", p.Key())
+	}
+	if m := p.Modifiers(); !m.Ctrl() || !m.Alt() || m.Shift() || m != CONTROL_KEY_PRESSED|ALT_KEY_PRESSED {
+		t.Error("Unexpected modifiers: ", m)
+	}
+	if r := p.Rune(); r != 0 {
+		t.Error("Expected no rune for a KEY_DOWN event, got: ", r)
+	}
+
+	chars := []struct {
+		code   uint32
+		expect rune
+	}{
+		{'a', 'a'},
+		{0xE9, 'é'},
+		{0x20AC, '€'},
+		{0xD83D, unicode.ReplacementChar},
+		{0xDE00, unicode.ReplacementChar},
+	}
+	for _, test := range chars {
+		p := &KeyParams{Cmd: KEY_CHAR | HANDLED, KeyCode: test.code}
+		if r := p.Rune(); r != test.expect {
+			t.Errorf("Expected %q for 0x%X but got %q", test.expect, test.code, r)
+		}
+	}
+}
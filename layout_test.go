@@ -0,0 +1,106 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// The size and field offsets of a struct that is shared with C.  The expected
+// values are taken from the C headers, for 32 and 64-bit builds.
+type structLayout struct {
+	name     string
+	size     uintptr
+	offsets  []uintptr
+	expect32 []uintptr // size, then offsets
+	expect64 []uintptr
+}
+
+func TestStructLayouts(t *testing.T) {
+	var (
+		nmhdr   NMHDR
+		create  NmhlCreateControl
+		destroy NmhlDestroyControl
+		load    NmhlLoadData
+		loaded  NmhlDataLoaded
+		attach  NmhlAttachBehavior
+	)
+	layouts := []structLayout{
+		{
+			"NMHDR", unsafe.Sizeof(nmhdr),
+			[]uintptr{unsafe.Offsetof(nmhdr.HwndFrom), unsafe.Offsetof(nmhdr.IdFrom), unsafe.Offsetof(nmhdr.Code)},
+			[]uintptr{12, 0, 4, 8},
+			[]uintptr{24, 0, 8, 16},
+		},
+		{
+			"NMHL_CREATE_CONTROL", unsafe.Sizeof(create),
+			[]uintptr{unsafe.Offsetof(create.Header), unsafe.Offsetof(create.Element), unsafe.Offsetof(create.InHwndParent),
+				unsafe.Offsetof(create.OutHwndControl), unsafe.Offsetof(create.reserved1), unsafe.Offsetof(create.reserved2)},
+			[]uintptr{32, 0, 12, 16, 20, 24, 28},
+			[]uintptr{56, 0, 24, 32, 40, 48, 52},
+		},
+		{
+			"NMHL_DESTROY_CONTROL", unsafe.Sizeof(destroy),
+			[]uintptr{unsafe.Offsetof(destroy.Header), unsafe.Offsetof(destroy.Element), unsafe.Offsetof(destroy.InOutHwndControl),
+				unsafe.Offsetof(destroy.reserved1)},
+			[]uintptr{24, 0, 12, 16, 20},
+			[]uintptr{48, 0, 24, 32, 40},
+		},
+		{
+			"NMHL_LOAD_DATA", unsafe.Sizeof(load),
+			[]uintptr{unsafe.Offsetof(load.Header), unsafe.Offsetof(load.Uri), unsafe.Offsetof(load.OutData),
+				unsafe.Offsetof(load.OutDataSize), unsafe.Offsetof(load.DataType), unsafe.Offsetof(load.Principal),
+				unsafe.Offsetof(load.Initiator)},
+			[]uintptr{36, 0, 12, 16, 20, 24, 28, 32},
+			[]uintptr{64, 0, 24, 32, 40, 44, 48, 56},
+		},
+		{
+			"NMHL_DATA_LOADED", unsafe.Sizeof(loaded),
+			[]uintptr{unsafe.Offsetof(loaded.Header), unsafe.Offsetof(loaded.Uri), unsafe.Offsetof(loaded.Data),
+				unsafe.Offsetof(loaded.DataSize), unsafe.Offsetof(loaded.DataType), unsafe.Offsetof(loaded.Status)},
+			[]uintptr{32, 0, 12, 16, 20, 24, 28},
+			[]uintptr{56, 0, 24, 32, 40, 44, 48},
+		},
+		{
+			"NMHL_ATTACH_BEHAVIOR", unsafe.Sizeof(attach),
+			[]uintptr{unsafe.Offsetof(attach.Header), unsafe.Offsetof(attach.Element), unsafe.Offsetof(attach.BehaviorName),
+				unsafe.Offsetof(attach.ElementProc), unsafe.Offsetof(attach.ElementTag), unsafe.Offsetof(attach.ElementEvents)},
+			[]uintptr{32, 0, 12, 16, 20, 24, 28},
+			[]uintptr{64, 0, 24, 32, 40, 48, 56},
+		},
+	}
+
+	checkLayouts(t, layouts)
+}
+
+func checkLayouts(t *testing.T, layouts []structLayout) {
+	for _, l := range layouts {
+		expect := l.expect64
+		if unsafe.Sizeof(uintptr(0)) == 4 {
+			expect = l.expect32
+		}
+		if l.size != expect[0] {
+			t.Errorf("%s: size is %d, expected %d", l.name, l.size, expect[0])
+		}
+		for i, offset := range l.offsets {
+			if offset != expect[i+1] {
+				t.Errorf("%s: field %d is at offset %d, expected %d", l.name, i, offset, expect[i+1])
+			}
+		}
+	}
+}
+
+func TestHwndNotTruncated(t *testing.T) {
+	if unsafe.Sizeof(HWND(0)) != unsafe.Sizeof(uintptr(0)) {
+		t.Fatal("HWND must be pointer sized")
+	}
+	mem := NewMemoryBackend()
+	hwnd := HWND(^uintptr(0) - 1)
+	if err := mem.LoadHtml(hwnd, pages["one-div"]); err != nil {
+		t.Fatal(err)
+	}
+	SetBackend(mem)
+	defer SetBackend(nil)
+	if RootElement(hwnd).Child(0).RootHwnd() != hwnd {
+		t.Fatal("Window handle was truncated")
+	}
+}
@@ -0,0 +1,32 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// The structs the tests share with user32
+func TestWindowStructLayouts(t *testing.T) {
+	var (
+		msg Msg
+		wc  Wndclassex
+	)
+	checkLayouts(t, []structLayout{
+		{
+			"MSG", unsafe.Sizeof(msg),
+			[]uintptr{unsafe.Offsetof(msg.Hwnd), unsafe.Offsetof(msg.Message), unsafe.Offsetof(msg.Wparam),
+				unsafe.Offsetof(msg.Lparam), unsafe.Offsetof(msg.Time), unsafe.Offsetof(msg.Pt)},
+			[]uintptr{28, 0, 4, 8, 12, 16, 20},
+			[]uintptr{48, 0, 8, 16, 24, 32, 36},
+		},
+		{
+			"WNDCLASSEX", unsafe.Sizeof(wc),
+			[]uintptr{unsafe.Offsetof(wc.Size), unsafe.Offsetof(wc.Style), unsafe.Offsetof(wc.WndProc),
+				unsafe.Offsetof(wc.ClsExtra), unsafe.Offsetof(wc.WndExtra), unsafe.Offsetof(wc.Instance),
+				unsafe.Offsetof(wc.Icon), unsafe.Offsetof(wc.Cursor), unsafe.Offsetof(wc.Background),
+				unsafe.Offsetof(wc.MenuName), unsafe.Offsetof(wc.ClassName), unsafe.Offsetof(wc.IconSm)},
+			[]uintptr{48, 0, 4, 8, 12, 16, 20, 24, 28, 32, 36, 40, 44},
+			[]uintptr{80, 0, 4, 8, 16, 20, 24, 32, 40, 48, 56, 64, 72},
+		},
+	})
+}
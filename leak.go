@@ -0,0 +1,187 @@
+package gohl
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"unsafe"
+)
+
+// An Element or handler attachment that was created while tracking was enabled
+type leakRecord struct {
+	seq     uint64
+	handle  HELEMENT
+	handler *EventHandler // nil for an Element
+	backend Backend
+	stack   []byte
+}
+
+type attachmentKey struct {
+	handle  HELEMENT
+	handler *EventHandler
+}
+
+var (
+	leakMutex       sync.Mutex
+	leakTracking    bool
+	leakSeq         uint64
+	trackedElements = make(map[uintptr]*leakRecord, 64)
+	trackedHandlers = make(map[attachmentKey]*leakRecord, 64)
+)
+
+/*
+Leak
+
+An Element that was neither released nor collected, or an event handler that
+was never detached, along with the stack at the point it was created.
+*/
+type Leak struct {
+	Handle HELEMENT
+
+	// The handler that was attached to Handle, nil if this is a leaked Element
+	Handler *EventHandler
+
+	// Describe() of the element when the report was made, or empty if the
+	// handle could not be described
+	Element string
+
+	// Where the Element was created or the handler was attached
+	Stack string
+}
+
+func (l *Leak) String() string {
+	what := "element"
+	if l.Handler != nil {
+		what = "event handler attached to element"
+	}
+	if l.Element != "" {
+		what += " " + l.Element
+	} else {
+		what += fmt.Sprintf(" %#x", uintptr(l.Handle))
+	}
+	return what + ", created at:\n" + l.Stack
+}
+
+// Debugging aid.  While enabled, the stack is recorded every time an Element is
+// created or an event handler is attached to an element, and forgotten again
+// when the Element is released or the handler detached.  Leaks reports the
+// ones that remain.  Disabling tracking discards everything recorded so far.
+func SetLeakTracking(enabled bool) {
+	leakMutex.Lock()
+	defer leakMutex.Unlock()
+	leakTracking = enabled
+	if !enabled {
+		trackedElements = make(map[uintptr]*leakRecord, 64)
+		trackedHandlers = make(map[attachmentKey]*leakRecord, 64)
+	}
+}
+
+func newLeakRecord(handle HELEMENT, handler *EventHandler) *leakRecord {
+	leakSeq++
+	return &leakRecord{leakSeq, handle, handler, backend, debug.Stack()}
+}
+
+// The Element is keyed by its address rather than by pointer, so that
+// tracking does not keep it from being collected
+func trackElement(e *Element) {
+	leakMutex.Lock()
+	defer leakMutex.Unlock()
+	if leakTracking {
+		trackedElements[uintptr(unsafe.Pointer(e))] = newLeakRecord(e.handle, nil)
+	}
+}
+
+func untrackElement(e *Element) {
+	leakMutex.Lock()
+	delete(trackedElements, uintptr(unsafe.Pointer(e)))
+	leakMutex.Unlock()
+}
+
+func trackHandler(he HELEMENT, handler *EventHandler) {
+	leakMutex.Lock()
+	defer leakMutex.Unlock()
+	if leakTracking {
+		trackedHandlers[attachmentKey{he, handler}] = newLeakRecord(he, handler)
+	}
+}
+
+func untrackHandler(he HELEMENT, handler *EventHandler) {
+	leakMutex.Lock()
+	delete(trackedHandlers, attachmentKey{he, handler})
+	leakMutex.Unlock()
+}
+
+// Returns the records made after seq, oldest first
+func leakRecordsSince(seq uint64) []*leakRecord {
+	leakMutex.Lock()
+	records := make([]*leakRecord, 0, len(trackedElements)+len(trackedHandlers))
+	for _, r := range trackedElements {
+		if r.seq > seq {
+			records = append(records, r)
+		}
+	}
+	for _, r := range trackedHandlers {
+		if r.seq > seq {
+			records = append(records, r)
+		}
+	}
+	leakMutex.Unlock()
+	sort.Slice(records, func(i, j int) bool { return records[i].seq < records[j].seq })
+	return records
+}
+
+func leaksSince(seq uint64) []*Leak {
+	records := leakRecordsSince(seq)
+	leaks := make([]*Leak, 0, len(records))
+	for _, r := range records {
+		l := &Leak{Handle: r.handle, Handler: r.handler, Stack: string(r.stack)}
+		// Only handles from the current backend can be described
+		if r.backend == backend {
+			l.Element = describeHandle(r.handle)
+		}
+		leaks = append(leaks, l)
+	}
+	return leaks
+}
+
+// Returns the Elements and handler attachments that are still alive, in the
+// order they were created.  Elements that are unreachable but have not been
+// finalized yet are included.  Must be called on the thread that owns the
+// dom, since the elements are described.
+func Leaks() []*Leak {
+	return leaksSince(0)
+}
+
+// The subset of testing.T used by NoLeaks
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// Runs scope with leak tracking enabled, then reports an error for every
+// Element and handler attachment made inside it that survives it.  Handles
+// already queued by the finalizer are released first, but the garbage
+// collector is not waited for, so scope must Release the Elements it creates
+// and detach the handlers it attaches.  Must be called on the thread that
+// owns the dom.
+func NoLeaks(t TestingT, scope func()) {
+	t.Helper()
+	leakMutex.Lock()
+	wasTracking := leakTracking
+	leakTracking = true
+	start := leakSeq
+	leakMutex.Unlock()
+	defer func() {
+		if !wasTracking {
+			SetLeakTracking(false)
+		}
+	}()
+
+	scope()
+	DrainReleaseQueue()
+
+	for _, l := range leaksSince(start) {
+		t.Errorf("Leaked %s", l)
+	}
+}
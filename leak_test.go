@@ -0,0 +1,153 @@
+package gohl
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Collects the errors reported by NoLeaks instead of failing the test
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestNoLeaksClean(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		NoLeaks(t, func() {
+			root := RootElement(hwnd)
+			handler := &EventHandler{}
+			d := root.Child(0)
+			d.AttachHandler(handler)
+			d.DetachHandler(handler)
+			root.Child(1).Release()
+			d.Release()
+			root.Release()
+		})
+	})
+}
+
+func TestNoLeaksReportsUnreleased(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		// Unreachable but not released, so left to the collector
+		ft := &fakeT{}
+		NoLeaks(ft, func() {
+			RootElement(hwnd)
+		})
+		if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "element html") {
+			t.Fatal("Expected the unreleased element to be reported, got: ", ft.errors)
+		}
+	})
+}
+
+func TestNoLeaksDrainsFinalized(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		h := RootElement(hwnd).Child(0).Handle()
+		before := refCount(h)
+		NoLeaks(t, func() {
+			root := RootElement(hwnd)
+			d := root.Child(0)
+			root.Release()
+			d.AttachHandler(&EventHandler{})
+
+			// Run the finalizer by hand, as the runtime would
+			d.enqueueRelease()
+		})
+		if refCount(h) != before || PendingReleases() != 0 {
+			t.Fatal("Expected the queued handle to be released")
+		}
+	})
+}
+
+func TestNoLeaksReportsHandler(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		// Releasing an Element detaches the handlers from its handle, so
+		// the handler only leaks while the Element is kept
+		var kept *Element
+		handler := &EventHandler{}
+		ft := &fakeT{}
+		NoLeaks(ft, func() {
+			root := RootElement(hwnd)
+			kept = root.Child(0)
+			root.Release()
+			kept.AttachHandler(handler)
+		})
+		if len(ft.errors) != 2 {
+			t.Fatal("Expected the element and the handler to leak, got: ", ft.errors)
+		}
+		if !strings.Contains(ft.errors[1], "handler attached to element div#a") {
+			t.Fatal("Leak should describe the element: ", ft.errors[1])
+		}
+		if !strings.Contains(ft.errors[1], "TestNoLeaksReportsHandler") {
+			t.Fatal("Leak should include the stack where the handler was attached: ", ft.errors[1])
+		}
+		kept.DetachHandler(handler)
+		kept.Release()
+	})
+}
+
+func TestNoLeaksReportsElement(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		var kept *Element
+		ft := &fakeT{}
+		NoLeaks(ft, func() {
+			root := RootElement(hwnd)
+			kept = root.Child(1)
+			root.Release()
+		})
+		if len(ft.errors) != 1 || !strings.Contains(ft.errors[0], "element div#b") {
+			t.Fatal("Expected the kept element to be reported, got: ", ft.errors)
+		}
+		kept.Release()
+	})
+}
+
+func TestLeaks(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		SetLeakTracking(true)
+		defer SetLeakTracking(false)
+
+		root := RootElement(hwnd)
+		d := root.Child(0)
+		handler := &EventHandler{}
+		d.AttachHandler(handler)
+		leaks := Leaks()
+		if len(leaks) != 3 {
+			t.Fatal("Expected two elements and one handler, got: ", len(leaks))
+		}
+		if leaks[0].Handler != nil || leaks[0].Element != "html" || leaks[2].Handler != handler {
+			t.Fatal("Leaks should be reported in the order they were created")
+		}
+
+		d.DetachHandler(handler)
+		d.Release()
+		root.Release()
+		if leaks := Leaks(); len(leaks) != 0 {
+			t.Fatal("Expected no leaks after releasing, got: ", leaks)
+		}
+	})
+}
+
+func TestLeakTrackingDestroyedWindow(t *testing.T) {
+	mem := NewMemoryBackend()
+	const hwnd = 3
+	if err := mem.LoadHtml(hwnd, pages["one-div"]); err != nil {
+		t.Fatal(err)
+	}
+	SetBackend(mem)
+	defer SetBackend(nil)
+
+	NoLeaks(t, func() {
+		root := RootElement(hwnd)
+		d := root.Child(0)
+		d.AttachHandler(&EventHandler{})
+		mem.DestroyWindow(hwnd)
+		d.Release()
+		root.Release()
+	})
+}
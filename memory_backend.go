@@ -0,0 +1,890 @@
+package gohl
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+)
+
+/*
+MemoryBackend
+
+A Backend that keeps the dom in memory instead of handing it to the HTMLayout
+dll.  Html is parsed into a tree of nodes and the wrapper's dom operations,
+selectors, attributes, inline styles, state flags and event handlers all work
+against that tree.  There is no layout or rendering: element locations are
+whatever was last set with Move or Resize.
+
+	mem := NewMemoryBackend()
+	SetBackend(mem)
+	defer SetBackend(nil)
+	mem.LoadHtml(1, `<div id="a"></div>`)
+	root := RootElement(1)
+
+Events are only delivered when the caller fires them, either with SendEvent
+and PostEvent on an Element or with FireEvent, ProcessPostedEvents and
+FireTimer on the backend.
+*/
+type MemoryBackend struct {
+	roots   map[HWND]*memNode
+	capture *memNode
+	posted  []memPostedEvent
+
+	// Handles are ids into a table of nodes rather than pointers into the Go
+	// heap.  A node gets its id the first time a handle to it is handed out,
+	// and loses it again once the last reference to it is dropped while it is
+	// outside of any document.
+	handlesMutex sync.Mutex
+	handles      map[HELEMENT]*memNode
+	lastHandle   HELEMENT
+}
+
+type memHandler struct {
+	handler      *EventHandler
+	subscription uint32
+}
+
+type memPostedEvent struct {
+	he     HELEMENT
+	code   uint
+	source HELEMENT
+	reason uint32
+}
+
+// Subscription flag that suppresses the attach and detach notifications
+const disableInitialization = uint32(DISABLE_INITIALIZATION & 0xffffffff)
+
+// Event groups whose params begin with a Cmd field and which travel down
+// from the root in a sinking phase before bubbling back up.
+var phasedEventGroups = map[uint32]bool{
+	HANDLE_MOUSE:          true,
+	HANDLE_KEY:            true,
+	HANDLE_FOCUS:          true,
+	HANDLE_SCROLL:         true,
+	HANDLE_BEHAVIOR_EVENT: true,
+	HANDLE_EXCHANGE:       true,
+	HANDLE_GESTURE:        true,
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{
+		roots:   make(map[HWND]*memNode, 4),
+		handles: make(map[HELEMENT]*memNode, 64),
+	}
+}
+
+func (b *MemoryBackend) handle(n *memNode) HELEMENT {
+	b.handlesMutex.Lock()
+	defer b.handlesMutex.Unlock()
+	if n.handle == BAD_HELEMENT {
+		b.lastHandle++
+		n.handle = b.lastHandle
+		b.handles[n.handle] = n
+	}
+	return n.handle
+}
+
+// Returns the node of the handle, deleted or not, or nil if there is none
+func (b *MemoryBackend) nodeOf(he HELEMENT) *memNode {
+	b.handlesMutex.Lock()
+	defer b.handlesMutex.Unlock()
+	return b.handles[he]
+}
+
+// Drops the id of a node once nothing refers to it, unless it is in a window's
+// document where it can still be found again.  Unreferenced nodes elsewhere in
+// a detached tree go with it, since nothing can reach them any more.
+func (b *MemoryBackend) forget(n *memNode) {
+	if !n.deleted {
+		if b.attached(n) {
+			return
+		}
+		n = n.root()
+	}
+	b.handlesMutex.Lock()
+	defer b.handlesMutex.Unlock()
+	var walk func(n *memNode)
+	walk = func(n *memNode) {
+		if n.handle != BAD_HELEMENT && atomic.LoadInt32(&n.refCount) <= 0 {
+			delete(b.handles, n.handle)
+			n.handle = BAD_HELEMENT
+		}
+		if !n.deleted {
+			for _, c := range n.children {
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+}
+
+// Converts a handle back into its node.  Returns HLDOM_INVALID_HANDLE for
+// nil handles and for nodes that have been deleted.
+func (b *MemoryBackend) lookup(he HELEMENT) (*memNode, HLDOM_RESULT) {
+	n := b.nodeOf(he)
+	if n == nil || n.deleted {
+		return nil, HLDOM_INVALID_HANDLE
+	}
+	return n, HLDOM_OK
+}
+
+// Parses the html and makes it the document of the given window, replacing
+// any document that was loaded there before.  Top level elements of a fragment
+// become children of a synthesized <html> root, as they do in the engine.
+func (b *MemoryBackend) LoadHtml(hwnd HWND, html string) error {
+	nodes, err := parseHtml(html)
+	if err != nil {
+		return err
+	}
+
+	var root *memNode
+	elements := 0
+	for _, n := range nodes {
+		if !n.isText() {
+			elements++
+			if n.tag == "html" {
+				root = n
+			}
+		}
+	}
+	if root == nil || elements > 1 {
+		root = &memNode{tag: "html"}
+		root.insertAt(0, nodes...)
+	}
+	root.hwnd = hwnd
+
+	if old, exists := b.roots[hwnd]; exists {
+		b.delete(old)
+	}
+	b.roots[hwnd] = root
+	return nil
+}
+
+// Destroys the document of the window, detaching its handlers, and releases
+// the handlers registered for the window, as ProcNoDefault does when a real
+// window is destroyed.
+func (b *MemoryBackend) DestroyWindow(hwnd HWND) {
+	if root, exists := b.roots[hwnd]; exists {
+		b.delete(root)
+		delete(b.roots, hwnd)
+	}
+	unregisterWindow(hwnd)
+}
+
+// Delivers an event to the element and its ancestors.  For event groups with
+// a Cmd field the handlers from the root down to the element first see the
+// event with the SINKING flag set, then the handlers from the element back up
+// to the root see it without.  Delivery stops at the first handler that
+// returns true.  Returns true if the event was handled.  As with a window,
+// a panicking handler is reported to the panic handler and treated as
+// not having handled the event.
+func (b *MemoryBackend) FireEvent(he HELEMENT, evtg uint32, params unsafe.Pointer) bool {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return false
+	}
+	if !phasedEventGroups[evtg] {
+		return b.dispatch(n, evtg, params)
+	}
+
+	path := make([]*memNode, 0, 16)
+	for p := n; p != nil; p = p.parent {
+		path = append(path, p)
+	}
+
+	cmd := (*uint32)(params)
+	original := *cmd
+	*cmd = original | SINKING
+	for i := len(path) - 1; i >= 0; i-- {
+		if b.dispatch(path[i], evtg, params) {
+			*cmd = original
+			return true
+		}
+	}
+	*cmd = original
+	for _, p := range path {
+		if b.dispatch(p, evtg, params) {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivers the events queued by PostEvent, in the order they were posted.
+// Events posted while processing are left for the next call.  Returns the
+// number of events delivered.  Like the message loop of a real window, it also
+// releases the handles of collected Elements.
+func (b *MemoryBackend) ProcessPostedEvents() int {
+	DrainReleaseQueue()
+	posted := b.posted
+	b.posted = nil
+	for _, e := range posted {
+		b.SendEvent(e.he, e.code, e.source, uintptr(e.reason))
+	}
+	return len(posted)
+}
+
+// Delivers a timer tick to an element that has a timer set.  As in the engine,
+// the timer is stopped if no handler returns true.  Returns true if the timer
+// is still running.
+func (b *MemoryBackend) FireTimer(he HELEMENT) bool {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK || n.timer == 0 {
+		return false
+	}
+	params := &TimerParams{}
+	if !b.dispatch(n, HANDLE_TIMER, unsafe.Pointer(params)) {
+		n.timer = 0
+	}
+	return n.timer != 0
+}
+
+// Returns the interval the element's timer was last set to, in milliseconds,
+// or 0 if it isn't running
+func (b *MemoryBackend) TimerInterval(he HELEMENT) uint {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return 0
+	}
+	return n.timer
+}
+
+// Calls the handlers attached directly to n, stopping at the first one that
+// handles the event
+func (b *MemoryBackend) dispatch(n *memNode, evtg uint32, params unsafe.Pointer) bool {
+	if len(n.handlers) == 0 {
+		return false
+	}
+	handlers := append([]memHandler(nil), n.handlers...)
+	for _, h := range handlers {
+		if h.subscription&evtg == 0 {
+			continue
+		}
+		if h.handler.handleEventRecorded(b.handle(n), evtg, params) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *MemoryBackend) initialize(n *memNode, handler *EventHandler, cmd uint32) {
+	params := &InitializationParams{Cmd: cmd}
+	handler.handleEventRecorded(b.handle(n), HANDLE_INITIALIZATION, unsafe.Pointer(params))
+}
+
+// Removes n and its subtree from the dom for good, detaching any handlers
+func (b *MemoryBackend) delete(n *memNode) {
+	n.detach()
+	var walk func(n *memNode)
+	walk = func(n *memNode) {
+		for _, c := range n.children {
+			walk(c)
+		}
+		handlers := n.handlers
+		n.handlers = nil
+		for _, h := range handlers {
+			if h.subscription&disableInitialization == 0 {
+				b.initialize(n, h.handler, BEHAVIOR_DETACH)
+			}
+		}
+		if b.capture == n {
+			b.capture = nil
+		}
+		n.deleted = true
+		b.forget(n)
+	}
+	walk(n)
+}
+
+// Returns true if n belongs to the document of a window
+func (b *MemoryBackend) attached(n *memNode) bool {
+	root := n.root()
+	return root.hwnd != 0 && b.roots[root.hwnd] == root
+}
+
+func (b *MemoryBackend) UseElement(he HELEMENT) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	atomic.AddInt32(&n.refCount, 1)
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) UnuseElement(he HELEMENT) HLDOM_RESULT {
+	n := b.nodeOf(he)
+	if n == nil {
+		return HLDOM_INVALID_HANDLE
+	}
+	atomic.AddInt32(&n.refCount, -1)
+	b.forget(n)
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) RootElement(hwnd HWND) (HELEMENT, HLDOM_RESULT) {
+	if root, exists := b.roots[hwnd]; exists {
+		return b.handle(root), HLDOM_OK
+	}
+	return BAD_HELEMENT, HLDOM_INVALID_HWND
+}
+
+func (b *MemoryBackend) FocusElement(hwnd HWND) (HELEMENT, HLDOM_RESULT) {
+	root, exists := b.roots[hwnd]
+	if !exists {
+		return BAD_HELEMENT, HLDOM_INVALID_HWND
+	}
+	var find func(n *memNode) *memNode
+	find = func(n *memNode) *memNode {
+		if n.state&STATE_FOCUS != 0 {
+			return n
+		}
+		for _, c := range n.elements() {
+			if f := find(c); f != nil {
+				return f
+			}
+		}
+		return nil
+	}
+	if focus := find(root); focus != nil {
+		return b.handle(focus), HLDOM_OK
+	}
+	return BAD_HELEMENT, HLDOM_OK
+}
+
+func (b *MemoryBackend) CreateElement(tagName string) (HELEMENT, HLDOM_RESULT) {
+	if tagName == "" {
+		return BAD_HELEMENT, HLDOM_INVALID_PARAMETER
+	}
+	return b.handle(&memNode{tag: tagName}), HLDOM_OK
+}
+
+func (b *MemoryBackend) CloneElement(he HELEMENT) (HELEMENT, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return BAD_HELEMENT, ret
+	}
+	return b.handle(n.clone()), HLDOM_OK
+}
+
+func (b *MemoryBackend) ChildrenCount(he HELEMENT) (uint, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return 0, ret
+	}
+	return uint(len(n.elements())), HLDOM_OK
+}
+
+func (b *MemoryBackend) NthChild(he HELEMENT, index uint) (HELEMENT, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return BAD_HELEMENT, ret
+	}
+	elements := n.elements()
+	if index >= uint(len(elements)) {
+		return BAD_HELEMENT, HLDOM_INVALID_PARAMETER
+	}
+	return b.handle(elements[index]), HLDOM_OK
+}
+
+func (b *MemoryBackend) ElementIndex(he HELEMENT) (uint, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return 0, ret
+	}
+	return uint(n.elementIndex()), HLDOM_OK
+}
+
+func (b *MemoryBackend) ParentElement(he HELEMENT) (HELEMENT, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return BAD_HELEMENT, ret
+	}
+	if n.parent == nil {
+		return BAD_HELEMENT, HLDOM_OK
+	}
+	return b.handle(n.parent), HLDOM_OK
+}
+
+func (b *MemoryBackend) InsertElement(he, parent HELEMENT, index uint) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	p, ret := b.lookup(parent)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	// An element cannot become its own descendant
+	for a := p; a != nil; a = a.parent {
+		if a == n {
+			return HLDOM_INVALID_PARAMETER
+		}
+	}
+	p.insertElement(n, int(index))
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) DetachElement(he HELEMENT) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	n.detach()
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) DeleteElement(he HELEMENT) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	if n.parent == nil && b.attached(n) {
+		// The root of a document goes away with its window
+		return HLDOM_INVALID_PARAMETER
+	}
+	b.delete(n)
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) SwapElements(he1, he2 HELEMENT) HLDOM_RESULT {
+	n1, ret := b.lookup(he1)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	n2, ret := b.lookup(he2)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	if n1.parent == nil || n2.parent == nil {
+		return HLDOM_INVALID_PARAMETER
+	}
+	p1, p2 := n1.parent, n2.parent
+	i1, i2 := -1, -1
+	for i, c := range p1.children {
+		if c == n1 {
+			i1 = i
+		}
+	}
+	for i, c := range p2.children {
+		if c == n2 {
+			i2 = i
+		}
+	}
+	p1.children[i1], p2.children[i2] = n2, n1
+	n1.parent, n2.parent = p2, p1
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) SortElements(he HELEMENT, start, end uint, comparator func(HELEMENT, HELEMENT) int) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	elements := n.elements()
+	if start > end || end > uint(len(elements)) {
+		return HLDOM_INVALID_PARAMETER
+	}
+
+	// Remember which slots of the children list the range occupies, so that
+	// text nodes stay where they are
+	slots := make([]int, 0, end-start)
+	for i, c := range n.children {
+		if !c.isText() {
+			if idx := c.elementIndex(); uint(idx) >= start && uint(idx) < end {
+				slots = append(slots, i)
+			}
+		}
+	}
+	sorted := append([]*memNode(nil), elements[start:end]...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return comparator(b.handle(sorted[i]), b.handle(sorted[j])) < 0
+	})
+	for i, slot := range slots {
+		n.children[slot] = sorted[i]
+	}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) SelectElements(he HELEMENT, selector string, callback func(HELEMENT) bool) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return HLDOM_INVALID_PARAMETER
+	}
+
+	// Collect the matches first so that the callback is free to modify the dom
+	matches := make([]*memNode, 0, 16)
+	var walk func(n *memNode)
+	walk = func(n *memNode) {
+		for _, c := range n.elements() {
+			if sel.matches(c) {
+				matches = append(matches, c)
+			}
+			walk(c)
+		}
+	}
+	walk(n)
+
+	for _, m := range matches {
+		if callback(b.handle(m)) {
+			break
+		}
+	}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) SelectParent(he HELEMENT, selector string, depth uint) (HELEMENT, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return BAD_HELEMENT, ret
+	}
+	sel, err := parseSelector(selector)
+	if err != nil {
+		return BAD_HELEMENT, HLDOM_INVALID_PARAMETER
+	}
+	for level := uint(1); n != nil && (depth == 0 || level <= depth); n, level = n.parent, level+1 {
+		if sel.matches(n) {
+			return b.handle(n), HLDOM_OK
+		}
+	}
+	return BAD_HELEMENT, HLDOM_OK
+}
+
+func (b *MemoryBackend) ElementType(he HELEMENT) (string, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return "", ret
+	}
+	return n.tag, HLDOM_OK
+}
+
+func (b *MemoryBackend) ElementHtml(he HELEMENT, outer bool) (string, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return "", ret
+	}
+	if outer {
+		return n.outerHtml(), HLDOM_OK
+	}
+	return n.innerHtml(), HLDOM_OK
+}
+
+func (b *MemoryBackend) SetElementHtml(he HELEMENT, html string, where uint) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	if !b.attached(n) {
+		return HLDOM_PASSIVE_HANDLE
+	}
+	nodes, err := parseHtml(html)
+	if err != nil {
+		return HLDOM_INVALID_PARAMETER
+	}
+
+	switch where {
+	case SIH_REPLACE_CONTENT:
+		for len(n.children) > 0 {
+			b.delete(n.children[0])
+		}
+		n.insertAt(0, nodes...)
+	case SIH_INSERT_AT_START:
+		n.insertAt(0, nodes...)
+	case SIH_APPEND_AFTER_LAST:
+		n.insertAt(len(n.children), nodes...)
+	case SOH_REPLACE, SOH_INSERT_BEFORE, SOH_INSERT_AFTER:
+		p := n.parent
+		if p == nil {
+			return HLDOM_INVALID_PARAMETER
+		}
+		pos := 0
+		for i, c := range p.children {
+			if c == n {
+				pos = i
+			}
+		}
+		switch where {
+		case SOH_REPLACE:
+			b.delete(n)
+		case SOH_INSERT_AFTER:
+			pos++
+		}
+		p.insertAt(pos, nodes...)
+	default:
+		return HLDOM_INVALID_PARAMETER
+	}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) ElementInnerText(he HELEMENT) (string, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return "", ret
+	}
+	return n.innerText(), HLDOM_OK
+}
+
+func (b *MemoryBackend) SetElementInnerText(he HELEMENT, text string) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	for len(n.children) > 0 {
+		b.delete(n.children[0])
+	}
+	if text != "" {
+		n.insertAt(0, &memNode{text: text})
+	}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) AttributeCount(he HELEMENT) (uint, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return 0, ret
+	}
+	return uint(len(n.attrs)), HLDOM_OK
+}
+
+func (b *MemoryBackend) NthAttribute(he HELEMENT, index uint) (string, string, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return "", "", ret
+	}
+	if index >= uint(len(n.attrs)) {
+		return "", "", HLDOM_INVALID_PARAMETER
+	}
+	return n.attrs[index].name, n.attrs[index].value, HLDOM_OK
+}
+
+func (b *MemoryBackend) AttributeByName(he HELEMENT, name string) (string, bool, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return "", false, ret
+	}
+	value, exists := n.attr(name)
+	return value, exists, HLDOM_OK
+}
+
+func (b *MemoryBackend) SetAttributeByName(he HELEMENT, name string, value *string) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	n.setAttr(name, value)
+	if name == "style" {
+		n.styles = nil
+		if value != nil {
+			n.styles = parseInlineStyle(*value)
+		}
+	}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) StyleAttribute(he HELEMENT, name string) (string, bool, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return "", false, ret
+	}
+	for _, s := range n.styles {
+		if s.name == name {
+			return s.value, true, HLDOM_OK
+		}
+	}
+	return "", false, HLDOM_OK
+}
+
+func (b *MemoryBackend) SetStyleAttribute(he HELEMENT, name string, value *string) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	if name == "" && value == nil {
+		n.styles = nil
+		return HLDOM_OK
+	}
+	n.styles = setStyle(n.styles, name, value)
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) ElementState(he HELEMENT) (uint32, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return 0, ret
+	}
+	return n.state, HLDOM_OK
+}
+
+func (b *MemoryBackend) SetElementState(he HELEMENT, bitsToSet, bitsToClear uint32, update bool) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	n.state = n.state&^bitsToClear | bitsToSet
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) ElementLocation(he HELEMENT, areas uint32) (Rect, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return Rect{}, ret
+	}
+	return n.rect, HLDOM_OK
+}
+
+func (b *MemoryBackend) MoveElement(he HELEMENT, x, y int) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	w, h := n.rect.Right-n.rect.Left, n.rect.Bottom-n.rect.Top
+	n.rect = Rect{int32(x), int32(y), int32(x) + w, int32(y) + h}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) MoveElementEx(he HELEMENT, x, y, w, h int) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	n.rect = Rect{int32(x), int32(y), int32(x + w), int32(y + h)}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) UpdateElement(he HELEMENT, flags uint32) HLDOM_RESULT {
+	_, ret := b.lookup(he)
+	return ret
+}
+
+func (b *MemoryBackend) ElementHwnd(he HELEMENT, root bool) (HWND, HLDOM_RESULT) {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return 0, ret
+	}
+	if !b.attached(n) {
+		return 0, HLDOM_OK
+	}
+	return n.root().hwnd, HLDOM_OK
+}
+
+func (b *MemoryBackend) SetCapture(he HELEMENT) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	b.capture = n
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) ReleaseCapture() bool {
+	b.capture = nil
+	return true
+}
+
+func (b *MemoryBackend) SetTimer(he HELEMENT, ms uint) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	n.timer = ms
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) SendEvent(he HELEMENT, eventCode uint, source HELEMENT, reason uintptr) (bool, HLDOM_RESULT) {
+	if _, ret := b.lookup(he); ret != HLDOM_OK {
+		return false, ret
+	}
+	params := &BehaviorEventParams{
+		Cmd:    uint32(eventCode),
+		Target: he,
+		Source: source,
+		Reason: uint32(reason),
+	}
+	return b.FireEvent(he, HANDLE_BEHAVIOR_EVENT, unsafe.Pointer(params)), HLDOM_OK
+}
+
+func (b *MemoryBackend) PostEvent(he HELEMENT, eventCode uint, source HELEMENT, reason uint32) HLDOM_RESULT {
+	if _, ret := b.lookup(he); ret != HLDOM_OK {
+		return ret
+	}
+	b.posted = append(b.posted, memPostedEvent{he, eventCode, source, reason})
+	return HLDOM_OK
+}
+
+// Method calls go to the handlers attached to the element.  If none of them
+// handle it, text values are provided for the elements that have one.
+func (b *MemoryBackend) CallBehaviorMethod(he HELEMENT, params *MethodParams) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	if b.dispatch(n, HANDLE_METHOD_CALL, unsafe.Pointer(params)) {
+		return HLDOM_OK
+	}
+
+	switch n.tag {
+	case "input", "textarea", "widget":
+	default:
+		return HLDOM_OK_NOT_HANDLED
+	}
+	if n.value == nil {
+		var value string
+		if n.tag == "textarea" {
+			value = n.innerText()
+		} else {
+			value, _ = n.attr("value")
+		}
+		n.value = &value
+	}
+
+	switch params.MethodId {
+	case GET_TEXT_VALUE:
+		args := (*TextValueParams)(unsafe.Pointer(params))
+		text := stringToUtf16(*n.value)
+		args.Text = &text[0]
+		args.Length = uint32(len(text) - 1)
+	case SET_TEXT_VALUE:
+		args := (*TextValueParams)(unsafe.Pointer(params))
+		if args.Text == nil {
+			return HLDOM_INVALID_PARAMETER
+		}
+		value := utf16ToString(args.Text)
+		n.value = &value
+	default:
+		return HLDOM_OK_NOT_HANDLED
+	}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) AttachEventHandler(he HELEMENT, handler *EventHandler, subscription uint32) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	n.handlers = append(n.handlers, memHandler{handler, subscription})
+	if subscription&disableInitialization == 0 {
+		b.initialize(n, handler, BEHAVIOR_ATTACH)
+	}
+	return HLDOM_OK
+}
+
+func (b *MemoryBackend) DetachEventHandler(he HELEMENT, handler *EventHandler) HLDOM_RESULT {
+	n, ret := b.lookup(he)
+	if ret != HLDOM_OK {
+		return ret
+	}
+	for i, h := range n.handlers {
+		if h.handler == handler {
+			n.handlers = append(n.handlers[:i], n.handlers[i+1:]...)
+			if h.subscription&disableInitialization == 0 {
+				b.initialize(n, handler, BEHAVIOR_DETACH)
+			}
+			return HLDOM_OK
+		}
+	}
+	return HLDOM_INVALID_PARAMETER
+}
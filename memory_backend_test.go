@@ -0,0 +1,627 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+const memoryHwnd = 1
+
+// Runs the test against a fresh in-memory dom, in place of a real window
+func testWithMemoryHtml(html string, test func(mem *MemoryBackend, hwnd HWND)) {
+	mem := NewMemoryBackend()
+	if err := mem.LoadHtml(memoryHwnd, html); err != nil {
+		panic(err)
+	}
+	SetBackend(mem)
+	defer SetBackend(nil)
+	defer mem.DestroyWindow(memoryHwnd)
+	test(mem, memoryHwnd)
+}
+
+func TestParseHtml(t *testing.T) {
+	nodes, err := parseHtml(`<div id=a class='x y'>one<br/>two &amp; <b>three</b></div><p>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nodes) != 2 {
+		t.Fatal("Expected two top level nodes, got: ", len(nodes))
+	}
+	div := nodes[0]
+	if id, _ := div.attr("id"); id != "a" {
+		t.Fatal("Unexpected id: ", id)
+	}
+	if class, _ := div.attr("class"); class != "x y" {
+		t.Fatal("Unexpected class: ", class)
+	}
+	if text := div.innerText(); text != "onetwo & three" {
+		t.Fatal("Unexpected text: ", text)
+	}
+	if html := div.innerHtml(); html != `one<br>two &amp; <b>three</b>` {
+		t.Fatal("Unexpected html: ", html)
+	}
+	if html := nodes[1].outerHtml(); html != `<p></p>` {
+		t.Fatal("Unclosed element should be closed at the end of input, got: ", html)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	valid := []string{
+		"div", "*", "#a", ".one.two", "div > span", "a + b ~ c", "[id]", `[type="text"]`, "[lang|=en]",
+		"li:first-child", "li:nth-child(2n+1)", "div:not(.x)", ":has-child-of-type(div)", "a:hover", "a, b",
+	}
+	for _, s := range valid {
+		if _, err := parseSelector(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	invalid := []string{"", "div >", "[id", "a:bogus", "div,", ":not(.x"}
+	for _, s := range invalid {
+		if _, err := parseSelector(s); err == nil {
+			t.Fatalf("Expected an error for selector '%s'", s)
+		}
+	}
+}
+
+func TestNthMatches(t *testing.T) {
+	cases := []struct {
+		expr     string
+		position int
+		expected bool
+	}{
+		{"3", 3, true},
+		{"3", 2, false},
+		{"odd", 1, true},
+		{"odd", 2, false},
+		{"even", 4, true},
+		{"2n+1", 5, true},
+		{"3n", 6, true},
+		{"3n", 7, false},
+		{"-n+2", 2, true},
+		{"-n+2", 3, false},
+		{"n", 9, true},
+	}
+	for _, c := range cases {
+		if nthMatches(c.expr, c.position) != c.expected {
+			t.Fatalf("Expected nth-child(%s) at %d to be %t", c.expr, c.position, c.expected)
+		}
+	}
+}
+
+func TestMemoryRootElement(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		if root.Type() != "html" {
+			t.Fatal("Type of root elem should be 'html', instead got: ", root.Type())
+		}
+		if !looseEqual(root.OuterHtml(), "<html>"+pages["nested-divs"]+"</html>") {
+			t.Fatal("Outer html of root elem not as expected: ", root.OuterHtml())
+		}
+		if root.Parent() != nil {
+			t.Fatal("Root's parent should be nil")
+		}
+		if root.Hwnd() != hwnd || root.Child(0).RootHwnd() != hwnd {
+			t.Fatal("Elements should report the hwnd they were loaded into")
+		}
+	})
+}
+
+func TestMemoryRootElementFromDocument(t *testing.T) {
+	testWithMemoryHtml(pages["page"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		if count := root.ChildCount(); count != 1 || root.Child(0).Type() != "body" {
+			t.Fatal("Expected the document's own html element to be the root")
+		}
+	})
+}
+
+func TestMemoryChildren(t *testing.T) {
+	testWithMemoryHtml(`<div id="a">text</div> <div id="b"></div>`, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		if count := root.ChildCount(); count != 2 {
+			t.Fatal("Text nodes should not be counted as children")
+		}
+		d1 := root.Child(0)
+		d2 := root.Child(1)
+		if d1.Index() != 0 || d2.Index() != 1 {
+			t.Fatal("Unexpected child indices")
+		}
+		if !d2.Parent().Equals(root) {
+			t.Fatal("Parent was not the expected elem")
+		}
+		func() {
+			defer expectDomError(HLDOM_INVALID_PARAMETER)
+			root.Child(2)
+		}()
+	})
+}
+
+func TestMemorySelect(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		results := root.Select("div > div")
+		if len(results) != 1 {
+			t.Fatal("Expected one result")
+		}
+		inner := root.Child(0).Child(0)
+		if !results[0].Equals(inner) {
+			t.Fatal("Expected to match inner div")
+		}
+	})
+}
+
+func TestMemorySelectors(t *testing.T) {
+	html := `<ul id="list">
+		<li class="a first" data-x="one two">1</li>
+		<li class="a">2</li>
+		<li lang="en-us"><span></span></li>
+		<li></li>
+	</ul>`
+	testWithMemoryHtml(html, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		root.Select("li")[1].SetState(STATE_CHECKED, true)
+
+		cases := map[string]int{
+			"li":                       4,
+			"ul li":                    4,
+			"#list > li":               4,
+			"html > li":                0,
+			".a":                       2,
+			".a.first":                 1,
+			"li.a + li":                2,
+			"li.first ~ li":            3,
+			"[data-x]":                 1,
+			"[data-x~=two]":            1,
+			"[data-x^=one]":            1,
+			"[data-x$=two]":            1,
+			`[data-x*="e t"]`:          1,
+			"[lang|=en]":               1,
+			"li:first-child":           1,
+			"li:last-child":            1,
+			"span:only-child":          1,
+			"li:nth-child(odd)":        2,
+			"li:nth-child(3)":          1,
+			"li:empty":                 1,
+			"li:not(.a)":               2,
+			"li:checked":               1,
+			"li, span":                 5,
+			"*":                        6,
+			":has-child-of-type(span)": 1,
+		}
+		for selector, expected := range cases {
+			if results := root.Select(selector); len(results) != expected {
+				t.Fatalf("Expected %d matches for '%s', got %d", expected, selector, len(results))
+			}
+		}
+
+		func() {
+			defer expectDomError(HLDOM_INVALID_PARAMETER)
+			root.Select("li >")
+		}()
+	})
+}
+
+func TestMemorySelectParentLimit(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		d1 := root.Child(0)
+		d2 := d1.Child(0)
+
+		if result := root.SelectParent("*:has-child-of-type(div):has-child-of-type(div)"); !result.Equals(root) {
+			t.Fatal("Expected to match root element")
+		}
+		if result := d2.SelectParentLimit("html", 0); result == nil || !result.Equals(root) {
+			t.Fatal("Expected to match root elem")
+		}
+		if result := d2.SelectParentLimit("*:has-child-of-type(div)", 1); result != nil {
+			t.Fatal("Expected to only check current element and not match it, instead got: ", result.OuterHtml())
+		}
+		if result := d2.SelectParentLimit("*:has-child-of-type(div)", 2); result == nil || !result.Equals(d1) {
+			t.Fatal("Expected to match outer div")
+		}
+	})
+}
+
+func TestMemoryInsertChild(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		d.InsertChild(NewElement("div"), 0)
+		d.InsertChild(NewElement("span"), 0)
+		d.AppendChild(NewElement("p"))
+		if !looseEqual(d.Html(), `<span></span><div></div><p></p>`) {
+			t.Fatal("Inserting element created unexpected html: ", d.Html())
+		}
+
+		// An element cannot be inserted into its own subtree
+		func() {
+			defer expectDomError(HLDOM_INVALID_PARAMETER)
+			d.Child(0).AppendChild(d)
+		}()
+	})
+}
+
+func TestMemoryDetach(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		inner := d.Child(0)
+		inner.Detach()
+		if !looseEqual(d.Html(), ``) {
+			t.Fatal("Element should not have any contents after detaching its only child")
+		}
+		if inner.Parent() != nil {
+			t.Fatal("Detached element should not have a parent")
+		}
+		d.AppendChild(inner)
+		if !looseEqual(d.Html(), inner.OuterHtml()) {
+			t.Fatal("Element should contain the reattached child")
+		}
+	})
+}
+
+func TestMemoryDelete(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		inner := d.Child(0)
+		handle := inner.Handle()
+
+		inner.Delete()
+		if !looseEqual(d.Html(), ``) {
+			t.Fatal("Element should not have any contents after deleting its only child")
+		}
+		func() {
+			defer expectReleasedError()
+			d.AppendChild(inner)
+		}()
+		if _, ret := mem.ElementType(handle); ret != HLDOM_INVALID_HANDLE {
+			t.Fatal("Handle of a deleted element should be invalid")
+		}
+	})
+}
+
+func TestMemoryHandlesDropped(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		handles := func() int {
+			mem.handlesMutex.Lock()
+			defer mem.handlesMutex.Unlock()
+			return len(mem.handles)
+		}
+		before := handles()
+
+		// A new element and its children go once the last reference is dropped
+		e := NewElement("div")
+		span := NewElement("span")
+		e.AppendChild(span)
+		span.Release()
+		if child := e.Child(0); child.Type() != "span" {
+			t.Fatal("The child should still be found through its parent, got: ", child.Describe())
+		} else {
+			child.Release()
+		}
+		e.Release()
+		if n := handles(); n != before {
+			t.Fatalf("Expected the new elements' handles to be dropped, %d handles, %d before", n, before)
+		}
+
+		// As does a detached one, but not one still in the document
+		d := RootElement(hwnd).Child(0)
+		inner := d.Child(0)
+		innerHandle := inner.Handle()
+		inner.Detach()
+		inner.Release()
+		if mem.nodeOf(innerHandle) != nil {
+			t.Fatal("Expected the detached element's handle to be dropped")
+		}
+		dHandle := d.Handle()
+		d.Release()
+		if mem.nodeOf(dHandle) == nil {
+			t.Fatal("An element in the document should keep its handle")
+		}
+
+		// Every backend has a table of its own
+		other := NewMemoryBackend()
+		if other.nodeOf(dHandle) != nil {
+			t.Fatal("Handles should not be shared between backends")
+		}
+	})
+}
+
+func TestMemoryClone(t *testing.T) {
+	testWithMemoryHtml(`<div class="x">a<b>b</b></div>`, func(mem *MemoryBackend, hwnd HWND) {
+		original := RootElement(hwnd).Child(0)
+		clone := original.Clone()
+		if !looseEqual(clone.OuterHtml(), original.OuterHtml()) {
+			t.Fatal("Clone should have same contents as original")
+		}
+		if clone.Parent() != nil {
+			t.Fatal("Clone should not be attached to the dom")
+		}
+		clone.SetAttr("class", "y")
+		if class, _ := original.Attr("class"); class != "x" {
+			t.Fatal("Modifying the clone should not affect the original")
+		}
+	})
+}
+
+func TestMemorySwap(t *testing.T) {
+	testWithMemoryHtml(`<div id="a"></div><p><div id="b"></div></p>`, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		a := root.Child(0)
+		b := root.Child(1).Child(0)
+		a.Swap(b)
+		if !looseEqual(root.Html(), `<div id="b"></div><p><div id="a"></div></p>`) {
+			t.Fatal("Elements should have swapped places: ", root.Html())
+		}
+		if !a.Parent().Equals(root.Child(1)) {
+			t.Fatal("Swapped element should have a new parent")
+		}
+	})
+}
+
+func TestMemorySortChildrenRange(t *testing.T) {
+	cmp := func(a, b *Element) int {
+		first := a.Html()[0]
+		second := b.Html()[0]
+		if first == second {
+			return 0
+		} else if first > second {
+			return 1
+		}
+		return -1
+	}
+	testWithMemoryHtml(`<div>d</div><div>c</div><div>b</div><div>a</div>`, func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		root.SortChildrenRange(1, 2, cmp)
+		if !looseEqual(root.Html(), `<div>d</div><div>b</div><div>c</div><div>a</div>`) {
+			t.Fatal("Only the middle elements should be sorted: ", root.Html())
+		}
+		root.SortChildren(cmp)
+		if !looseEqual(root.Html(), `<div>a</div><div>b</div><div>c</div><div>d</div>`) {
+			t.Fatal("All elements should be sorted: ", root.Html())
+		}
+	})
+}
+
+func TestMemorySetHtml(t *testing.T) {
+	SetBackend(NewMemoryBackend())
+	func() {
+		defer SetBackend(nil)
+		defer expectDomError(HLDOM_PASSIVE_HANDLE)
+		NewElement("div").SetHtml("<span></span>")
+	}()
+
+	testWithMemoryHtml(`<p><div id="a"></div></p>`, func(mem *MemoryBackend, hwnd HWND) {
+		p := RootElement(hwnd).Child(0)
+		d := p.Child(0)
+		d.SetHtml("<span></span>")
+		d.PrependHtml("<b></b>")
+		d.AppendHtml("<i></i>")
+		if !looseEqual(d.Html(), `<b></b><span></span><i></i>`) {
+			t.Fatal("Unexpected html: ", d.Html())
+		}
+		if ret := mem.SetElementHtml(d.Handle(), "<hr>", SOH_INSERT_AFTER); ret != HLDOM_OK {
+			t.Fatal("Failed to insert html after element")
+		}
+		if ret := mem.SetElementHtml(d.Handle(), "<br>", SOH_REPLACE); ret != HLDOM_OK {
+			t.Fatal("Failed to replace element")
+		}
+		if !looseEqual(p.Html(), `<br><hr>`) {
+			t.Fatal("Unexpected html: ", p.Html())
+		}
+	})
+}
+
+func TestMemoryText(t *testing.T) {
+	testWithMemoryHtml(`<div>a <b>b</b></div>`, func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		if d.Text() != "a b" {
+			t.Fatal("Unexpected text: ", d.Text())
+		}
+		d.SetText("<x>")
+		if d.Html() != "&lt;x&gt;" {
+			t.Fatal("Text should be escaped in html: ", d.Html())
+		}
+	})
+}
+
+func TestMemoryAttr(t *testing.T) {
+	testWithMemoryHtml(pages["attr"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		if count := d.AttrCount(); count != 4 {
+			t.Fatal("Expected four attributes on div")
+		}
+		expectedKeys := []string{"id", "first", "second", "third"}
+		expectedValues := []string{"a", "5", "5.1", "yes"}
+		for i := range expectedKeys {
+			if key, val := d.AttrByIndex(i); key != expectedKeys[i] || val != expectedValues[i] {
+				t.Fatalf("Expected (%s,%s), got (%s, %s)", expectedKeys[i], expectedValues[i], key, val)
+			}
+		}
+		d.SetAttr("second", 9)
+		if value, _, _ := d.AttrAsInt("second"); value != 9 {
+			t.Fatal("Expected to overwrite attr")
+		}
+		d.RemoveAttr("second")
+		if _, exists := d.Attr("second"); exists {
+			t.Fatal("Should not exist")
+		}
+	})
+}
+
+func TestMemoryClasses(t *testing.T) {
+	testWithMemoryHtml(pages["classes"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		d.AddClass("four")
+		d.RemoveClass("one")
+		if classes, _ := d.Attr("class"); classes != "two three four" {
+			t.Fatalf("Unexpected class attr value: '%s'", classes)
+		}
+		if !d.HasClass("four") || d.HasClass("one") {
+			t.Fatal("Unexpected result from HasClass")
+		}
+	})
+}
+
+func TestMemoryStyle(t *testing.T) {
+	testWithMemoryHtml(pages["css"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		if s, exists := d.Style("left"); !exists || s != "10px" {
+			t.Fatal("Unexpected value for style 'left': ", s)
+		}
+		if s, exists := d.Style("text-align"); !exists || s != "center" {
+			t.Fatal("Unexpected value for style 'text-align': ", s)
+		}
+		d.SetStyle("height", 9)
+		if s, _ := d.Style("height"); s != "9px" {
+			t.Fatal("Unexpected value: ", s)
+		}
+		d.RemoveStyle("left")
+		if _, exists := d.Style("left"); exists {
+			t.Fatal("Should not exist")
+		}
+		d.ClearStyles("")
+		if _, exists := d.Style("opacity"); exists {
+			t.Fatal("Should not exist")
+		}
+	})
+}
+
+func TestMemoryState(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		d.SetState(STATE_FOCUS, true)
+		d.SetState(STATE_HOVER, true)
+		if !d.State(STATE_FOCUS) || !d.State(STATE_HOVER) {
+			t.Fatal("Expected state flags to be set")
+		}
+		if f := FocusedElement(hwnd); f == nil || !f.Equals(d) {
+			t.Fatal("Expected the focused element to be the div")
+		}
+		d.SetStateFlags(STATE_CHECKED)
+		if d.StateFlags() != STATE_CHECKED {
+			t.Fatal("Expected state flags to be replaced")
+		}
+	})
+}
+
+func TestMemoryValue(t *testing.T) {
+	testWithMemoryHtml(pages["input"], func(mem *MemoryBackend, hwnd HWND) {
+		input := RootElement(hwnd).Child(0)
+		if s, err := input.ValueAsString(); err != nil {
+			t.Fatal(err)
+		} else if s != "test string" {
+			t.Fatal("Unexpected value: ", s)
+		}
+		input.SetValue("woohoo")
+		if s, _ := input.ValueAsString(); s != "woohoo" {
+			t.Fatal("Unexpected value: ", s)
+		}
+	})
+}
+
+func TestMemoryEvents(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		outer := root.Child(0)
+		inner := outer.Child(0)
+
+		var order []string
+		attached := false
+		handler := func(name string, handle bool) *EventHandler {
+			return &EventHandler{
+				OnAttached: func(he HELEMENT) { attached = true },
+				OnBehaviorEvent: func(he HELEMENT, params *BehaviorEventParams) bool {
+					if params.Cmd&SINKING != 0 {
+						order = append(order, name+" sinking")
+					} else {
+						order = append(order, name)
+					}
+					return handle && params.Cmd&SINKING == 0
+				},
+			}
+		}
+		outer.AttachHandler(handler("outer", true))
+		inner.AttachHandler(handler("inner", false))
+		if !attached {
+			t.Fatal("Handler should be notified when attached")
+		}
+
+		if !inner.SendEvent(FIRST_APPLICATION_EVENT_CODE, inner, 0) {
+			t.Fatal("Event should have been handled by the outer div")
+		}
+		expected := []string{"outer sinking", "inner sinking", "inner", "outer"}
+		if len(order) != len(expected) {
+			t.Fatal("Unexpected event order: ", order)
+		}
+		for i := range expected {
+			if order[i] != expected[i] {
+				t.Fatal("Unexpected event order: ", order)
+			}
+		}
+
+		order = nil
+		inner.PostEvent(FIRST_APPLICATION_EVENT_CODE, inner, 0)
+		if len(order) != 0 {
+			t.Fatal("Posted event should not be delivered until processed")
+		}
+		if mem.ProcessPostedEvents() != 1 || len(order) == 0 {
+			t.Fatal("Posted event should have been delivered")
+		}
+	})
+}
+
+func TestMemoryTimer(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		ticks := 0
+		d.AttachHandler(&EventHandler{
+			OnTimer: func(he HELEMENT, params *TimerParams) bool {
+				ticks++
+				return ticks < 2
+			},
+		})
+		if mem.FireTimer(d.Handle()) {
+			t.Fatal("Timer should not fire before it is set")
+		}
+		d.SetTimer(10)
+		if !mem.FireTimer(d.Handle()) || mem.FireTimer(d.Handle()) || ticks != 2 {
+			t.Fatal("Timer should stop once the handler returns false")
+		}
+	})
+}
+
+func TestMemoryDetachHandlerOnDelete(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		detached := false
+		d.Child(0).AttachHandler(&EventHandler{
+			OnDetached: func(he HELEMENT) { detached = true },
+		})
+		d.Delete()
+		if !detached {
+			t.Fatal("Deleting an element should detach the handlers in its subtree")
+		}
+	})
+}
+
+func TestMemoryFireEvent(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		var cmds []uint32
+		RootElement(hwnd).AttachHandler(&EventHandler{
+			OnMouse: func(he HELEMENT, params *MouseParams) bool {
+				cmds = append(cmds, params.Cmd)
+				return false
+			},
+		})
+		params := &MouseParams{Cmd: MOUSE_DOWN, Target: d.Handle()}
+		if mem.FireEvent(d.Handle(), HANDLE_MOUSE, unsafe.Pointer(params)) {
+			t.Fatal("Event should not have been handled")
+		}
+		if len(cmds) != 2 || cmds[0] != MOUSE_DOWN|SINKING || cmds[1] != MOUSE_DOWN {
+			t.Fatal("Expected the root to see the event in both phases: ", cmds)
+		}
+		if params.Cmd != MOUSE_DOWN {
+			t.Fatal("Params should be restored after dispatch")
+		}
+	})
+}
@@ -0,0 +1,485 @@
+package gohl
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A node in the in-memory dom.  Text nodes have an empty tag and
+// carry their contents in text.
+type memNode struct {
+	tag      string
+	text     string
+	attrs    []memAttr
+	styles   []memAttr
+	state    uint32
+	rect     Rect
+	parent   *memNode
+	children []*memNode
+
+	// Bookkeeping used by MemoryBackend
+	hwnd     HWND
+	handle   HELEMENT
+	refCount int32
+	deleted  bool
+	timer    uint
+	handlers []memHandler
+	value    *string
+}
+
+type memAttr struct {
+	name  string
+	value string
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+var rawTextElements = map[string]bool{
+	"script": true, "style": true,
+}
+
+var htmlEntities = map[string]string{
+	"amp": "&", "lt": "<", "gt": ">", "quot": "\"", "apos": "'", "nbsp": " ",
+}
+
+func (n *memNode) isText() bool {
+	return n.tag == ""
+}
+
+func (n *memNode) attr(name string) (string, bool) {
+	for _, a := range n.attrs {
+		if a.name == name {
+			return a.value, true
+		}
+	}
+	return "", false
+}
+
+func (n *memNode) setAttr(name string, value *string) {
+	for i, a := range n.attrs {
+		if a.name == name {
+			if value == nil {
+				n.attrs = append(n.attrs[:i], n.attrs[i+1:]...)
+			} else {
+				n.attrs[i].value = *value
+			}
+			return
+		}
+	}
+	if value != nil {
+		n.attrs = append(n.attrs, memAttr{name, *value})
+	}
+}
+
+// The element children of this node, text nodes excluded
+func (n *memNode) elements() []*memNode {
+	elements := make([]*memNode, 0, len(n.children))
+	for _, c := range n.children {
+		if !c.isText() {
+			elements = append(elements, c)
+		}
+	}
+	return elements
+}
+
+func (n *memNode) elementIndex() int {
+	if n.parent == nil {
+		return 0
+	}
+	for i, c := range n.parent.elements() {
+		if c == n {
+			return i
+		}
+	}
+	return -1
+}
+
+func (n *memNode) root() *memNode {
+	for n.parent != nil {
+		n = n.parent
+	}
+	return n
+}
+
+func (n *memNode) detach() {
+	if p := n.parent; p != nil {
+		for i, c := range p.children {
+			if c == n {
+				p.children = append(p.children[:i], p.children[i+1:]...)
+				break
+			}
+		}
+		n.parent = nil
+	}
+}
+
+// Inserts child before the element child at index, or at the end if index
+// is past the last element.
+func (n *memNode) insertElement(child *memNode, index int) {
+	child.detach()
+	pos := len(n.children)
+	for i, c := range n.children {
+		if c.isText() {
+			continue
+		}
+		if index == 0 {
+			pos = i
+			break
+		}
+		index--
+	}
+	n.insertAt(pos, child)
+}
+
+// Inserts detached nodes at position pos of the children list
+func (n *memNode) insertAt(pos int, nodes ...*memNode) {
+	tail := append([]*memNode(nil), n.children[pos:]...)
+	n.children = append(append(n.children[:pos], nodes...), tail...)
+	for _, c := range nodes {
+		c.parent = n
+	}
+}
+
+func (n *memNode) clone() *memNode {
+	c := &memNode{
+		tag:    n.tag,
+		text:   n.text,
+		attrs:  append([]memAttr(nil), n.attrs...),
+		styles: append([]memAttr(nil), n.styles...),
+		state:  n.state,
+		rect:   n.rect,
+	}
+	if n.value != nil {
+		v := *n.value
+		c.value = &v
+	}
+	for _, child := range n.children {
+		cc := child.clone()
+		cc.parent = c
+		c.children = append(c.children, cc)
+	}
+	return c
+}
+
+func (n *memNode) innerText() string {
+	if n.isText() {
+		return n.text
+	}
+	parts := make([]string, 0, len(n.children))
+	for _, c := range n.children {
+		parts = append(parts, c.innerText())
+	}
+	return strings.Join(parts, "")
+}
+
+func (n *memNode) innerHtml() string {
+	var b strings.Builder
+	for _, c := range n.children {
+		c.writeHtml(&b)
+	}
+	return b.String()
+}
+
+func (n *memNode) outerHtml() string {
+	var b strings.Builder
+	n.writeHtml(&b)
+	return b.String()
+}
+
+func (n *memNode) writeHtml(b *strings.Builder) {
+	if n.isText() {
+		b.WriteString(escapeHtml(n.text, false))
+		return
+	}
+	b.WriteString("<" + n.tag)
+	for _, a := range n.attrs {
+		b.WriteString(" " + a.name + "=\"" + escapeHtml(a.value, true) + "\"")
+	}
+	b.WriteString(">")
+	if voidElements[n.tag] {
+		return
+	}
+	for _, c := range n.children {
+		c.writeHtml(b)
+	}
+	b.WriteString("</" + n.tag + ">")
+}
+
+func escapeHtml(s string, attr bool) string {
+	s = strings.Replace(s, "&", "&amp;", -1)
+	s = strings.Replace(s, "<", "&lt;", -1)
+	s = strings.Replace(s, ">", "&gt;", -1)
+	if attr {
+		s = strings.Replace(s, "\"", "&quot;", -1)
+	}
+	return s
+}
+
+func unescapeHtml(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	var b strings.Builder
+	for len(s) > 0 {
+		amp := strings.IndexByte(s, '&')
+		if amp < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:amp])
+		s = s[amp:]
+		semi := strings.IndexByte(s, ';')
+		if semi < 0 {
+			b.WriteString(s)
+			break
+		}
+		name := s[1:semi]
+		if replacement, ok := htmlEntities[name]; ok {
+			b.WriteString(replacement)
+		} else if r, ok := numericEntity(name); ok {
+			b.WriteRune(r)
+		} else {
+			b.WriteString(s[:semi+1])
+		}
+		s = s[semi+1:]
+	}
+	return b.String()
+}
+
+func numericEntity(name string) (rune, bool) {
+	if !strings.HasPrefix(name, "#") {
+		return 0, false
+	}
+	var n uint64
+	var err error
+	if strings.HasPrefix(name, "#x") || strings.HasPrefix(name, "#X") {
+		n, err = strconv.ParseUint(name[2:], 16, 32)
+	} else {
+		n, err = strconv.ParseUint(name[1:], 10, 32)
+	}
+	if err != nil || !utf8.ValidRune(rune(n)) {
+		return 0, false
+	}
+	return rune(n), true
+}
+
+// Parses an html fragment into a list of sibling nodes.  The parser is
+// lenient in the same way the engine is: unknown end tags are dropped
+// and unclosed elements are closed at the end of their parent.
+func parseHtml(html string) ([]*memNode, error) {
+	container := &memNode{tag: "#fragment"}
+	stack := []*memNode{container}
+	top := func() *memNode { return stack[len(stack)-1] }
+
+	appendText := func(text string) {
+		if text == "" {
+			return
+		}
+		parent := top()
+		if n := len(parent.children); n > 0 && parent.children[n-1].isText() {
+			parent.children[n-1].text += text
+			return
+		}
+		parent.insertAt(len(parent.children), &memNode{text: text})
+	}
+
+	for len(html) > 0 {
+		lt := strings.IndexByte(html, '<')
+		if lt < 0 {
+			appendText(unescapeHtml(html))
+			break
+		}
+		appendText(unescapeHtml(html[:lt]))
+		html = html[lt:]
+
+		switch {
+		case strings.HasPrefix(html, "<!--"):
+			end := strings.Index(html, "-->")
+			if end < 0 {
+				return nil, errors.New("Unterminated comment")
+			}
+			html = html[end+3:]
+		case strings.HasPrefix(html, "<!"), strings.HasPrefix(html, "<?"):
+			end := strings.IndexByte(html, '>')
+			if end < 0 {
+				return nil, errors.New("Unterminated declaration")
+			}
+			html = html[end+1:]
+		case strings.HasPrefix(html, "</"):
+			end := strings.IndexByte(html, '>')
+			if end < 0 {
+				return nil, errors.New("Unterminated end tag")
+			}
+			name := strings.ToLower(strings.TrimSpace(html[2:end]))
+			html = html[end+1:]
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == name {
+					stack = stack[:i]
+					break
+				}
+			}
+		default:
+			node, selfClosing, rest, ok := parseStartTag(html)
+			if !ok {
+				// Not a tag after all, treat the '<' as text
+				appendText("<")
+				html = html[1:]
+				continue
+			}
+			html = rest
+			top().insertAt(len(top().children), node)
+			if rawTextElements[node.tag] {
+				end := strings.Index(strings.ToLower(html), "</"+node.tag)
+				if end < 0 {
+					end = len(html)
+				}
+				if end > 0 {
+					node.children = append(node.children, &memNode{text: html[:end], parent: node})
+				}
+				html = html[end:]
+				if close := strings.IndexByte(html, '>'); close >= 0 {
+					html = html[close+1:]
+				}
+			} else if !selfClosing && !voidElements[node.tag] {
+				stack = append(stack, node)
+			}
+		}
+	}
+
+	nodes := container.children
+	for _, n := range nodes {
+		n.parent = nil
+	}
+	return nodes, nil
+}
+
+// Parses a start tag at the beginning of s.  Returns the new element, whether the tag
+// was self closing and the remainder of the input.
+func parseStartTag(s string) (node *memNode, selfClosing bool, rest string, ok bool) {
+	i := 1
+	start := i
+	for i < len(s) && isNameChar(rune(s[i])) {
+		i++
+	}
+	if i == start {
+		return nil, false, s, false
+	}
+	node = &memNode{tag: strings.ToLower(s[start:i])}
+
+	for {
+		for i < len(s) && unicode.IsSpace(rune(s[i])) {
+			i++
+		}
+		if i >= len(s) {
+			return nil, false, s, false
+		}
+		if s[i] == '>' {
+			return node, false, s[i+1:], true
+		}
+		if strings.HasPrefix(s[i:], "/>") {
+			return node, true, s[i+2:], true
+		}
+
+		start = i
+		for i < len(s) && s[i] != '=' && s[i] != '>' && s[i] != '/' && !unicode.IsSpace(rune(s[i])) {
+			i++
+		}
+		name := strings.ToLower(s[start:i])
+		if name == "" {
+			// Stray character, skip it
+			i++
+			continue
+		}
+		for i < len(s) && unicode.IsSpace(rune(s[i])) {
+			i++
+		}
+		value := ""
+		if i < len(s) && s[i] == '=' {
+			i++
+			for i < len(s) && unicode.IsSpace(rune(s[i])) {
+				i++
+			}
+			if i < len(s) && (s[i] == '"' || s[i] == '\'') {
+				quote := s[i]
+				end := strings.IndexByte(s[i+1:], quote)
+				if end < 0 {
+					return nil, false, s, false
+				}
+				value = s[i+1 : i+1+end]
+				i += end + 2
+			} else {
+				start = i
+				for i < len(s) && s[i] != '>' && !unicode.IsSpace(rune(s[i])) {
+					i++
+				}
+				value = s[start:i]
+			}
+		}
+		value = unescapeHtml(value)
+		if name == "style" {
+			node.styles = parseInlineStyle(value)
+		}
+		node.setAttr(name, &value)
+	}
+}
+
+func isNameChar(r rune) bool {
+	return r == '-' || r == '_' || r == ':' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Properties whose unitless numeric values the engine reports in pixels
+var lengthStyles = map[string]bool{
+	"left": true, "top": true, "right": true, "bottom": true,
+	"width": true, "height": true, "min-width": true, "min-height": true, "max-width": true, "max-height": true,
+	"margin-left": true, "margin-top": true, "margin-right": true, "margin-bottom": true,
+	"padding-left": true, "padding-top": true, "padding-right": true, "padding-bottom": true,
+	"border-width": true, "font-size": true, "line-height": true, "border-spacing": true,
+}
+
+func parseInlineStyle(css string) []memAttr {
+	styles := make([]memAttr, 0, 4)
+	for _, decl := range strings.Split(css, ";") {
+		colon := strings.IndexByte(decl, ':')
+		if colon < 0 {
+			continue
+		}
+		name := strings.ToLower(strings.TrimSpace(decl[:colon]))
+		value := strings.TrimSpace(decl[colon+1:])
+		if name == "" {
+			continue
+		}
+		styles = setStyle(styles, name, &value)
+	}
+	return styles
+}
+
+func setStyle(styles []memAttr, name string, value *string) []memAttr {
+	for i, s := range styles {
+		if s.name == name {
+			if value == nil {
+				return append(styles[:i], styles[i+1:]...)
+			}
+			styles[i].value = normalizeStyle(name, *value)
+			return styles
+		}
+	}
+	if value != nil {
+		styles = append(styles, memAttr{name, normalizeStyle(name, *value)})
+	}
+	return styles
+}
+
+func normalizeStyle(name, value string) string {
+	if lengthStyles[name] {
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return value + "px"
+		}
+	}
+	return value
+}
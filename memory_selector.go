@@ -0,0 +1,492 @@
+package gohl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A parsed css selector list, as understood by the in-memory dom.  Supports type,
+// universal, id, class and attribute selectors, the descendant, child and sibling
+// combinators, the structural pseudo classes, :not(), the htmlayout specific
+// :has-child-of-type() family and the pseudo classes backed by element state flags.
+type memSelector []memComplexSelector
+
+// Compound selectors ordered left to right.  combinators[i] joins compounds[i]
+// and compounds[i+1].
+type memComplexSelector struct {
+	compounds   []memCompoundSelector
+	combinators []byte
+}
+
+type memCompoundSelector struct {
+	tag     string
+	id      string
+	classes []string
+	attrs   []memAttrSelector
+	pseudos []memPseudoSelector
+}
+
+type memAttrSelector struct {
+	name  string
+	op    string
+	value string
+}
+
+type memPseudoSelector struct {
+	name string
+	arg  string
+	not  *memCompoundSelector
+}
+
+var stateSelectors = map[string]uint32{
+	"link":        STATE_LINK,
+	"hover":       STATE_HOVER,
+	"active":      STATE_ACTIVE,
+	"focus":       STATE_FOCUS,
+	"visited":     STATE_VISITED,
+	"current":     STATE_CURRENT,
+	"checked":     STATE_CHECKED,
+	"disabled":    STATE_DISABLED,
+	"read-only":   STATE_READONLY,
+	"expanded":    STATE_EXPANDED,
+	"collapsed":   STATE_COLLAPSED,
+	"incomplete":  STATE_INCOMPLETE,
+	"animating":   STATE_ANIMATING,
+	"focusable":   STATE_FOCUSABLE,
+	"anchor":      STATE_ANCHOR,
+	"synthetic":   STATE_SYNTHETIC,
+	"owns-popup":  STATE_OWNS_POPUP,
+	"tab-focus":   STATE_TABFOCUS,
+	"busy":        STATE_BUSY,
+	"drag-over":   STATE_DRAG_OVER,
+	"drop-target": STATE_DROP_TARGET,
+	"moving":      STATE_MOVING,
+	"copying":     STATE_COPYING,
+	"drag-source": STATE_DRAG_SOURCE,
+	"popup":       STATE_POPUP,
+	"pressed":     STATE_PRESSED,
+	"ltr":         STATE_IS_LTR,
+	"rtl":         STATE_IS_RTL,
+}
+
+func parseSelector(selector string) (memSelector, error) {
+	p := &selectorParser{s: selector}
+	var list memSelector
+	for {
+		complex, err := p.complex()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, complex)
+		p.skipSpace()
+		if p.done() {
+			return list, nil
+		}
+		if !p.consume(',') {
+			return nil, p.errorf("unexpected character %q", p.peek())
+		}
+	}
+}
+
+type selectorParser struct {
+	s   string
+	pos int
+}
+
+func (p *selectorParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("Bad selector '%s' at %d: %s", p.s, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *selectorParser) done() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *selectorParser) peek() byte {
+	if p.done() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *selectorParser) consume(c byte) bool {
+	if p.peek() == c && !p.done() {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *selectorParser) skipSpace() bool {
+	start := p.pos
+	for !p.done() && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+	return p.pos > start
+}
+
+func (p *selectorParser) ident() string {
+	start := p.pos
+	for !p.done() && isNameChar(rune(p.s[p.pos])) && p.s[p.pos] != ':' {
+		p.pos++
+	}
+	return p.s[start:p.pos]
+}
+
+func (p *selectorParser) complex() (memComplexSelector, error) {
+	var complex memComplexSelector
+	p.skipSpace()
+	for {
+		compound, err := p.compound()
+		if err != nil {
+			return complex, err
+		}
+		complex.compounds = append(complex.compounds, compound)
+
+		sawSpace := p.skipSpace()
+		switch c := p.peek(); {
+		case c == '>' || c == '+' || c == '~':
+			p.pos++
+			p.skipSpace()
+			complex.combinators = append(complex.combinators, c)
+		case p.done() || c == ',' || c == ')':
+			return complex, nil
+		case sawSpace:
+			complex.combinators = append(complex.combinators, ' ')
+		default:
+			return complex, p.errorf("unexpected character %q", c)
+		}
+	}
+}
+
+func (p *selectorParser) compound() (memCompoundSelector, error) {
+	var c memCompoundSelector
+	if p.consume('*') {
+		c.tag = "*"
+	} else {
+		c.tag = strings.ToLower(p.ident())
+	}
+	for !p.done() {
+		switch p.peek() {
+		case '#':
+			p.pos++
+			if c.id = p.ident(); c.id == "" {
+				return c, p.errorf("expected id")
+			}
+		case '.':
+			p.pos++
+			class := p.ident()
+			if class == "" {
+				return c, p.errorf("expected class name")
+			}
+			c.classes = append(c.classes, class)
+		case '[':
+			p.pos++
+			attr, err := p.attribute()
+			if err != nil {
+				return c, err
+			}
+			c.attrs = append(c.attrs, attr)
+		case ':':
+			p.pos++
+			pseudo, err := p.pseudo()
+			if err != nil {
+				return c, err
+			}
+			c.pseudos = append(c.pseudos, pseudo)
+		default:
+			if c.tag == "" && c.id == "" && len(c.classes) == 0 && len(c.attrs) == 0 && len(c.pseudos) == 0 {
+				return c, p.errorf("expected selector")
+			}
+			return c, nil
+		}
+	}
+	if c.tag == "" && c.id == "" && len(c.classes) == 0 && len(c.attrs) == 0 && len(c.pseudos) == 0 {
+		return c, p.errorf("expected selector")
+	}
+	return c, nil
+}
+
+func (p *selectorParser) attribute() (memAttrSelector, error) {
+	var a memAttrSelector
+	p.skipSpace()
+	if a.name = strings.ToLower(p.ident()); a.name == "" {
+		return a, p.errorf("expected attribute name")
+	}
+	p.skipSpace()
+	if p.consume(']') {
+		return a, nil
+	}
+	for _, op := range []string{"~=", "|=", "^=", "$=", "*=", "="} {
+		if strings.HasPrefix(p.s[p.pos:], op) {
+			a.op = op
+			p.pos += len(op)
+			break
+		}
+	}
+	if a.op == "" {
+		return a, p.errorf("expected attribute operator")
+	}
+	p.skipSpace()
+	if q := p.peek(); q == '"' || q == '\'' {
+		end := strings.IndexByte(p.s[p.pos+1:], q)
+		if end < 0 {
+			return a, p.errorf("unterminated string")
+		}
+		a.value = p.s[p.pos+1 : p.pos+1+end]
+		p.pos += end + 2
+	} else {
+		a.value = p.ident()
+	}
+	p.skipSpace()
+	if !p.consume(']') {
+		return a, p.errorf("expected ']'")
+	}
+	return a, nil
+}
+
+func (p *selectorParser) pseudo() (memPseudoSelector, error) {
+	var ps memPseudoSelector
+	start := p.pos
+	for !p.done() && (isNameChar(rune(p.s[p.pos])) && p.s[p.pos] != ':') {
+		p.pos++
+	}
+	ps.name = strings.ToLower(p.s[start:p.pos])
+	if ps.name == "" {
+		return ps, p.errorf("expected pseudo class")
+	}
+	if p.consume('(') {
+		if ps.name == "not" {
+			p.skipSpace()
+			not, err := p.compound()
+			if err != nil {
+				return ps, err
+			}
+			ps.not = &not
+			p.skipSpace()
+		} else {
+			end := strings.IndexByte(p.s[p.pos:], ')')
+			if end < 0 {
+				return ps, p.errorf("expected ')'")
+			}
+			ps.arg = strings.TrimSpace(p.s[p.pos : p.pos+end])
+			p.pos += end
+		}
+		if !p.consume(')') {
+			return ps, p.errorf("expected ')'")
+		}
+	}
+	switch ps.name {
+	case "root", "first-child", "last-child", "only-child", "empty", "not",
+		"has-child-of-type", "has-children-of-type", "nth-child", "nth-last-child":
+	default:
+		if _, ok := stateSelectors[ps.name]; !ok {
+			return ps, p.errorf("unsupported pseudo class '%s'", ps.name)
+		}
+	}
+	return ps, nil
+}
+
+func (s memSelector) matches(n *memNode) bool {
+	for _, complex := range s {
+		if complex.matches(n, len(complex.compounds)-1) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches the compounds up to and including index i against n, right to left
+func (c memComplexSelector) matches(n *memNode, i int) bool {
+	if !c.compounds[i].matches(n) {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	switch c.combinators[i-1] {
+	case '>':
+		return n.parent != nil && c.matches(n.parent, i-1)
+	case ' ':
+		for p := n.parent; p != nil; p = p.parent {
+			if c.matches(p, i-1) {
+				return true
+			}
+		}
+	case '+':
+		if prev := n.previousElement(); prev != nil {
+			return c.matches(prev, i-1)
+		}
+	case '~':
+		for prev := n.previousElement(); prev != nil; prev = prev.previousElement() {
+			if c.matches(prev, i-1) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *memCompoundSelector) matches(n *memNode) bool {
+	if n.isText() {
+		return false
+	}
+	if c.tag != "" && c.tag != "*" && c.tag != n.tag {
+		return false
+	}
+	if c.id != "" {
+		if id, _ := n.attr("id"); id != c.id {
+			return false
+		}
+	}
+	if len(c.classes) > 0 {
+		classList, _ := n.attr("class")
+		classes := strings.Fields(classList)
+		for _, want := range c.classes {
+			found := false
+			for _, class := range classes {
+				if class == want {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	for _, a := range c.attrs {
+		if !a.matches(n) {
+			return false
+		}
+	}
+	for _, ps := range c.pseudos {
+		if !ps.matches(n) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *memAttrSelector) matches(n *memNode) bool {
+	value, exists := n.attr(a.name)
+	if !exists {
+		return false
+	}
+	switch a.op {
+	case "":
+		return true
+	case "=":
+		return value == a.value
+	case "~=":
+		for _, word := range strings.Fields(value) {
+			if word == a.value {
+				return true
+			}
+		}
+		return false
+	case "|=":
+		return value == a.value || strings.HasPrefix(value, a.value+"-")
+	case "^=":
+		return strings.HasPrefix(value, a.value)
+	case "$=":
+		return strings.HasSuffix(value, a.value)
+	case "*=":
+		return strings.Contains(value, a.value)
+	}
+	return false
+}
+
+func (ps *memPseudoSelector) matches(n *memNode) bool {
+	switch ps.name {
+	case "root":
+		return n.parent == nil
+	case "first-child":
+		return n.parent != nil && n.elementIndex() == 0
+	case "last-child":
+		return n.parent != nil && n.elementIndex() == len(n.parent.elements())-1
+	case "only-child":
+		return n.parent != nil && len(n.parent.elements()) == 1
+	case "nth-child", "nth-last-child":
+		if n.parent == nil {
+			return false
+		}
+		index := n.elementIndex()
+		if ps.name == "nth-last-child" {
+			index = len(n.parent.elements()) - 1 - index
+		}
+		return nthMatches(ps.arg, index+1)
+	case "empty":
+		return len(n.children) == 0
+	case "not":
+		return !ps.not.matches(n)
+	case "has-child-of-type", "has-children-of-type":
+		count := 0
+		for _, c := range n.elements() {
+			if c.tag == strings.ToLower(ps.arg) {
+				count++
+			}
+		}
+		if ps.name == "has-child-of-type" {
+			return count == 1
+		}
+		return count > 0
+	}
+	flag := stateSelectors[ps.name]
+	return n.state&flag == flag
+}
+
+// Evaluates an an+b expression (or odd/even) against a 1-based position
+func nthMatches(expr string, position int) bool {
+	expr = strings.ToLower(strings.Replace(expr, " ", "", -1))
+	switch expr {
+	case "odd":
+		expr = "2n+1"
+	case "even":
+		expr = "2n"
+	}
+	n := strings.IndexByte(expr, 'n')
+	if n < 0 {
+		b, err := strconv.Atoi(expr)
+		return err == nil && position == b
+	}
+	a := 1
+	switch expr[:n] {
+	case "":
+	case "-":
+		a = -1
+	default:
+		var err error
+		if a, err = strconv.Atoi(expr[:n]); err != nil {
+			return false
+		}
+	}
+	b := 0
+	if rest := expr[n+1:]; rest != "" {
+		var err error
+		if b, err = strconv.Atoi(strings.TrimPrefix(rest, "+")); err != nil {
+			return false
+		}
+	}
+	if a == 0 {
+		return position == b
+	}
+	k := (position - b) / a
+	return k >= 0 && k*a+b == position
+}
+
+func (n *memNode) previousElement() *memNode {
+	if n.parent == nil {
+		return nil
+	}
+	var prev *memNode
+	for _, c := range n.parent.children {
+		if c == n {
+			return prev
+		}
+		if !c.isText() {
+			prev = c
+		}
+	}
+	return nil
+}
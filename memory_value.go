@@ -0,0 +1,292 @@
+//go:build !windows
+
+package gohl
+
+import (
+	"math"
+	"sync"
+)
+
+// Values kept in Go, for when there is no HTMLayout dll.  Scalars are held in
+// the value's D field as HTMLayout holds them; strings, bytes, arrays and maps
+// are held in a table, and D is their id.
+type memoryValues struct {
+	mutex sync.Mutex
+	data  map[uint64]interface{}
+	last  uint64
+}
+
+// The items of a T_ARRAY, or the keys and items of a T_MAP
+type memoryItems struct {
+	keys  []JsonValue
+	items []JsonValue
+}
+
+var values valueApi = &memoryValues{data: make(map[uint64]interface{})}
+
+func isTableValue(t uint32) bool {
+	switch t {
+	case T_STRING, T_BYTES, T_ARRAY, T_MAP:
+		return true
+	}
+	return false
+}
+
+func (m *memoryValues) Init(v *JsonValue) {
+	*v = JsonValue{}
+}
+
+func (m *memoryValues) Clear(v *JsonValue) {
+	if isTableValue(v.T) {
+		m.mutex.Lock()
+		data := m.data[v.D]
+		delete(m.data, v.D)
+		m.mutex.Unlock()
+		if items, ok := data.(*memoryItems); ok {
+			for i := range items.items {
+				m.Clear(&items.items[i])
+			}
+			for i := range items.keys {
+				m.Clear(&items.keys[i])
+			}
+		}
+	}
+	*v = JsonValue{}
+}
+
+// Replaces v with a new table value
+func (m *memoryValues) store(v *JsonValue, data interface{}, t, units uint32) VALUE_RESULT {
+	m.Clear(v)
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.last++
+	m.data[m.last] = data
+	*v = JsonValue{T: t, U: units, D: m.last}
+	return HV_OK
+}
+
+func (m *memoryValues) load(v *JsonValue, t uint32) (interface{}, VALUE_RESULT) {
+	if v.T != t {
+		return nil, HV_INCOMPATIBLE_TYPE
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	data, ok := m.data[v.D]
+	if !ok {
+		return nil, HV_BAD_PARAMETER
+	}
+	return data, HV_OK
+}
+
+// Copies src into dst, which is cleared first
+func (m *memoryValues) Copy(dst, src *JsonValue) VALUE_RESULT {
+	if dst == src {
+		return HV_OK
+	}
+	if !isTableValue(src.T) {
+		m.Clear(dst)
+		*dst = *src
+		return HV_OK
+	}
+	data, ret := m.load(src, src.T)
+	if ret != HV_OK {
+		return ret
+	}
+	switch data := data.(type) {
+	case []byte:
+		return m.store(dst, append([]byte(nil), data...), src.T, src.U)
+	case *memoryItems:
+		items := &memoryItems{}
+		if data.keys != nil {
+			items.keys = make([]JsonValue, len(data.keys))
+			for i := range data.keys {
+				m.Copy(&items.keys[i], &data.keys[i])
+			}
+		}
+		items.items = make([]JsonValue, len(data.items))
+		for i := range data.items {
+			m.Copy(&items.items[i], &data.items[i])
+		}
+		return m.store(dst, items, src.T, src.U)
+	}
+	return m.store(dst, data, src.T, src.U)
+}
+
+func (m *memoryValues) IntData(v *JsonValue) (int, VALUE_RESULT) {
+	switch v.T {
+	case T_INT, T_BOOL:
+		return int(int32(v.D)), HV_OK
+	}
+	return 0, HV_INCOMPATIBLE_TYPE
+}
+
+func (m *memoryValues) SetIntData(v *JsonValue, i int, t, units uint32) VALUE_RESULT {
+	switch t {
+	case T_ARRAY:
+		return m.store(v, &memoryItems{items: make([]JsonValue, 0, i)}, t, units)
+	case T_MAP:
+		return m.store(v, &memoryItems{keys: make([]JsonValue, 0, i), items: make([]JsonValue, 0, i)}, t, units)
+	case T_STRING, T_BYTES:
+		return HV_INCOMPATIBLE_TYPE
+	}
+	m.Clear(v)
+	*v = JsonValue{T: t, U: units, D: uint64(uint32(int32(i)))}
+	return HV_OK
+}
+
+func (m *memoryValues) Int64Data(v *JsonValue) (int64, VALUE_RESULT) {
+	switch v.T {
+	case T_DATE, T_CURRENCY, T_DOM_OBJECT:
+		return int64(v.D), HV_OK
+	}
+	return 0, HV_INCOMPATIBLE_TYPE
+}
+
+func (m *memoryValues) SetInt64Data(v *JsonValue, i int64, t, units uint32) VALUE_RESULT {
+	if isTableValue(t) {
+		return HV_INCOMPATIBLE_TYPE
+	}
+	m.Clear(v)
+	*v = JsonValue{T: t, U: units, D: uint64(i)}
+	return HV_OK
+}
+
+func (m *memoryValues) FloatData(v *JsonValue) (float64, VALUE_RESULT) {
+	switch v.T {
+	case T_FLOAT, T_LENGTH:
+		return math.Float64frombits(v.D), HV_OK
+	}
+	return 0, HV_INCOMPATIBLE_TYPE
+}
+
+func (m *memoryValues) SetFloatData(v *JsonValue, f float64, t, units uint32) VALUE_RESULT {
+	if isTableValue(t) {
+		return HV_INCOMPATIBLE_TYPE
+	}
+	m.Clear(v)
+	*v = JsonValue{T: t, U: units, D: math.Float64bits(f)}
+	return HV_OK
+}
+
+func (m *memoryValues) StringData(v *JsonValue) (string, VALUE_RESULT) {
+	data, ret := m.load(v, T_STRING)
+	if ret != HV_OK {
+		return "", ret
+	}
+	return data.(string), HV_OK
+}
+
+func (m *memoryValues) SetStringData(v *JsonValue, s string, units uint32) VALUE_RESULT {
+	return m.store(v, s, T_STRING, units)
+}
+
+func (m *memoryValues) BinaryData(v *JsonValue) ([]byte, VALUE_RESULT) {
+	data, ret := m.load(v, T_BYTES)
+	if ret != HV_OK {
+		return nil, ret
+	}
+	return append([]byte(nil), data.([]byte)...), HV_OK
+}
+
+func (m *memoryValues) SetBinaryData(v *JsonValue, b []byte, t, units uint32) VALUE_RESULT {
+	if t != T_BYTES {
+		return HV_INCOMPATIBLE_TYPE
+	}
+	return m.store(v, append([]byte(nil), b...), t, units)
+}
+
+func (m *memoryValues) items(v *JsonValue) (*memoryItems, VALUE_RESULT) {
+	if v.T != T_ARRAY && v.T != T_MAP {
+		return nil, HV_INCOMPATIBLE_TYPE
+	}
+	data, ret := m.load(v, v.T)
+	if ret != HV_OK {
+		return nil, ret
+	}
+	return data.(*memoryItems), HV_OK
+}
+
+func (m *memoryValues) ElementsCount(v *JsonValue) (int, VALUE_RESULT) {
+	items, ret := m.items(v)
+	if ret != HV_OK {
+		return 0, ret
+	}
+	return len(items.items), HV_OK
+}
+
+func (m *memoryValues) NthElementValue(v *JsonValue, n int, item *JsonValue) VALUE_RESULT {
+	items, ret := m.items(v)
+	if ret != HV_OK {
+		return ret
+	}
+	if n < 0 || n >= len(items.items) {
+		return HV_BAD_PARAMETER
+	}
+	return m.Copy(item, &items.items[n])
+}
+
+func (m *memoryValues) NthElementKey(v *JsonValue, n int, key *JsonValue) VALUE_RESULT {
+	if v.T != T_MAP {
+		return HV_INCOMPATIBLE_TYPE
+	}
+	items, ret := m.items(v)
+	if ret != HV_OK {
+		return ret
+	}
+	if n < 0 || n >= len(items.keys) {
+		return HV_BAD_PARAMETER
+	}
+	return m.Copy(key, &items.keys[n])
+}
+
+// Setting an item past the end of an array grows it with undefined values
+func (m *memoryValues) SetNthElementValue(v *JsonValue, n int, item *JsonValue) VALUE_RESULT {
+	if v.T != T_ARRAY {
+		return HV_INCOMPATIBLE_TYPE
+	}
+	items, ret := m.items(v)
+	if ret != HV_OK {
+		return ret
+	}
+	if n < 0 {
+		return HV_BAD_PARAMETER
+	}
+	for len(items.items) <= n {
+		items.items = append(items.items, JsonValue{})
+	}
+	return m.Copy(&items.items[n], item)
+}
+
+func (m *memoryValues) SetValueToKey(v *JsonValue, key, item *JsonValue) VALUE_RESULT {
+	if v.T != T_MAP {
+		return HV_INCOMPATIBLE_TYPE
+	}
+	items, ret := m.items(v)
+	if ret != HV_OK {
+		return ret
+	}
+	for i := range items.keys {
+		if m.sameKey(&items.keys[i], key) {
+			return m.Copy(&items.items[i], item)
+		}
+	}
+	items.keys = append(items.keys, JsonValue{})
+	items.items = append(items.items, JsonValue{})
+	n := len(items.keys) - 1
+	if ret := m.Copy(&items.keys[n], key); ret != HV_OK {
+		return ret
+	}
+	return m.Copy(&items.items[n], item)
+}
+
+func (m *memoryValues) sameKey(a, b *JsonValue) bool {
+	if a.T != b.T {
+		return false
+	}
+	if a.T == T_STRING {
+		sa, _ := m.StringData(a)
+		sb, _ := m.StringData(b)
+		return sa == sb
+	}
+	return !isTableValue(a.T) && *a == *b
+}
@@ -0,0 +1,37 @@
+//go:build !windows
+
+package gohl
+
+import (
+	"testing"
+)
+
+func TestJsonValueIsOwnedCopy(t *testing.T) {
+	m := values.(*memoryValues)
+	stored := func() int {
+		m.mutex.Lock()
+		defer m.mutex.Unlock()
+		return len(m.data)
+	}
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		d.AttachHandler(&EventHandler{
+			OnMethodCall: func(he HELEMENT, params *MethodParams) bool {
+				if args, ok := params.Decode().(*ValueParams); ok && args.MethodId == GET_VALUE {
+					args.Val.SetValue([]interface{}{"a", "b"})
+					return true
+				}
+				return false
+			},
+		})
+		before := stored()
+		v := d.JsonValue()
+		if x, err := v.Value(); err != nil || len(x.([]interface{})) != 2 {
+			t.Fatal("Unexpected value: ", x, err)
+		}
+		v.Clear()
+		if n := stored(); n != before {
+			t.Fatalf("Expected nothing to be left once the copy is cleared, %d values stored, %d before", n, before)
+		}
+	})
+}
@@ -0,0 +1,157 @@
+package gohl
+
+import (
+	"unsafe"
+)
+
+/*
+Event
+
+An event on its way to an EventHandler, as seen by middleware.  Params points
+to the params struct for the group; use the accessor for the group to get at
+it, e.g. event.Mouse() for HANDLE_MOUSE.
+*/
+type Event struct {
+	// The element the handler is attached to, or the root for window handlers
+	Element HELEMENT
+
+	// One of the HANDLE_* event groups
+	Group uint32
+
+	Params unsafe.Pointer
+}
+
+// The accessors return nil if the event belongs to a different group
+
+func (e *Event) Mouse() *MouseParams {
+	if e.Group != HANDLE_MOUSE {
+		return nil
+	}
+	return (*MouseParams)(e.Params)
+}
+
+func (e *Event) Key() *KeyParams {
+	if e.Group != HANDLE_KEY {
+		return nil
+	}
+	return (*KeyParams)(e.Params)
+}
+
+func (e *Event) Focus() *FocusParams {
+	if e.Group != HANDLE_FOCUS {
+		return nil
+	}
+	return (*FocusParams)(e.Params)
+}
+
+func (e *Event) Draw() *DrawParams {
+	if e.Group != HANDLE_DRAW {
+		return nil
+	}
+	return (*DrawParams)(e.Params)
+}
+
+func (e *Event) Timer() *TimerParams {
+	if e.Group != HANDLE_TIMER {
+		return nil
+	}
+	return (*TimerParams)(e.Params)
+}
+
+func (e *Event) BehaviorEvent() *BehaviorEventParams {
+	if e.Group != HANDLE_BEHAVIOR_EVENT {
+		return nil
+	}
+	return (*BehaviorEventParams)(e.Params)
+}
+
+func (e *Event) MethodCall() *MethodParams {
+	if e.Group != HANDLE_METHOD_CALL {
+		return nil
+	}
+	return (*MethodParams)(e.Params)
+}
+
+func (e *Event) DataArrived() *DataArrivedParams {
+	if e.Group != HANDLE_DATA_ARRIVED {
+		return nil
+	}
+	return (*DataArrivedParams)(e.Params)
+}
+
+func (e *Event) Scroll() *ScrollParams {
+	if e.Group != HANDLE_SCROLL {
+		return nil
+	}
+	return (*ScrollParams)(e.Params)
+}
+
+func (e *Event) Exchange() *ExchangeParams {
+	if e.Group != HANDLE_EXCHANGE {
+		return nil
+	}
+	return (*ExchangeParams)(e.Params)
+}
+
+func (e *Event) Gesture() *GestureParams {
+	if e.Group != HANDLE_GESTURE {
+		return nil
+	}
+	return (*GestureParams)(e.Params)
+}
+
+// Returns the Cmd field of the params, including any phase flags, or false
+// for groups whose params have no command
+func (e *Event) Cmd() (uint32, bool) {
+	switch e.Group {
+	case HANDLE_MOUSE:
+		return e.Mouse().Cmd, true
+	case HANDLE_KEY:
+		return e.Key().Cmd, true
+	case HANDLE_FOCUS:
+		return e.Focus().Cmd, true
+	case HANDLE_DRAW:
+		return e.Draw().Cmd, true
+	case HANDLE_BEHAVIOR_EVENT:
+		return e.BehaviorEvent().Cmd, true
+	case HANDLE_SCROLL:
+		return e.Scroll().Cmd, true
+	case HANDLE_EXCHANGE:
+		return e.Exchange().Cmd, true
+	case HANDLE_GESTURE:
+		return e.Gesture().Cmd, true
+	}
+	return 0, false
+}
+
+/*
+Middleware
+
+Wraps the delivery of an event to an EventHandler's callbacks.  Calling next
+passes the event on to the next middleware in the stack, or to the callback
+once the stack is exhausted, and returns whether it was handled.  Returning
+without calling next stops the event there; the return value says whether it
+counts as handled.
+*/
+type Middleware func(event *Event, next func() bool) bool
+
+// Adds middleware to the handler.  Middleware runs in the order it was added,
+// the first being outermost, and sees every event the handler subscribes to
+// apart from attach and detach.  Returns the handler, so that it can be used
+// in place:
+//
+//	el.AttachHandler((&EventHandler{OnMouse: onMouse}).Use(logEvents, timeEvents))
+func (handler *EventHandler) Use(middleware ...Middleware) *EventHandler {
+	handler.middleware = append(handler.middleware, middleware...)
+	return handler
+}
+
+// Runs the event through the middleware stack, ending with final
+func runMiddleware(middleware []Middleware, event *Event, final func() bool) bool {
+	if len(middleware) == 0 {
+		return final()
+	}
+	return middleware[0](event, func() bool {
+		return runMiddleware(middleware[1:], event, final)
+	})
+}
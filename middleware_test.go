@@ -0,0 +1,106 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// Records its name on the way in and out of the stack
+func tracingMiddleware(name string, trace *[]string) Middleware {
+	return func(event *Event, next func() bool) bool {
+		*trace = append(*trace, name)
+		handled := next()
+		*trace = append(*trace, "/"+name)
+		return handled
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var trace []string
+	final := func() bool {
+		trace = append(trace, "handler")
+		return true
+	}
+	middleware := []Middleware{tracingMiddleware("a", &trace), tracingMiddleware("b", &trace)}
+	if !runMiddleware(middleware, &Event{}, final) {
+		t.Fatal("Handled result should be passed back through the stack")
+	}
+	expected := []string{"a", "b", "handler", "/b", "/a"}
+	if len(trace) != len(expected) {
+		t.Fatal("Unexpected trace: ", trace)
+	}
+	for i := range expected {
+		if trace[i] != expected[i] {
+			t.Fatal("Unexpected trace: ", trace)
+		}
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	tests := []struct {
+		handled bool
+	}{{true}, {false}}
+	for _, test := range tests {
+		var trace []string
+		stop := func(event *Event, next func() bool) bool {
+			return test.handled
+		}
+		middleware := []Middleware{tracingMiddleware("a", &trace), stop, tracingMiddleware("b", &trace)}
+		handled := runMiddleware(middleware, &Event{}, func() bool {
+			t.Fatal("Handler should not be reached")
+			return false
+		})
+		if handled != test.handled {
+			t.Fatal("Expected the stopping middleware's result, got ", handled)
+		}
+		if len(trace) != 2 || trace[0] != "a" || trace[1] != "/a" {
+			t.Fatal("Middleware after the one that stopped should not run: ", trace)
+		}
+	}
+}
+
+func TestMiddlewareOnElement(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		var groups []uint32
+		attached := false
+		handler := (&EventHandler{
+			OnAttached: func(he HELEMENT) { attached = true },
+			OnMouse: func(he HELEMENT, params *MouseParams) bool {
+				return params.Cmd == MOUSE_DOWN
+			},
+		}).Use(func(event *Event, next func() bool) bool {
+			groups = append(groups, event.Group)
+			if event.Element != d.Handle() || event.Mouse() == nil || event.Key() != nil {
+				t.Fatal("Event should describe the mouse event on the element")
+			}
+			// Permission check: swallow mouse up
+			if cmd, _ := event.Cmd(); cmd == MOUSE_UP {
+				return true
+			}
+			return next()
+		})
+		d.AttachHandler(handler)
+		defer d.DetachHandler(handler)
+
+		if !attached || len(groups) != 0 {
+			t.Fatal("Attach should reach the handler without going through the middleware")
+		}
+		fire := func(cmd uint32) bool {
+			params := &MouseParams{Cmd: cmd, Target: d.Handle()}
+			return mem.FireEvent(d.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		}
+		if !fire(MOUSE_DOWN) {
+			t.Fatal("Mouse down should have been handled by the handler")
+		}
+		if !fire(MOUSE_UP) {
+			t.Fatal("Mouse up should have been handled by the middleware")
+		}
+		if fire(MOUSE_MOVE) {
+			t.Fatal("Mouse move should not have been handled")
+		}
+		if len(groups) == 0 {
+			t.Fatal("Middleware was never called")
+		}
+	})
+}
@@ -0,0 +1,47 @@
+package gohl
+
+import (
+	"sync"
+)
+
+// The functions collecting TextSelectionParams output, keyed by the Data
+// handed to goOutputStreamProc.  Go pointers can't be passed through the
+// dll, so they're passed by id.
+var (
+	outputStreamsMutex sync.Mutex
+	outputStreams      = make(map[uintptr]func(uint32) bool)
+	lastOutputStream   uintptr
+)
+
+func registerOutputStream(output func(uint32) bool) uintptr {
+	outputStreamsMutex.Lock()
+	defer outputStreamsMutex.Unlock()
+	lastOutputStream++
+	outputStreams[lastOutputStream] = output
+	return lastOutputStream
+}
+
+func unregisterOutputStream(id uintptr) {
+	outputStreamsMutex.Lock()
+	defer outputStreamsMutex.Unlock()
+	delete(outputStreams, id)
+}
+
+// Returns nil if the stream has been unregistered
+func outputStream(id uintptr) func(uint32) bool {
+	outputStreamsMutex.Lock()
+	defer outputStreamsMutex.Unlock()
+	return outputStreams[id]
+}
+
+// Writes to one of our own streams, or, since the params may have come from
+// the dll, to its stream
+func writeOutputStream(outs, data uintptr, unit uint32) bool {
+	if outs == goOutputStreamProc {
+		if output := outputStream(data); output != nil {
+			return output(unit)
+		}
+		return false
+	}
+	return callOutputStreamProc(outs, data, unit)
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package gohl
+
+// Stands in for the callback pointer; writeOutputStream calls our own
+// streams directly.
+var goOutputStreamProc = ^uintptr(0)
+
+// No stream can come from the dll outside of Windows
+func callOutputStreamProc(outs, data uintptr, unit uint32) bool {
+	return false
+}
@@ -0,0 +1,10 @@
+package gohl
+
+import (
+	"syscall"
+)
+
+func callOutputStreamProc(outs, data uintptr, unit uint32) bool {
+	ret, _, _ := syscall.Syscall(outs, 2, data, uintptr(unit), 0)
+	return ret != 0
+}
@@ -0,0 +1,518 @@
+package gohl
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+/*
+RecordedEvent
+
+One line of a recording.  Elements are identified by their path, the child
+indices leading to them from the root of their document, so that a recording
+can be replayed against a fresh copy of the same document.  Cmd and Element
+are only there for people reading the file.
+*/
+type RecordedEvent struct {
+	Seq uint64 `json:"seq"`
+
+	// Set for events dispatched to an EventHandler, e.g. "HANDLE_MOUSE"
+	Group string `json:"group,omitempty"`
+
+	// Set for notifications dispatched to a NotifyHandler, e.g. "HLN_LOAD_DATA"
+	Notification string `json:"notification,omitempty"`
+	Hwnd         HWND   `json:"hwnd,omitempty"`
+
+	Cmd     string `json:"cmd,omitempty"`
+	Element string `json:"element,omitempty"`
+	Path    []uint `json:"path"`
+
+	// The fields of the params struct, as they were before dispatch.  Element
+	// handles are stored as paths and wide strings as strings.  Pointers and
+	// uintptr fields such as device contexts and callbacks, which would mean
+	// nothing when replayed, are left out, along with the sizes of the buffers
+	// they point to.  For notifications
+	// these are the decoded params, e.g. the behavior name of
+	// HLN_ATTACH_BEHAVIOR or the uri of HLN_LOAD_DATA, as they were once the
+	// notification was handled.
+	Params map[string]interface{} `json:"params,omitempty"`
+
+	// Params fields that held something the recording can't represent, such
+	// as the JsonValue Data of a behavior event.  Replay refuses these events
+	// rather than dispatching them without it.
+	Unrecorded []string `json:"unrecorded,omitempty"`
+
+	Handled bool `json:"handled"`
+}
+
+/*
+Recorder
+
+Writes every event dispatched to an EventHandler, along with the result, as a
+line of JSON.  Notifications dispatched to a NotifyHandler are written as well,
+with their params decoded.  Install it with SetRecorder.  Nothing is recorded
+while Replay is running.
+*/
+type Recorder struct {
+	mutex   sync.Mutex
+	encoder *json.Encoder
+	seq     uint64
+	err     error
+}
+
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{encoder: json.NewEncoder(w)}
+}
+
+// Returns the first error writing to the writer; nothing more is written after it
+func (r *Recorder) Err() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.err
+}
+
+func (r *Recorder) write(event *RecordedEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.err != nil {
+		return
+	}
+	r.seq++
+	event.Seq = r.seq
+	r.err = r.encoder.Encode(event)
+}
+
+var (
+	recorderMutex sync.Mutex
+	recorder      *Recorder
+
+	// Number of calls to Replay in progress
+	replaying int
+)
+
+// Starts recording every dispatched event to the recorder, or stops recording
+// if it is nil
+func SetRecorder(r *Recorder) {
+	recorderMutex.Lock()
+	recorder = r
+	recorderMutex.Unlock()
+}
+
+func currentRecorder() *Recorder {
+	recorderMutex.Lock()
+	defer recorderMutex.Unlock()
+	if replaying > 0 {
+		return nil
+	}
+	return recorder
+}
+
+// Stops recording until the returned function is called, so that replayed
+// events don't end up in the recording
+func suspendRecording() func() {
+	recorderMutex.Lock()
+	replaying++
+	recorderMutex.Unlock()
+	return func() {
+		recorderMutex.Lock()
+		replaying--
+		recorderMutex.Unlock()
+	}
+}
+
+// The params struct for each event group.  HANDLE_SIZE has none.
+var eventParamsTypes = map[uint32]reflect.Type{
+	HANDLE_INITIALIZATION: reflect.TypeOf(InitializationParams{}),
+	HANDLE_MOUSE:          reflect.TypeOf(MouseParams{}),
+	HANDLE_KEY:            reflect.TypeOf(KeyParams{}),
+	HANDLE_FOCUS:          reflect.TypeOf(FocusParams{}),
+	HANDLE_DRAW:           reflect.TypeOf(DrawParams{}),
+	HANDLE_TIMER:          reflect.TypeOf(TimerParams{}),
+	HANDLE_BEHAVIOR_EVENT: reflect.TypeOf(BehaviorEventParams{}),
+	HANDLE_METHOD_CALL:    reflect.TypeOf(MethodParams{}),
+	HANDLE_DATA_ARRIVED:   reflect.TypeOf(DataArrivedParams{}),
+	HANDLE_SCROLL:         reflect.TypeOf(ScrollParams{}),
+	HANDLE_EXCHANGE:       reflect.TypeOf(ExchangeParams{}),
+	HANDLE_GESTURE:        reflect.TypeOf(GestureParams{}),
+}
+
+var (
+	helementType  = reflect.TypeOf(HELEMENT(0))
+	jsonValueType = reflect.TypeOf(JsonValue{})
+	wideCharsType = reflect.TypeOf((*uint16)(nil))
+)
+
+// The event groups whose params can't be rebuilt from a recording: method
+// params are followed by a struct that depends on the method id, exchange
+// params carry a callback, draw params a device context and data arrived
+// params a buffer.  Replay refuses them rather than making something up.
+var unreplayableGroups = map[uint32]bool{
+	HANDLE_DRAW:         true,
+	HANDLE_METHOD_CALL:  true,
+	HANDLE_DATA_ARRIVED: true,
+	HANDLE_EXCHANGE:     true,
+}
+
+// Reports whether a field can be stored in a recording.  Numbers and structs
+// of numbers can, pointers and uintptrs can't.  JsonValue is left out since it
+// may point at its data.
+func recordable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Int,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uint,
+		reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Struct:
+		if t == jsonValueType {
+			return false
+		}
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath != "" || !recordable(f.Type) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Reports whether the field is the size of the buffer another field points to,
+// like DataSize next to Data, which means nothing once the pointer is left out
+func bufferSize(t reflect.Type, f reflect.StructField) bool {
+	if !strings.HasSuffix(f.Name, "Size") {
+		return false
+	}
+	buffer, exists := t.FieldByName(strings.TrimSuffix(f.Name, "Size"))
+	return exists && buffer.Type.Kind() == reflect.Ptr
+}
+
+// Returns the child indices leading from the document root to the element, or
+// nil if the handle is nil
+func elementPath(he HELEMENT) []uint {
+	if he == BAD_HELEMENT {
+		return nil
+	}
+	path := make([]uint, 0, 8)
+	for {
+		parent, ret := dom.ParentElement(he)
+		if ret != HLDOM_OK || parent == BAD_HELEMENT {
+			break
+		}
+		index, ret := dom.ElementIndex(he)
+		if ret != HLDOM_OK {
+			break
+		}
+		path = append(path, index)
+		he = parent
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
+
+// The inverse of elementPath
+func resolvePath(root HELEMENT, path []uint) (HELEMENT, error) {
+	if path == nil {
+		return BAD_HELEMENT, nil
+	}
+	he := root
+	for _, index := range path {
+		child, ret := dom.NthChild(he, index)
+		if ret != HLDOM_OK {
+			return BAD_HELEMENT, domError(ret, "Failed to find recorded element at path ", path)
+		}
+		if child == BAD_HELEMENT {
+			return BAD_HELEMENT, fmt.Errorf("no recorded element at path %v", path)
+		}
+		he = child
+	}
+	return he, nil
+}
+
+// A wide string as a string, or nil for a null pointer
+func recordWideChars(s *uint16) interface{} {
+	if s == nil {
+		return nil
+	}
+	return utf16ToString(s)
+}
+
+// Returns the recordable fields of the params, and the names of the fields
+// whose contents had to be left out
+func recordParams(evtg uint32, params unsafe.Pointer) (map[string]interface{}, []string) {
+	t, exists := eventParamsTypes[evtg]
+	if !exists || params == nil {
+		return nil, nil
+	}
+	v := reflect.NewAt(t, params).Elem()
+	fields := make(map[string]interface{}, t.NumField())
+	var unrecorded []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		switch {
+		case f.PkgPath != "", bufferSize(t, f):
+		case f.Type == helementType:
+			fields[f.Name] = elementPath(*(*HELEMENT)(unsafe.Pointer(v.Field(i).UnsafeAddr())))
+		case f.Type == wideCharsType:
+			fields[f.Name] = recordWideChars(*(**uint16)(unsafe.Pointer(v.Field(i).UnsafeAddr())))
+		case f.Type == jsonValueType:
+			// The value may point at data that is gone by the time of replay
+			if v.Field(i).Interface().(JsonValue).T != T_UNDEFINED {
+				unrecorded = append(unrecorded, f.Name)
+			}
+		case recordable(f.Type):
+			fields[f.Name] = v.Field(i).Interface()
+		}
+	}
+	return fields, unrecorded
+}
+
+// Returns the Cmd field of the params decoded for reading, or empty if the
+// group has no commands
+func recordCmd(evtg uint32, params unsafe.Pointer) string {
+	event := &Event{Group: evtg, Params: params}
+	if params == nil {
+		return ""
+	}
+	cmd, ok := event.Cmd()
+	if !ok {
+		return ""
+	}
+	switch evtg {
+	case HANDLE_MOUSE:
+		return MouseEvent(cmd).String()
+	case HANDLE_KEY:
+		return KeyEvent(cmd).String()
+	case HANDLE_FOCUS:
+		return FocusEvent(cmd).String()
+	case HANDLE_DRAW:
+		return DrawEvent(cmd).String()
+	case HANDLE_BEHAVIOR_EVENT:
+		return BehaviorEvent(cmd).String()
+	case HANDLE_SCROLL:
+		return ScrollEvent(cmd).String()
+	case HANDLE_EXCHANGE:
+		return ExchangeEvent(cmd).String()
+	case HANDLE_GESTURE:
+		return GestureCmd(cmd).String()
+	}
+	return ""
+}
+
+// Dispatches the event to the handler, recording it if a recorder is installed
+func (handler *EventHandler) handleEventRecorded(he HELEMENT, evtg uint32, params unsafe.Pointer) bool {
+	r := currentRecorder()
+	if r == nil {
+		return handler.handleEventSafely(he, evtg, params)
+	}
+	event := &RecordedEvent{
+		Group:   EventGroups(evtg).String(),
+		Cmd:     recordCmd(evtg, params),
+		Element: describeHandle(he),
+		Path:    elementPath(he),
+	}
+	event.Params, event.Unrecorded = recordParams(evtg, params)
+	event.Handled = handler.handleEventSafely(he, evtg, params)
+	r.write(event)
+	return event.Handled
+}
+
+// Decodes the params of a notification into the element it concerns and the
+// fields worth recording.  Data buffers, procs and tags are left out.
+func notificationParams(phdr *NMHDR) (HELEMENT, map[string]interface{}) {
+	switch phdr.Code {
+	case HLN_CREATE_CONTROL, HLN_CONTROL_CREATED:
+		params := (*NmhlCreateControl)(unsafe.Pointer(phdr))
+		return params.Element, map[string]interface{}{
+			"InHwndParent":   params.InHwndParent,
+			"OutHwndControl": params.OutHwndControl,
+		}
+	case HLN_DESTROY_CONTROL:
+		params := (*NmhlDestroyControl)(unsafe.Pointer(phdr))
+		return params.Element, map[string]interface{}{
+			"InOutHwndControl": params.InOutHwndControl,
+		}
+	case HLN_LOAD_DATA:
+		params := (*NmhlLoadData)(unsafe.Pointer(phdr))
+		return params.Principal, map[string]interface{}{
+			"Uri":         recordWideChars(params.Uri),
+			"OutDataSize": params.OutDataSize,
+			"DataType":    params.DataType,
+			"Principal":   elementPath(params.Principal),
+			"Initiator":   elementPath(params.Initiator),
+		}
+	case HLN_DATA_LOADED:
+		params := (*NmhlDataLoaded)(unsafe.Pointer(phdr))
+		return BAD_HELEMENT, map[string]interface{}{
+			"Uri":      recordWideChars(params.Uri),
+			"DataSize": params.DataSize,
+			"DataType": params.DataType,
+			"Status":   params.Status,
+		}
+	case HLN_ATTACH_BEHAVIOR:
+		params := (*NmhlAttachBehavior)(unsafe.Pointer(phdr))
+		return params.Element, map[string]interface{}{
+			"BehaviorName":  cStringToString(params.BehaviorName),
+			"ElementEvents": params.ElementEvents,
+		}
+	}
+	return BAD_HELEMENT, nil
+}
+
+func recordNotification(hwnd HWND, phdr *NMHDR, result uintptr) {
+	if r := currentRecorder(); r != nil {
+		he, params := notificationParams(phdr)
+		r.write(&RecordedEvent{
+			Notification: NotifyCode(phdr.Code).String(),
+			Hwnd:         hwnd,
+			Element:      describeHandle(he),
+			Path:         elementPath(he),
+			Params:       params,
+			Handled:      result != 0,
+		})
+	}
+}
+
+// Reads back the events written by a Recorder
+func ReadRecording(r io.Reader) ([]*RecordedEvent, error) {
+	events := make([]*RecordedEvent, 0, 64)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		event := &RecordedEvent{}
+		if err := json.Unmarshal(scanner.Bytes(), event); err != nil {
+			return nil, fmt.Errorf("recording line %d: %s", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+// Rebuilds the params struct for a recorded event
+func replayParams(root HELEMENT, evtg uint32, fields map[string]interface{}) (unsafe.Pointer, error) {
+	t, exists := eventParamsTypes[evtg]
+	if !exists {
+		return nil, nil
+	}
+	v := reflect.New(t)
+	for name, value := range fields {
+		f := v.Elem().FieldByName(name)
+		if !f.IsValid() || !f.CanSet() {
+			return nil, fmt.Errorf("unknown field %s in recorded %s params", name, EventGroups(evtg))
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		if f.Type() == helementType {
+			var path []uint
+			if err := json.Unmarshal(data, &path); err != nil {
+				return nil, err
+			}
+			he, err := resolvePath(root, path)
+			if err != nil {
+				return nil, err
+			}
+			*(*HELEMENT)(unsafe.Pointer(f.UnsafeAddr())) = he
+		} else if f.Type() == wideCharsType {
+			var chars *string
+			if err := json.Unmarshal(data, &chars); err != nil {
+				return nil, fmt.Errorf("recorded %s params field %s: %s", EventGroups(evtg), name, err)
+			}
+			if chars != nil {
+				*(**uint16)(unsafe.Pointer(f.UnsafeAddr())) = stringToUtf16Ptr(*chars)
+			}
+		} else if err := json.Unmarshal(data, f.Addr().Interface()); err != nil {
+			return nil, fmt.Errorf("recorded %s params field %s: %s", EventGroups(evtg), name, err)
+		}
+	}
+	return unsafe.Pointer(v.Pointer()), nil
+}
+
+func parseEventGroup(name string) (uint32, bool) {
+	for _, n := range eventGroupNames {
+		if n.name == name {
+			return n.value, true
+		}
+	}
+	return 0, false
+}
+
+// The outcome of Replay
+type ReplayResult struct {
+	// Number of events dispatched
+	Replayed int
+
+	// The recorded events whose handled result came out differently
+	Mismatched []*RecordedEvent
+}
+
+// Feeds a recording back into the handlers, as though they were attached to
+// the recorded elements: each event is dispatched to the handlers in turn until
+// one of them handles it.  Elements are looked up by path under root, so root
+// should hold the same document the recording was made with, e.g. loaded into a
+// MemoryBackend.  Notifications, and the attach and detach events, are skipped.
+// Replay fails on an event whose element can't be found under root, or whose
+// params could not be recorded in full, which includes every draw, method call,
+// data arrived and exchange event.  Recording is suspended while it runs.
+func Replay(r io.Reader, root *Element, handlers ...*EventHandler) (*ReplayResult, error) {
+	events, err := ReadRecording(r)
+	if err != nil {
+		return nil, err
+	}
+	if err := root.checkReleased(); err != nil {
+		return nil, err
+	}
+	defer suspendRecording()()
+	result := &ReplayResult{}
+	for _, event := range events {
+		if event.Group == "" {
+			continue
+		}
+		evtg, ok := parseEventGroup(event.Group)
+		if !ok {
+			return result, fmt.Errorf("unknown event group %s in recorded event %d", event.Group, event.Seq)
+		}
+		if evtg == HANDLE_INITIALIZATION {
+			continue
+		}
+		if unreplayableGroups[evtg] {
+			return result, fmt.Errorf("recorded event %d is a %s event, whose params can't be replayed", event.Seq, event.Group)
+		}
+		if len(event.Unrecorded) > 0 {
+			return result, fmt.Errorf("recorded event %d is missing params %v", event.Seq, event.Unrecorded)
+		}
+		if event.Path == nil {
+			return result, fmt.Errorf("recorded event %d has no element", event.Seq)
+		}
+		he, err := resolvePath(root.handle, event.Path)
+		if err != nil {
+			return result, fmt.Errorf("recorded event %d: %s", event.Seq, err)
+		}
+		params, err := replayParams(root.handle, evtg, event.Params)
+		if err != nil {
+			return result, err
+		}
+		handled := false
+		for _, handler := range handlers {
+			if handled = handler.handleEventRecorded(he, evtg, params); handled {
+				break
+			}
+		}
+		result.Replayed++
+		if handled != event.Handled {
+			result.Mismatched = append(result.Mismatched, event)
+		}
+	}
+	return result, nil
+}
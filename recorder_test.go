@@ -0,0 +1,280 @@
+package gohl
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	var recording bytes.Buffer
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		recorder := NewRecorder(&recording)
+		SetRecorder(recorder)
+		defer SetRecorder(nil)
+
+		root := RootElement(hwnd)
+		b := root.Select("#b")[0]
+		root.AttachHandler(&EventHandler{
+			OnMouse: func(he HELEMENT, params *MouseParams) bool {
+				return params.Event() == MOUSE_DOWN && params.Phase() == Phase(BUBBLING)
+			},
+			OnBehaviorEvent: func(he HELEMENT, params *BehaviorEventParams) bool {
+				return false
+			},
+		})
+		params := &MouseParams{Cmd: MOUSE_DOWN, Target: b.Handle(), Pos: Point{3, 4}, ButtonState: MAIN_MOUSE_BUTTON}
+		mem.FireEvent(b.Handle(), HANDLE_MOUSE, unsafe.Pointer(params))
+		b.SendEvent(BUTTON_CLICK, b, 7)
+		if err := recorder.Err(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	events, err := ReadRecording(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Attach, then each event in the sinking and bubbling phase at the root
+	if len(events) != 5 {
+		t.Fatal("Expected 5 recorded events, got ", len(events), "\n", recording.String())
+	}
+	if e := events[0]; e.Group != "HANDLE_INITIALIZATION" || e.Path == nil || len(e.Path) != 0 {
+		t.Fatalf("Unexpected first event: %+v", e)
+	}
+	if e := events[1]; e.Group != "HANDLE_MOUSE" || e.Cmd != "MOUSE_DOWN|SINKING" || e.Handled {
+		t.Fatalf("Unexpected sinking mouse event: %+v", e)
+	}
+	if e := events[2]; e.Cmd != "MOUSE_DOWN" || !e.Handled || e.Element != "html" {
+		t.Fatalf("Unexpected bubbling mouse event: %+v", e)
+	}
+	if e := events[4]; e.Group != "HANDLE_BEHAVIOR_EVENT" || e.Cmd != "BUTTON_CLICK" || e.Seq != 5 {
+		t.Fatalf("Unexpected behavior event: %+v", e)
+	}
+	if _, exists := events[1].Params["Dragging"]; !exists {
+		t.Fatal("Handle fields should be recorded: ", events[1].Params)
+	}
+
+	// Replay into a handler that was never attached, against a fresh document
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		b := root.Select("#b")[0]
+		var mouse []MouseParams
+		var reasons []uint32
+		handler := &EventHandler{
+			OnMouse: func(he HELEMENT, params *MouseParams) bool {
+				if he != root.Handle() {
+					t.Fatal("Replayed event should be delivered to the recorded element")
+				}
+				mouse = append(mouse, *params)
+				return params.Phase() == Phase(BUBBLING)
+			},
+			OnBehaviorEvent: func(he HELEMENT, params *BehaviorEventParams) bool {
+				reasons = append(reasons, params.Reason)
+				if params.Target != b.Handle() || params.Source != b.Handle() {
+					t.Fatal("Element fields of the params should be resolved in the new document")
+				}
+				return true
+			},
+		}
+		result, err := Replay(bytes.NewReader(recording.Bytes()), root, handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Replayed != 4 {
+			t.Fatal("Expected everything but the attach event to be replayed, got ", result.Replayed)
+		}
+		if len(mouse) != 2 || mouse[1].Target != b.Handle() || mouse[1].Pos != (Point{3, 4}) || mouse[1].ButtonState != MAIN_MOUSE_BUTTON {
+			t.Fatalf("Mouse params were not replayed faithfully: %+v", mouse)
+		}
+		if len(reasons) != 2 || reasons[0] != 7 {
+			t.Fatal("Behavior event params were not replayed faithfully: ", reasons)
+		}
+		// The behavior events are now handled where they weren't before
+		if len(result.Mismatched) != 2 || result.Mismatched[0].Seq != 4 {
+			t.Fatalf("Expected the behavior events to be reported as mismatched: %+v", result.Mismatched)
+		}
+	})
+}
+
+func TestReadRecordingError(t *testing.T) {
+	_, err := ReadRecording(strings.NewReader("{\"seq\":1,\"group\":\"HANDLE_KEY\",\"path\":[]}\n\nnot json\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Fatal("Expected an error pointing at the bad line, got ", err)
+	}
+}
+
+func TestRecordNotification(t *testing.T) {
+	var recording bytes.Buffer
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		recorder := NewRecorder(&recording)
+		SetRecorder(recorder)
+		defer SetRecorder(nil)
+
+		b := RootElement(hwnd).Select("#b")[0]
+		name := []byte("counter\x00")
+		attach := &NmhlAttachBehavior{Header: NMHDR{Code: HLN_ATTACH_BEHAVIOR}, Element: b.Handle(), BehaviorName: &name[0]}
+		recordNotification(hwnd, &attach.Header, 1)
+		load := &NmhlLoadData{Header: NMHDR{Code: HLN_LOAD_DATA}, Uri: stringToUtf16Ptr("res:logo.png"), Principal: b.Handle()}
+		recordNotification(hwnd, &load.Header, 0)
+		loaded := &NmhlDataLoaded{Header: NMHDR{Code: HLN_DATA_LOADED}, Status: 404}
+		recordNotification(hwnd, &loaded.Header, 0)
+	})
+
+	events, err := ReadRecording(bytes.NewReader(recording.Bytes()))
+	if err != nil || len(events) != 3 {
+		t.Fatal("Expected 3 recorded notifications, got ", len(events), err)
+	}
+	if e := events[0]; e.Notification != "HLN_ATTACH_BEHAVIOR" || !e.Handled || len(e.Path) != 2 || e.Params["BehaviorName"] != "counter" {
+		t.Fatalf("Unexpected attach behavior notification: %+v", e)
+	}
+	if e := events[1]; e.Notification != "HLN_LOAD_DATA" || e.Params["Uri"] != "res:logo.png" || len(e.Path) != 2 {
+		t.Fatalf("Unexpected load data notification: %+v", e)
+	}
+	if e := events[2]; e.Params["Uri"] != nil || e.Params["Status"] != float64(404) {
+		t.Fatalf("Unexpected data loaded notification: %+v", e)
+	}
+}
+
+func TestRecordRefusesJsonValueData(t *testing.T) {
+	var recording bytes.Buffer
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		recorder := NewRecorder(&recording)
+		SetRecorder(recorder)
+		defer SetRecorder(nil)
+
+		a := RootElement(hwnd).Child(0)
+		a.AttachHandler(&EventHandler{
+			OnBehaviorEvent: func(he HELEMENT, params *BehaviorEventParams) bool { return false },
+		})
+		params := &BehaviorEventParams{Cmd: BUTTON_CLICK, Target: a.Handle(), Data: JsonValue{T: T_INT, D: 5}}
+		mem.FireEvent(a.Handle(), HANDLE_BEHAVIOR_EVENT, unsafe.Pointer(params))
+	})
+
+	events, err := ReadRecording(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := events[len(events)-1]
+	if len(last.Unrecorded) != 1 || last.Unrecorded[0] != "Data" {
+		t.Fatalf("Expected the event data to be marked as unrecorded: %+v", last)
+	}
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		_, err := Replay(bytes.NewReader(recording.Bytes()), RootElement(hwnd), &EventHandler{})
+		if err == nil || !strings.Contains(err.Error(), "Data") {
+			t.Fatal("Expected replay to refuse an event with unrecorded data, got ", err)
+		}
+	})
+}
+
+func TestReplaySuspendsRecording(t *testing.T) {
+	recording := "{\"seq\":1,\"group\":\"HANDLE_BEHAVIOR_EVENT\",\"path\":[0],\"params\":{\"Cmd\":0},\"handled\":false}\n"
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		var rerecorded bytes.Buffer
+		SetRecorder(NewRecorder(&rerecorded))
+		defer SetRecorder(nil)
+
+		root := RootElement(hwnd)
+		handler := &EventHandler{
+			OnBehaviorEvent: func(he HELEMENT, params *BehaviorEventParams) bool {
+				// Events raised by the replayed handlers aren't recorded either
+				if params.Cmd != BUTTON_CLICK {
+					return false
+				}
+				a := NewElementFromHandle(he)
+				defer a.Release()
+				a.SendEvent(BUTTON_PRESS, a, 0)
+				return false
+			},
+		}
+		root.Child(0).AttachHandler(handler)
+		rerecorded.Reset()
+
+		result, err := Replay(strings.NewReader(recording), root, handler)
+		if err != nil || result.Replayed != 1 {
+			t.Fatal("Unexpected replay result: ", result, err)
+		}
+		if rerecorded.Len() != 0 {
+			t.Fatal("Nothing should be recorded during replay, got: ", rerecorded.String())
+		}
+		if currentRecorder() == nil {
+			t.Fatal("Recording should resume after replay")
+		}
+	})
+}
+
+func TestReplayUnresolvedPath(t *testing.T) {
+	tests := []string{
+		"{\"seq\":1,\"group\":\"HANDLE_MOUSE\",\"path\":[3,0],\"handled\":false}\n",
+		"{\"seq\":1,\"group\":\"HANDLE_MOUSE\",\"handled\":false}\n",
+		"{\"seq\":1,\"group\":\"HANDLE_MOUSE\",\"path\":[],\"params\":{\"Target\":[0,4]},\"handled\":false}\n",
+	}
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		for _, recording := range tests {
+			result, err := Replay(strings.NewReader(recording), RootElement(hwnd), &EventHandler{})
+			if err == nil || result.Replayed != 0 {
+				t.Error("Expected replay to fail on an element that can't be found: ", recording)
+			}
+		}
+	})
+}
+
+func TestRecordLeavesOutPointers(t *testing.T) {
+	var recording bytes.Buffer
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		SetRecorder(NewRecorder(&recording))
+		defer SetRecorder(nil)
+
+		a := RootElement(hwnd).Child(0)
+		a.AttachHandler(&EventHandler{
+			OnDraw:        func(he HELEMENT, params *DrawParams) bool { return false },
+			OnDataArrived: func(he HELEMENT, params *DataArrivedParams) bool { return false },
+			OnExchange:    func(he HELEMENT, params *ExchangeParams) bool { return false },
+		})
+		data := []byte("data")
+		mem.FireEvent(a.Handle(), HANDLE_DRAW, unsafe.Pointer(&DrawParams{Cmd: DRAW_CONTENT, Hdc: 0x1234}))
+		mem.FireEvent(a.Handle(), HANDLE_DATA_ARRIVED, unsafe.Pointer(&DataArrivedParams{Data: &data[0], DataSize: uint32(len(data))}))
+		mem.FireEvent(a.Handle(), HANDLE_EXCHANGE, unsafe.Pointer(&ExchangeParams{Cmd: X_DRAG, Target: a.Handle(), FetchData: 0x5678}))
+	})
+
+	events, err := ReadRecording(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	left := map[string]string{
+		"HANDLE_DRAW":         "Hdc",
+		"HANDLE_DATA_ARRIVED": "DataSize",
+		"HANDLE_EXCHANGE":     "FetchData",
+	}
+	for _, event := range events {
+		if field, exists := left[event.Group]; exists {
+			if _, recorded := event.Params[field]; recorded {
+				t.Errorf("%s should not be recorded for %s: %+v", field, event.Group, event.Params)
+			}
+			delete(left, event.Group)
+		}
+	}
+	if len(left) != 0 {
+		t.Fatal("Expected the events to be recorded, missing: ", left)
+	}
+
+	// None of them can be replayed faithfully
+	for _, line := range strings.SplitAfter(recording.String(), "\n") {
+		if !strings.Contains(line, "HANDLE_DRAW") && !strings.Contains(line, "HANDLE_DATA_ARRIVED") && !strings.Contains(line, "HANDLE_EXCHANGE") {
+			continue
+		}
+		testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+			result, err := Replay(strings.NewReader(line), RootElement(hwnd), &EventHandler{})
+			if err == nil || result.Replayed != 0 {
+				t.Error("Expected replay to refuse: ", line)
+			}
+		})
+	}
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		recorded := "{\"seq\":1,\"group\":\"HANDLE_METHOD_CALL\",\"path\":[0],\"params\":{\"MethodId\":12},\"handled\":true}\n"
+		if _, err := Replay(strings.NewReader(recorded), RootElement(hwnd), &EventHandler{}); err == nil {
+			t.Error("Expected replay to refuse a method call")
+		}
+	})
+}
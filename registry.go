@@ -0,0 +1,290 @@
+package gohl
+
+import (
+	"sync"
+)
+
+// Sent to a window after it has been removed from the screen, see ProcNoDefault
+const wmDestroy = 0x0002
+
+/*
+Registry
+
+Holds a reference to the handlers in use by one window, so that they don't get
+garbage collected while htmlayout holds pointers to them.  Handlers attached to
+elements that do not yet belong to a window are kept in the registry for
+hwnd 0.  Every registry has its own mutex; registriesMutex only guards the
+map of registries.
+*/
+type windowRegistry struct {
+	hwnd  HWND
+	mutex sync.Mutex
+
+	notifyHandler   *NotifyHandler
+	eventHandler    *EventHandler
+	elementHandlers map[HELEMENT]map[*EventHandler]bool
+	behaviors       map[*EventHandler]int
+
+	// Registered with RegisterWindowBehavior
+	behaviorFactories map[string]BehaviorArgsFactory
+}
+
+var (
+	registriesMutex sync.Mutex
+	registries      = make(map[HWND]*windowRegistry, 4)
+)
+
+/*
+Stats
+
+Counts of the handlers being kept alive, for a single window or for all of them.
+Useful for tracking down handlers that are never detached.
+*/
+type Stats struct {
+	Windows             int // Windows that have a registry
+	NotifyHandlers      int
+	WindowEventHandlers int
+	Elements            int // Elements with at least one handler attached
+	ElementHandlers     int // Handlers attached to elements, summed over the elements
+	Behaviors           int // Distinct behaviors currently attached
+	PendingReleases     int // Handles waiting for DrainReleaseQueue
+	PendingPayloads     int // Payloads of application events not yet released
+}
+
+// Returns the registry for the window, creating it if create is true
+func registryFor(hwnd HWND, create bool) *windowRegistry {
+	registriesMutex.Lock()
+	defer registriesMutex.Unlock()
+	r, exists := registries[hwnd]
+	if !exists && create {
+		r = &windowRegistry{
+			hwnd:            hwnd,
+			elementHandlers: make(map[HELEMENT]map[*EventHandler]bool, 32),
+			behaviors:       make(map[*EventHandler]int, 8),
+
+			behaviorFactories: make(map[string]BehaviorArgsFactory, 4),
+		}
+		registries[hwnd] = r
+	}
+	return r
+}
+
+// A snapshot of all the registries, so that they can be visited without
+// holding registriesMutex
+func allRegistries() []*windowRegistry {
+	registriesMutex.Lock()
+	defer registriesMutex.Unlock()
+	all := make([]*windowRegistry, 0, len(registries))
+	for _, r := range registries {
+		all = append(all, r)
+	}
+	return all
+}
+
+// Returns the registry for the window the element belongs to, or the one for
+// hwnd 0 if it doesn't belong to a window
+func elementRegistry(he HELEMENT, create bool) *windowRegistry {
+	hwnd, ret := dom.ElementHwnd(he, true)
+	if ret != HLDOM_OK {
+		hwnd = 0
+	}
+	return registryFor(hwnd, create)
+}
+
+// Drops every reference held for the window.  Called once the window has been
+// destroyed, by which point htmlayout has already detached the handlers.
+func unregisterWindow(hwnd HWND) {
+	if hwnd == 0 {
+		return
+	}
+	registriesMutex.Lock()
+	r, exists := registries[hwnd]
+	delete(registries, hwnd)
+	registriesMutex.Unlock()
+	dropWindowPayloads(hwnd)
+
+	if exists {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+		for he, attachedHandlers := range r.elementHandlers {
+			for handler := range attachedHandlers {
+				untrackHandler(he, handler)
+			}
+		}
+	}
+}
+
+// Window handlers
+
+func (r *windowRegistry) setNotifyHandler(handler *NotifyHandler) {
+	r.mutex.Lock()
+	r.notifyHandler = handler
+	r.mutex.Unlock()
+}
+
+func (r *windowRegistry) getNotifyHandler() *NotifyHandler {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.notifyHandler
+}
+
+func (r *windowRegistry) setEventHandler(handler *EventHandler) {
+	r.mutex.Lock()
+	r.eventHandler = handler
+	r.mutex.Unlock()
+}
+
+func (r *windowRegistry) getEventHandler() *EventHandler {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.eventHandler
+}
+
+// Element handlers
+
+func (r *windowRegistry) hasElementHandler(he HELEMENT, handler *EventHandler) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.elementHandlers[he][handler]
+}
+
+func (r *windowRegistry) addElementHandler(he HELEMENT, handler *EventHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	attachedHandlers, exists := r.elementHandlers[he]
+	if !exists {
+		attachedHandlers = make(map[*EventHandler]bool, 8)
+		r.elementHandlers[he] = attachedHandlers
+	}
+	attachedHandlers[handler] = true
+}
+
+func (r *windowRegistry) removeElementHandler(he HELEMENT, handler *EventHandler) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if attachedHandlers, exists := r.elementHandlers[he]; exists {
+		delete(attachedHandlers, handler)
+		if len(attachedHandlers) == 0 {
+			delete(r.elementHandlers, he)
+		}
+	}
+}
+
+// Stops tracking the element, returning the handlers that were attached to it
+func (r *windowRegistry) removeElement(he HELEMENT) []*EventHandler {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	attachedHandlers := r.elementHandlers[he]
+	delete(r.elementHandlers, he)
+	handlers := make([]*EventHandler, 0, len(attachedHandlers))
+	for handler := range attachedHandlers {
+		handlers = append(handlers, handler)
+	}
+	return handlers
+}
+
+// Returns the registry that tracks the handler on the element, or nil.  An
+// element that was moved to another window since the handler was attached
+// is still tracked by the registry it was attached under.
+func findElementHandler(he HELEMENT, handler *EventHandler) *windowRegistry {
+	if r := elementRegistry(he, false); r != nil && r.hasElementHandler(he, handler) {
+		return r
+	}
+	for _, r := range allRegistries() {
+		if r.hasElementHandler(he, handler) {
+			return r
+		}
+	}
+	return nil
+}
+
+// Detaches and forgets every handler attached to the element, in any window
+func detachElementHandlers(b Backend, he HELEMENT) {
+	for _, r := range allRegistries() {
+		for _, handler := range r.removeElement(he) {
+			b.DetachEventHandler(he, handler)
+			untrackHandler(he, handler)
+		}
+	}
+}
+
+// Behaviors
+
+func (r *windowRegistry) addBehavior(handler *EventHandler) {
+	r.mutex.Lock()
+	r.behaviors[handler]++
+	r.mutex.Unlock()
+}
+
+// Decrements the reference count for the behavior and stops tracking it once
+// the count is exhausted.  Returns false if the behavior is not tracked here.
+func (r *windowRegistry) releaseBehavior(handler *EventHandler) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	refCount, exists := r.behaviors[handler]
+	if !exists {
+		return false
+	}
+	if refCount <= 1 {
+		delete(r.behaviors, handler)
+	} else {
+		r.behaviors[handler] = refCount - 1
+	}
+	return true
+}
+
+// Called when a handler is detached from the element.  If it was attached as a
+// behavior, one reference to it is dropped.
+func forgetBehavior(he HELEMENT, handler *EventHandler) {
+	if r := elementRegistry(he, false); r != nil && r.releaseBehavior(handler) {
+		return
+	}
+	for _, r := range allRegistries() {
+		if r.releaseBehavior(handler) {
+			return
+		}
+	}
+}
+
+// Stats
+
+func (r *windowRegistry) addStats(s *Stats) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.hwnd != 0 {
+		s.Windows++
+	}
+	if r.notifyHandler != nil {
+		s.NotifyHandlers++
+	}
+	if r.eventHandler != nil {
+		s.WindowEventHandlers++
+	}
+	s.Elements += len(r.elementHandlers)
+	for _, attachedHandlers := range r.elementHandlers {
+		s.ElementHandlers += len(attachedHandlers)
+	}
+	s.Behaviors += len(r.behaviors)
+}
+
+// Returns the handler counts for a single window.  Pass 0 for the handlers
+// attached to elements that do not belong to a window.
+func WindowStats(hwnd HWND) Stats {
+	var s Stats
+	if r := registryFor(hwnd, false); r != nil {
+		r.addStats(&s)
+	}
+	return s
+}
+
+// Returns the handler counts summed over all windows, along with the number
+// of handles and application event payloads waiting to be released
+func GlobalStats() Stats {
+	var s Stats
+	for _, r := range allRegistries() {
+		r.addStats(&s)
+	}
+	s.PendingReleases = PendingReleases()
+	s.PendingPayloads = PendingPayloads()
+	return s
+}
@@ -0,0 +1,117 @@
+package gohl
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRegistryPerWindow(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		const otherHwnd = 2
+		if err := mem.LoadHtml(otherHwnd, pages["one-div"]); err != nil {
+			t.Fatal(err)
+		}
+		defer mem.DestroyWindow(otherHwnd)
+
+		handler := &EventHandler{}
+		root := RootElement(hwnd)
+		root.Child(0).AttachHandler(handler)
+		root.Child(1).AttachHandler(handler)
+		root.Child(1).AttachHandler(&EventHandler{})
+
+		detached := false
+		other := RootElement(otherHwnd).Child(0)
+		other.AttachHandler(&EventHandler{
+			OnDetached: func(he HELEMENT) { detached = true },
+		})
+
+		if s := WindowStats(hwnd); s.Windows != 1 || s.Elements != 2 || s.ElementHandlers != 3 {
+			t.Fatalf("Unexpected stats for the first window: %+v", s)
+		}
+		if s := WindowStats(otherHwnd); s.Elements != 1 || s.ElementHandlers != 1 {
+			t.Fatalf("Unexpected stats for the second window: %+v", s)
+		}
+		global := GlobalStats()
+		if global.Windows < 2 || global.ElementHandlers < 4 {
+			t.Fatalf("Global stats should include both windows: %+v", global)
+		}
+
+		mem.DestroyWindow(otherHwnd)
+		if !detached {
+			t.Fatal("Handler should have been detached when the window was destroyed")
+		}
+		if s := WindowStats(otherHwnd); s != (Stats{}) {
+			t.Fatalf("Destroyed window should have no handlers left: %+v", s)
+		}
+		if s := WindowStats(hwnd); s.ElementHandlers != 3 {
+			t.Fatal("Destroying one window should not affect another")
+		}
+	})
+}
+
+func TestRegistryUnattachedElement(t *testing.T) {
+	testWithMemoryHtml(pages["empty"], func(mem *MemoryBackend, hwnd HWND) {
+		before := WindowStats(0)
+		d := NewElement("div")
+		handler := &EventHandler{}
+		d.AttachHandler(handler)
+		if s := WindowStats(0); s.ElementHandlers != before.ElementHandlers+1 {
+			t.Fatal("Handler on an element without a window should be kept under hwnd 0")
+		}
+
+		// Still found after the element is moved into a window
+		RootElement(hwnd).AppendChild(d)
+		d.DetachHandler(handler)
+		if s := WindowStats(0); s.ElementHandlers != before.ElementHandlers {
+			t.Fatal("Handler was not unregistered")
+		}
+	})
+}
+
+func TestRegistryBehaviorRefCount(t *testing.T) {
+	r := &windowRegistry{
+		elementHandlers: make(map[HELEMENT]map[*EventHandler]bool),
+		behaviors:       make(map[*EventHandler]int),
+	}
+	behavior := &EventHandler{}
+	r.addBehavior(behavior)
+	r.addBehavior(behavior)
+	if !r.releaseBehavior(behavior) || len(r.behaviors) != 1 {
+		t.Fatal("Behavior should still be referenced once")
+	}
+	if !r.releaseBehavior(behavior) || len(r.behaviors) != 0 {
+		t.Fatal("Behavior should no longer be tracked")
+	}
+	if r.releaseBehavior(behavior) {
+		t.Fatal("Releasing an untracked behavior should report false")
+	}
+}
+
+func TestRegistryConcurrentAccess(t *testing.T) {
+	r := &windowRegistry{
+		elementHandlers: make(map[HELEMENT]map[*EventHandler]bool),
+		behaviors:       make(map[*EventHandler]int),
+	}
+	mem := NewMemoryBackend()
+	nodes := make([]memNode, 8)
+	var wg sync.WaitGroup
+	for i := range nodes {
+		wg.Add(1)
+		go func(he HELEMENT) {
+			defer wg.Done()
+			handler := &EventHandler{}
+			for j := 0; j < 100; j++ {
+				r.addElementHandler(he, handler)
+				r.addBehavior(handler)
+				r.removeElementHandler(he, handler)
+				r.releaseBehavior(handler)
+			}
+		}(mem.handle(&nodes[i]))
+	}
+	wg.Wait()
+	var s Stats
+	r.addStats(&s)
+	if s.Elements != 0 || s.Behaviors != 0 {
+		t.Fatalf("Expected an empty registry: %+v", s)
+	}
+}
@@ -0,0 +1,85 @@
+package gohl
+
+import (
+	"sync"
+)
+
+// A handle whose Element was collected, along with the backend it came from
+type pendingRelease struct {
+	backend Backend
+	handle  HELEMENT
+}
+
+var (
+	releaseMutex sync.Mutex
+	releaseQueue []pendingRelease
+	releaseCount uint64
+)
+
+// Finalizer for Elements.  The finalizer runs on the Go runtime's own goroutine,
+// where it is not safe to call into htmlayout or to touch the handler maps, so
+// the handle is only queued here and released later by DrainReleaseQueue.
+func (e *Element) enqueueRelease() {
+	untrackElement(e)
+	if e.handle == BAD_HELEMENT {
+		return
+	}
+	releaseMutex.Lock()
+	releaseQueue = append(releaseQueue, pendingRelease{e.backend, e.handle})
+	releaseMutex.Unlock()
+	e.handle = BAD_HELEMENT
+}
+
+// Detaches the handlers of, and releases, the handles of collected Elements.
+// The payloads of posted application events that have been delivered are
+// dropped as well.  Must be called on the thread that owns the dom.
+// ProcNoDefault and Dispatcher.Drain call this, so a program that pumps its
+// windows through either does not need to.  A failure to release a handle is
+// passed to the panic handler, as a panic in a callback is, and does not stop
+// the rest of the queue.  Returns the number of handles released.
+func DrainReleaseQueue() int {
+	releaseDeliveredPayloads()
+
+	releaseMutex.Lock()
+	queue := releaseQueue
+	releaseQueue = nil
+	releaseMutex.Unlock()
+
+	for _, r := range queue {
+		r.release()
+	}
+
+	releaseMutex.Lock()
+	releaseCount += uint64(len(queue))
+	releaseMutex.Unlock()
+	return len(queue)
+}
+
+func (r pendingRelease) release() {
+	defer recoverCallbackPanic(r.handle, 0, 0)
+	releaseHandle(r.backend, r.handle)
+}
+
+// Returns the number of handles waiting for DrainReleaseQueue
+func PendingReleases() int {
+	releaseMutex.Lock()
+	defer releaseMutex.Unlock()
+	return len(releaseQueue)
+}
+
+// Returns the number of handles released by DrainReleaseQueue so far
+func DrainedReleases() uint64 {
+	releaseMutex.Lock()
+	defer releaseMutex.Unlock()
+	return releaseCount
+}
+
+// Detaches any handlers attached to the handle and drops our reference to it
+func releaseHandle(b Backend, handle HELEMENT) {
+	detachElementHandlers(b, handle)
+	if handle != BAD_HELEMENT {
+		if dr := b.UnuseElement(handle); dr != HLDOM_OK {
+			domPanic(dr, "UnuseElement")
+		}
+	}
+}
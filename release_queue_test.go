@@ -0,0 +1,148 @@
+package gohl
+
+import (
+	"runtime"
+	"sync/atomic"
+	"testing"
+)
+
+func refCount(h HELEMENT) int32 {
+	return atomic.LoadInt32(&backend.(*MemoryBackend).nodeOf(h).refCount)
+}
+
+func TestFinalizerQueuesRelease(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		DrainReleaseQueue()
+		d := RootElement(hwnd).Child(0)
+		h := d.Handle()
+		before := refCount(h)
+
+		detached := false
+		handler := &EventHandler{
+			OnDetached: func(he HELEMENT) { detached = true },
+		}
+		d.AttachHandler(handler)
+
+		// Run the finalizer by hand, as the runtime would
+		d.enqueueRelease()
+		if PendingReleases() != 1 {
+			t.Fatal("Expected one pending release, got: ", PendingReleases())
+		}
+		if refCount(h) != before || detached {
+			t.Fatal("Finalizer should not touch the dom")
+		}
+
+		drained := DrainedReleases()
+		if n := DrainReleaseQueue(); n != 1 {
+			t.Fatal("Expected one handle to be released, got: ", n)
+		}
+		if refCount(h) != before-1 {
+			t.Fatal("Handle was not released")
+		}
+		if !detached {
+			t.Fatal("Handler was not detached")
+		}
+		if findElementHandler(h, handler) != nil {
+			t.Fatal("Handler should have been unregistered")
+		}
+		if PendingReleases() != 0 || DrainedReleases() != drained+1 {
+			t.Fatal("Unexpected release counters")
+		}
+	})
+}
+
+func TestCollectedElementReleasedByPump(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		DrainReleaseQueue()
+		child := RootElement(hwnd).Child(0)
+		h := child.Handle()
+		before := refCount(h)
+		NewElementFromHandle(h)
+		if refCount(h) != before+1 {
+			t.Fatal("Expected the new element to hold a reference")
+		}
+
+		// Elements left over from other tests may be collected first
+		collected := false
+		for i := 0; i < 10 && !collected; i++ {
+			runtime.GC()
+			if PendingReleases() > 0 {
+				mem.ProcessPostedEvents()
+				if PendingReleases() != 0 {
+					t.Fatal("Pump should have drained the release queue")
+				}
+				collected = refCount(h) == before
+			}
+		}
+		if !collected {
+			t.Skip("Element was not collected")
+		}
+		runtime.KeepAlive(child)
+	})
+}
+
+func TestDispatcherDrainsReleases(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		DrainReleaseQueue()
+		d, _ := newFakePumpDispatcher()
+		RootElement(hwnd).Child(0).enqueueRelease()
+		d.Drain()
+		if PendingReleases() != 0 {
+			t.Fatal("Dispatcher should drain the release queue")
+		}
+	})
+}
+
+// Fails to drop references, as a backend would for a handle it has lost
+type failingUnuseBackend struct {
+	Backend
+}
+
+func (b failingUnuseBackend) UnuseElement(he HELEMENT) HLDOM_RESULT {
+	return HLDOM_OPERATION_FAILED
+}
+
+func TestDrainContainsReleaseFailures(t *testing.T) {
+	testWithMemoryHtml(pages["two-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		DrainReleaseQueue()
+		var reported []*CallbackPanic
+		SetPanicHandler(func(p *CallbackPanic) { reported = append(reported, p) })
+		defer SetPanicHandler(nil)
+
+		root := RootElement(hwnd)
+		a, b := root.Child(0), root.Child(1)
+		h := b.Handle()
+		before := refCount(h)
+		a.backend = failingUnuseBackend{mem}
+		a.enqueueRelease()
+		b.enqueueRelease()
+
+		if n := DrainReleaseQueue(); n != 2 {
+			t.Fatal("Expected both handles to be drained, got: ", n)
+		}
+		if len(reported) != 1 {
+			t.Fatal("Expected the failure to be reported, got: ", reported)
+		}
+		if err, ok := reported[0].Value.(*DomError); !ok || err.Result != HLDOM_OPERATION_FAILED {
+			t.Fatal("Unexpected report: ", reported[0].Value)
+		}
+		if refCount(h) != before-1 {
+			t.Fatal("A failure should not stop the rest of the queue")
+		}
+	})
+}
+
+func TestReleaseUsesElementBackend(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		h := d.Handle()
+		before := refCount(h)
+
+		SetBackend(failingUnuseBackend{mem})
+		d.Release()
+		SetBackend(mem)
+		if refCount(h) != before-1 {
+			t.Fatal("Expected the handle to be released through the backend it came from")
+		}
+	})
+}
@@ -0,0 +1,288 @@
+package gohl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// The modifier bits of KeyParams.AltState that take part in a chord
+const chordModifiers = CONTROL_KEY_PRESSED | SHIFT_KEY_PRESSED | ALT_KEY_PRESSED
+
+/*
+Chord
+
+A key combination such as Ctrl+Shift+S: a virtual key code along with the
+modifier keys held down, as CONTROL_KEY_PRESSED, SHIFT_KEY_PRESSED and
+ALT_KEY_PRESSED bits.
+*/
+type Chord struct {
+	Key       uint32
+	Modifiers uint32
+}
+
+var modifierNames = []codeName{
+	{CONTROL_KEY_PRESSED, "Ctrl"},
+	{ALT_KEY_PRESSED, "Alt"},
+	{SHIFT_KEY_PRESSED, "Shift"},
+}
+
+// Alternative spellings accepted when parsing
+var modifierAliases = map[string]uint32{
+	"ctrl":    CONTROL_KEY_PRESSED,
+	"control": CONTROL_KEY_PRESSED,
+	"alt":     ALT_KEY_PRESSED,
+	"shift":   SHIFT_KEY_PRESSED,
+}
+
+// Names of the virtual keys that can be used in a chord.  Where a key has more
+// than one name, the first is the one it is formatted with.
+var chordKeyNames = []codeName{
+	{VK_BACK, "Backspace"},
+	{VK_TAB, "Tab"},
+	{VK_RETURN, "Enter"},
+	{VK_RETURN, "Return"},
+	{VK_PAUSE, "Pause"},
+	{VK_ESCAPE, "Esc"},
+	{VK_ESCAPE, "Escape"},
+	{VK_SPACE, "Space"},
+	{VK_PRIOR, "PageUp"},
+	{VK_PRIOR, "PgUp"},
+	{VK_NEXT, "PageDown"},
+	{VK_NEXT, "PgDn"},
+	{VK_END, "End"},
+	{VK_HOME, "Home"},
+	{VK_LEFT, "Left"},
+	{VK_UP, "Up"},
+	{VK_RIGHT, "Right"},
+	{VK_DOWN, "Down"},
+	{VK_SNAPSHOT, "PrintScreen"},
+	{VK_INSERT, "Insert"},
+	{VK_INSERT, "Ins"},
+	{VK_DELETE, "Delete"},
+	{VK_DELETE, "Del"},
+	{VK_MULTIPLY, "Multiply"},
+	{VK_ADD, "Add"},
+	{VK_SUBTRACT, "Subtract"},
+	{VK_DECIMAL, "Decimal"},
+	{VK_DIVIDE, "Divide"},
+	{VK_OEM_1, ";"},
+	{VK_OEM_PLUS, "="},
+	{VK_OEM_PLUS, "Plus"},
+	{VK_OEM_COMMA, ","},
+	{VK_OEM_MINUS, "-"},
+	{VK_OEM_MINUS, "Minus"},
+	{VK_OEM_PERIOD, "."},
+	{VK_OEM_2, "/"},
+	{VK_OEM_3, "`"},
+	{VK_OEM_4, "["},
+	{VK_OEM_5, "\\"},
+	{VK_OEM_6, "]"},
+	{VK_OEM_7, "'"},
+}
+
+// Returns the virtual key code for a key name, ignoring case
+func parseChordKey(name string) (uint32, bool) {
+	upper := strings.ToUpper(name)
+	switch {
+	case len(upper) == 1 && (upper[0] >= 'A' && upper[0] <= 'Z' || upper[0] >= '0' && upper[0] <= '9'):
+		// Letters and digits are their own key codes
+		return uint32(upper[0]), true
+	case len(upper) > 1 && upper[0] == 'F':
+		var n uint32
+		if _, err := fmt.Sscanf(upper[1:], "%d", &n); err == nil && fmt.Sprint(n) == upper[1:] && n >= 1 && n <= 24 {
+			return VK_F1 + n - 1, true
+		}
+	case strings.HasPrefix(upper, "NUMPAD") && len(upper) == 7 && upper[6] >= '0' && upper[6] <= '9':
+		return VK_NUMPAD0 + uint32(upper[6]-'0'), true
+	}
+	for _, n := range chordKeyNames {
+		if strings.ToUpper(n.name) == upper {
+			return n.value, true
+		}
+	}
+	return 0, false
+}
+
+func formatChordKey(key uint32) string {
+	switch {
+	case key >= 'A' && key <= 'Z' || key >= '0' && key <= '9':
+		return string(rune(key))
+	case key >= VK_F1 && key <= VK_F24:
+		return fmt.Sprint("F", key-VK_F1+1)
+	case key >= VK_NUMPAD0 && key <= VK_NUMPAD9:
+		return fmt.Sprint("Numpad", key-VK_NUMPAD0)
+	}
+	for _, n := range chordKeyNames {
+		if n.value == key {
+			return n.name
+		}
+	}
+	return fmt.Sprintf("0x%02X", key)
+}
+
+// Parses a chord such as "Ctrl+Shift+S", "F5" or "Alt+Enter".  Names are not
+// case sensitive, and a trailing "+" stands for the plus key, as in "Ctrl++".
+func ParseChord(s string) (Chord, error) {
+	var chord Chord
+	parts := strings.Split(s, "+")
+	if strings.HasSuffix(s, "++") {
+		parts = append(parts[:len(parts)-2], "Plus")
+	}
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if i < len(parts)-1 {
+			modifier, ok := modifierAliases[strings.ToLower(part)]
+			if !ok {
+				return Chord{}, fmt.Errorf("invalid key chord %q: %q is not a modifier", s, part)
+			}
+			if chord.Modifiers&modifier != 0 {
+				return Chord{}, fmt.Errorf("invalid key chord %q: %s appears twice", s, part)
+			}
+			chord.Modifiers |= modifier
+			continue
+		}
+		if part == "" {
+			return Chord{}, fmt.Errorf("invalid key chord %q: no key", s)
+		}
+		if _, isModifier := modifierAliases[strings.ToLower(part)]; isModifier {
+			return Chord{}, fmt.Errorf("invalid key chord %q: no key after the modifiers", s)
+		}
+		key, ok := parseChordKey(part)
+		if !ok {
+			return Chord{}, fmt.Errorf("invalid key chord %q: unknown key %q", s, part)
+		}
+		chord.Key = key
+	}
+	return chord, nil
+}
+
+// Like ParseChord, but panics if the chord is invalid.  For chords that are
+// known at compile time.
+func MustParseChord(s string) Chord {
+	chord, err := ParseChord(s)
+	if err != nil {
+		panic(err)
+	}
+	return chord
+}
+
+// Formats the chord the way ParseChord reads it, with the modifiers in the
+// order Ctrl, Alt, Shift
+func (c Chord) String() string {
+	parts := make([]string, 0, 4)
+	for _, n := range modifierNames {
+		if c.Modifiers&n.value != 0 {
+			parts = append(parts, n.name)
+		}
+	}
+	return strings.Join(append(parts, formatChordKey(c.Key)), "+")
+}
+
+// Returns the chord for a key event
+func (p *KeyParams) Chord() Chord {
+	return Chord{p.KeyCode, uint32(p.Modifiers())}
+}
+
+// Returned when binding a chord that is already bound in the same map
+type ShortcutConflictError struct {
+	Chord Chord
+}
+
+func (e *ShortcutConflictError) Error() string {
+	return fmt.Sprint("shortcut ", e.Chord, " is already bound")
+}
+
+/*
+ShortcutMap
+
+Key bindings for a scope.  Attach its EventHandler to a window with
+AttachWindowEventHandler for window wide shortcuts, or to an element with
+AttachHandler for shortcuts that only apply while focus is inside it.  A
+binding runs on KEY_DOWN, in the bubbling phase, so the focused element gets
+to handle the key first.  The map must only be used on the UI thread.
+*/
+type ShortcutMap struct {
+	bindings map[Chord]func(he HELEMENT, params *KeyParams) bool
+	handler  *EventHandler
+}
+
+func NewShortcutMap() *ShortcutMap {
+	return &ShortcutMap{bindings: make(map[Chord]func(HELEMENT, *KeyParams) bool, 16)}
+}
+
+// Binds the chord to the action.  The action returns true if it handled the
+// key.  Fails if the chord doesn't parse or is already bound in this map.
+func (m *ShortcutMap) TryBind(chord string, action func(he HELEMENT, params *KeyParams) bool) error {
+	c, err := ParseChord(chord)
+	if err != nil {
+		return err
+	}
+	if _, exists := m.bindings[c]; exists {
+		return &ShortcutConflictError{c}
+	}
+	m.bindings[c] = action
+	return nil
+}
+
+func (m *ShortcutMap) Bind(chord string, action func(he HELEMENT, params *KeyParams) bool) {
+	mustSucceed(m.TryBind(chord, action))
+}
+
+// Removes the binding for the chord, returning false if there wasn't one
+func (m *ShortcutMap) Unbind(chord string) bool {
+	c, err := ParseChord(chord)
+	if err != nil {
+		return false
+	}
+	_, exists := m.bindings[c]
+	delete(m.bindings, c)
+	return exists
+}
+
+// Returns the action bound to the chord, or nil
+func (m *ShortcutMap) Lookup(c Chord) func(he HELEMENT, params *KeyParams) bool {
+	return m.bindings[c]
+}
+
+// Returns the bound chords, formatted and sorted
+func (m *ShortcutMap) Chords() []string {
+	chords := make([]string, 0, len(m.bindings))
+	for c := range m.bindings {
+		chords = append(chords, c.String())
+	}
+	sort.Strings(chords)
+	return chords
+}
+
+// Returns the chords bound in both maps, formatted and sorted.  Use it to find
+// the window shortcuts an element scope would shadow.
+func (m *ShortcutMap) Conflicts(other *ShortcutMap) []string {
+	conflicts := make([]string, 0, 4)
+	for c := range m.bindings {
+		if _, exists := other.bindings[c]; exists {
+			conflicts = append(conflicts, c.String())
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// Returns the EventHandler that runs the bindings.  The same pointer is
+// returned every time, so it can be detached again later.
+func (m *ShortcutMap) EventHandler() *EventHandler {
+	if m.handler == nil {
+		m.handler = &EventHandler{
+			OnKey: func(he HELEMENT, params *KeyParams) bool {
+				if params.Event() != KEY_DOWN || params.Phase() != Phase(BUBBLING) {
+					return false
+				}
+				if action := m.bindings[params.Chord()]; action != nil {
+					return action(he, params)
+				}
+				return false
+			},
+		}
+	}
+	return m.handler
+}
@@ -0,0 +1,142 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestParseChord(t *testing.T) {
+	tests := []struct {
+		chord  string
+		expect Chord
+		format string
+	}{
+		{"Ctrl+Shift+S", Chord{'S', CONTROL_KEY_PRESSED | SHIFT_KEY_PRESSED}, "Ctrl+Shift+S"},
+		{"shift+control+s", Chord{'S', CONTROL_KEY_PRESSED | SHIFT_KEY_PRESSED}, "Ctrl+Shift+S"},
+		{"F5", Chord{0x74, 0}, "F5"},
+		{"Alt+F24", Chord{0x87, ALT_KEY_PRESSED}, "Alt+F24"},
+		{"Alt+Return", Chord{0x0D, ALT_KEY_PRESSED}, "Alt+Enter"},
+		{"escape", Chord{0x1B, 0}, "Esc"},
+		{"Ctrl+Numpad7", Chord{0x67, CONTROL_KEY_PRESSED}, "Ctrl+Numpad7"},
+		{"Ctrl++", Chord{0xBB, CONTROL_KEY_PRESSED}, "Ctrl+="},
+		{"Ctrl + -", Chord{0xBD, CONTROL_KEY_PRESSED}, "Ctrl+-"},
+		{"Ctrl+Alt+Shift+1", Chord{'1', CONTROL_KEY_PRESSED | ALT_KEY_PRESSED | SHIFT_KEY_PRESSED}, "Ctrl+Alt+Shift+1"},
+	}
+	for _, test := range tests {
+		c, err := ParseChord(test.chord)
+		if err != nil {
+			t.Fatal("Failed to parse ", test.chord, ": ", err)
+		}
+		if c != test.expect {
+			t.Fatalf("Parsed %q as %+v, expected %+v", test.chord, c, test.expect)
+		}
+		if s := c.String(); s != test.format {
+			t.Fatalf("Formatted %q as %q, expected %q", test.chord, s, test.format)
+		}
+		if again := MustParseChord(c.String()); again != c {
+			t.Fatal("Formatted chord does not parse back: ", c)
+		}
+	}
+
+	for _, bad := range []string{"", "Ctrl+", "Ctrl", "Ctrl+Shift", "Hyper+S", "Ctrl+Ctrl+S", "F0", "F25", "F05", "S+Ctrl", "Ctrl+Nope"} {
+		if _, err := ParseChord(bad); err == nil {
+			t.Fatalf("Expected %q to fail to parse", bad)
+		}
+	}
+	if s := (Chord{0xFF, 0}).String(); s != "0xFF" {
+		t.Fatal("Unexpected format for an unnamed key: ", s)
+	}
+}
+
+func TestKeyParamsChord(t *testing.T) {
+	params := &KeyParams{Cmd: KEY_DOWN, KeyCode: 'S', AltState: CONTROL_KEY_PRESSED | SHIFT_KEY_PRESSED | 0x100}
+	if c := params.Chord(); c != MustParseChord("Ctrl+Shift+S") {
+		t.Fatal("Unexpected chord: ", c)
+	}
+}
+
+func TestShortcutMapBindings(t *testing.T) {
+	m := NewShortcutMap()
+	noop := func(he HELEMENT, params *KeyParams) bool { return true }
+	m.Bind("Ctrl+S", noop)
+	m.Bind("F5", noop)
+	err := m.TryBind("control+s", noop)
+	if conflict, ok := err.(*ShortcutConflictError); !ok || conflict.Chord != MustParseChord("Ctrl+S") {
+		t.Fatal("Expected a conflict, got: ", err)
+	}
+	if err := m.TryBind("Ctrl+", noop); err == nil {
+		t.Fatal("Expected an invalid chord to fail")
+	}
+	if chords := m.Chords(); len(chords) != 2 || chords[0] != "Ctrl+S" || chords[1] != "F5" {
+		t.Fatal("Unexpected chords: ", chords)
+	}
+	if m.Lookup(MustParseChord("F5")) == nil || m.Lookup(MustParseChord("F6")) != nil {
+		t.Fatal("Unexpected lookup results")
+	}
+
+	other := NewShortcutMap()
+	other.Bind("F5", noop)
+	other.Bind("Esc", noop)
+	if conflicts := m.Conflicts(other); len(conflicts) != 1 || conflicts[0] != "F5" {
+		t.Fatal("Unexpected conflicts: ", conflicts)
+	}
+
+	if !m.Unbind("f5") || m.Unbind("F5") || m.Unbind("Ctrl+") {
+		t.Fatal("Unexpected unbind results")
+	}
+	if len(m.Conflicts(other)) != 0 {
+		t.Fatal("Expected no conflicts after unbinding")
+	}
+}
+
+func TestShortcutMapDispatch(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		root := RootElement(hwnd)
+		b := root.Select("#b")[0]
+		saves, inner := 0, 0
+
+		window := NewShortcutMap()
+		window.Bind("Ctrl+S", func(he HELEMENT, params *KeyParams) bool {
+			saves++
+			return true
+		})
+		window.Bind("Esc", func(he HELEMENT, params *KeyParams) bool {
+			return false
+		})
+		root.AttachHandler(window.EventHandler())
+		if window.EventHandler() != window.EventHandler() {
+			t.Fatal("Expected the same handler every time")
+		}
+
+		scope := NewShortcutMap()
+		scope.Bind("Esc", func(he HELEMENT, params *KeyParams) bool {
+			inner++
+			return true
+		})
+		b.AttachHandler(scope.EventHandler())
+
+		fire := func(cmd uint32, chord string) bool {
+			c := MustParseChord(chord)
+			params := &KeyParams{Cmd: cmd, Target: b.Handle(), KeyCode: c.Key, AltState: c.Modifiers}
+			return mem.FireEvent(b.Handle(), HANDLE_KEY, unsafe.Pointer(params))
+		}
+
+		if !fire(KEY_DOWN, "Ctrl+S") || saves != 1 {
+			t.Fatal("Expected the window binding to handle the key once, ran: ", saves)
+		}
+		if fire(KEY_UP, "Ctrl+S") || saves != 1 {
+			t.Fatal("Bindings should not run on KEY_UP")
+		}
+		if fire(KEY_DOWN, "Ctrl+Shift+S") || saves != 1 {
+			t.Fatal("Extra modifiers should not match")
+		}
+		if !fire(KEY_DOWN, "Esc") || inner != 1 {
+			t.Fatal("Expected the element scope to handle the key")
+		}
+
+		b.DetachHandler(scope.EventHandler())
+		if fire(KEY_DOWN, "Esc") || inner != 1 {
+			t.Fatal("Detached scope should not run")
+		}
+	})
+}
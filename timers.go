@@ -0,0 +1,260 @@
+package gohl
+
+import (
+	"time"
+)
+
+// The source of the current time for element timers
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (c systemClock) Now() time.Time {
+	return time.Now()
+}
+
+var timerClock Clock = systemClock{}
+
+// Replaces the clock that element timers are scheduled with, so that tests can
+// control time.  Passing nil restores the system clock.
+func SetTimerClock(clock Clock) {
+	if clock == nil {
+		clock = systemClock{}
+	}
+	timerClock = clock
+}
+
+/*
+Timer
+
+A single logical timer created with Element.AfterFunc or Element.Every.  Any
+number of them share the one engine timer of their element, which is set to
+go off when the earliest of them is due.  Timers run on the UI thread and
+must only be stopped from it.  A pending timer keeps the element's timer
+handler attached and the element referenced on its own, so it fires whether or
+not the Element it was started from is still around.
+*/
+type Timer struct {
+	deadline time.Time
+	period   time.Duration // Zero for a timer that only fires once
+	fn       func()
+
+	// The element's timers while the timer is pending, nil once it has fired
+	// or been stopped
+	timers *elementTimers
+}
+
+// Stops the timer, returning false if it had already fired or been stopped
+func (t *Timer) Stop() bool {
+	timers := t.timers
+	if timers == nil {
+		return false
+	}
+	t.timers = nil
+	timers.queue.remove(t)
+	timers.reschedule()
+	if len(timers.queue.timers) == 0 {
+		timers.close()
+	}
+	return true
+}
+
+// The pending timers, ordered by deadline.  Timers with the same deadline stay
+// in the order they were added.
+type timerQueue struct {
+	timers []*Timer
+}
+
+func (q *timerQueue) add(t *Timer) {
+	i := len(q.timers)
+	for i > 0 && q.timers[i-1].deadline.After(t.deadline) {
+		i--
+	}
+	q.timers = append(q.timers, nil)
+	copy(q.timers[i+1:], q.timers[i:])
+	q.timers[i] = t
+}
+
+func (q *timerQueue) remove(t *Timer) bool {
+	for i, queued := range q.timers {
+		if queued == t {
+			q.timers = append(q.timers[:i], q.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Removes the timers that are due at now and returns them in order.  Repeating
+// timers are queued again for their next period; one that has fallen more than
+// a period behind skips the ticks it missed, as a time.Ticker does.
+func (q *timerQueue) popDue(now time.Time) []*Timer {
+	n := 0
+	for n < len(q.timers) && !q.timers[n].deadline.After(now) {
+		n++
+	}
+	due := append([]*Timer(nil), q.timers[:n]...)
+	q.timers = append(q.timers[:0], q.timers[n:]...)
+	for _, t := range due {
+		if t.period > 0 {
+			t.deadline = t.deadline.Add(t.period)
+			if !t.deadline.After(now) {
+				t.deadline = now.Add(t.period)
+			}
+			q.add(t)
+		}
+	}
+	return due
+}
+
+// Returns how long after now the next timer is due, or false if none are pending
+func (q *timerQueue) next(now time.Time) (time.Duration, bool) {
+	if len(q.timers) == 0 {
+		return 0, false
+	}
+	d := q.timers[0].deadline.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// The engine timer can't be set for less than a millisecond, and setting it
+// to zero stops it
+func timerMilliseconds(d time.Duration) uint {
+	ms := (d + time.Millisecond - 1) / time.Millisecond
+	if ms < 1 {
+		ms = 1
+	}
+	return uint(ms)
+}
+
+// The logical timers of one element and the handler that runs them.  The set
+// holds its own reference to the element and attaches its handler directly,
+// rather than through an Element, so that releasing the Elements for the
+// handle doesn't detach it.  Both are let go by close.
+type elementTimers struct {
+	he      HELEMENT
+	queue   timerQueue
+	handler *EventHandler
+	closed  bool
+}
+
+// Keyed by element handle, which the set's reference keeps valid.  Only
+// touched on the UI thread.
+var elementTimerSets = make(map[HELEMENT]*elementTimers, 8)
+
+// Sets the engine timer for the earliest pending timer, or stops it if there
+// are none
+func (s *elementTimers) reschedule() HLDOM_RESULT {
+	d, pending := s.queue.next(timerClock.Now())
+	if !pending {
+		return dom.SetTimer(s.he, 0)
+	}
+	return dom.SetTimer(s.he, timerMilliseconds(d))
+}
+
+// Returning false lets the engine stop its timer once nothing is pending
+func (s *elementTimers) onTimer(he HELEMENT, params *TimerParams) bool {
+	for _, t := range s.queue.popDue(timerClock.Now()) {
+		if t.timers == nil {
+			// Stopped by an earlier timer in the same tick
+			continue
+		}
+		if t.period == 0 {
+			t.timers = nil
+		}
+		t.fn()
+	}
+	if s.closed {
+		return false
+	}
+	if len(s.queue.timers) == 0 {
+		s.reschedule()
+		s.close()
+		return false
+	}
+	return s.reschedule() == HLDOM_OK
+}
+
+// Cancels every pending timer when the element goes away
+func (s *elementTimers) onDetached(he HELEMENT) {
+	s.close()
+}
+
+// Cancels any pending timers, detaches the handler and drops the set's
+// reference to the element.  A set is closed once nothing is pending, and a
+// later timer on the element starts a new one.
+func (s *elementTimers) close() {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	for _, t := range s.queue.timers {
+		t.timers = nil
+	}
+	s.queue.timers = nil
+	delete(elementTimerSets, s.he)
+
+	// Fails harmlessly when the element is already detaching the handler
+	dom.DetachEventHandler(s.he, s.handler)
+	unuse(s.he)
+}
+
+func (e *Element) startTimer(t *Timer) (*Timer, error) {
+	if err := e.checkReleased(); err != nil {
+		return nil, err
+	}
+	s := elementTimerSets[e.handle]
+	if s == nil {
+		s = &elementTimers{he: e.handle}
+		s.handler = &EventHandler{OnTimer: s.onTimer, OnDetached: s.onDetached}
+		use(s.he)
+		if ret := dom.AttachEventHandler(s.he, s.handler, s.handler.Subscription()); ret != HLDOM_OK {
+			unuse(s.he)
+			return nil, domError(ret, "Failed to attach timer handler to element")
+		}
+		elementTimerSets[e.handle] = s
+	}
+	t.timers = s
+	s.queue.add(t)
+	if ret := s.reschedule(); ret != HLDOM_OK {
+		s.queue.remove(t)
+		t.timers = nil
+		if len(s.queue.timers) == 0 {
+			s.close()
+		}
+		return nil, domError(ret, "Failed to set timer")
+	}
+	return t, nil
+}
+
+// Calls fn on the UI thread once d has elapsed, unless the timer is stopped
+// first or the element is detached.  The element's own engine timer is used to
+// run it, so this must not be combined with SetTimer on the same element.
+func (e *Element) TryAfterFunc(d time.Duration, fn func()) (*Timer, error) {
+	return e.startTimer(&Timer{deadline: timerClock.Now().Add(d), fn: fn})
+}
+
+func (e *Element) AfterFunc(d time.Duration, fn func()) *Timer {
+	t, err := e.TryAfterFunc(d, fn)
+	mustSucceed(err)
+	return t
+}
+
+// Calls fn on the UI thread every d until the timer is stopped or the element
+// is detached.  The period must be positive.
+func (e *Element) TryEvery(d time.Duration, fn func()) (*Timer, error) {
+	if d <= 0 {
+		return nil, ErrInvalidParameter
+	}
+	return e.startTimer(&Timer{deadline: timerClock.Now().Add(d), period: d, fn: fn})
+}
+
+func (e *Element) Every(d time.Duration, fn func()) *Timer {
+	t, err := e.TryEvery(d, fn)
+	mustSucceed(err)
+	return t
+}
@@ -0,0 +1,192 @@
+package gohl
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func withFakeClock(test func(clock *fakeClock)) {
+	clock := &fakeClock{time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)}
+	SetTimerClock(clock)
+	defer SetTimerClock(nil)
+	test(clock)
+}
+
+func TestTimerQueue(t *testing.T) {
+	start := time.Date(2012, 1, 1, 0, 0, 0, 0, time.UTC)
+	q := &timerQueue{}
+	a := &Timer{deadline: start.Add(30 * time.Millisecond)}
+	b := &Timer{deadline: start.Add(10 * time.Millisecond), period: 10 * time.Millisecond}
+	c := &Timer{deadline: start.Add(30 * time.Millisecond)}
+	q.add(a)
+	q.add(b)
+	q.add(c)
+	if d, ok := q.next(start); !ok || d != 10*time.Millisecond {
+		t.Fatal("Unexpected next deadline: ", d)
+	}
+	if due := q.popDue(start.Add(5 * time.Millisecond)); len(due) != 0 {
+		t.Fatal("Nothing should be due yet")
+	}
+	if due := q.popDue(start.Add(10 * time.Millisecond)); len(due) != 1 || due[0] != b {
+		t.Fatal("Expected the repeating timer to be due: ", due)
+	}
+	if len(q.timers) != 3 || !b.deadline.Equal(start.Add(20*time.Millisecond)) {
+		t.Fatal("Expected the repeating timer to be queued again")
+	}
+
+	// b falls behind and skips the ticks it missed; a and c keep their order
+	due := q.popDue(start.Add(55 * time.Millisecond))
+	if len(due) != 3 || due[0] != b || due[1] != a || due[2] != c {
+		t.Fatal("Unexpected due timers: ", due)
+	}
+	if len(q.timers) != 1 || !b.deadline.Equal(start.Add(65*time.Millisecond)) {
+		t.Fatal("Unexpected deadline after falling behind: ", b.deadline.Sub(start))
+	}
+	if !q.remove(b) || q.remove(b) {
+		t.Fatal("Unexpected remove results")
+	}
+	if _, ok := q.next(start); ok {
+		t.Fatal("Expected an empty queue")
+	}
+
+	if ms := timerMilliseconds(0); ms != 1 {
+		t.Error("Expected a minimum of 1ms, got: ", ms)
+	}
+	if ms := timerMilliseconds(1500 * time.Microsecond); ms != 2 {
+		t.Error("Expected to round up, got: ", ms)
+	}
+}
+
+func TestElementTimers(t *testing.T) {
+	withFakeClock(func(clock *fakeClock) {
+		testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+			d := RootElement(hwnd).Child(0)
+			var fired []string
+			d.AfterFunc(50*time.Millisecond, func() { fired = append(fired, "once") })
+			tick := d.Every(20*time.Millisecond, func() { fired = append(fired, "tick") })
+			stopped := d.AfterFunc(30*time.Millisecond, func() { fired = append(fired, "stopped") })
+			if ms := mem.TimerInterval(d.Handle()); ms != 20 {
+				t.Fatal("Expected the engine timer to be set for the earliest timer, got: ", ms)
+			}
+			if !stopped.Stop() || stopped.Stop() {
+				t.Fatal("Unexpected results stopping a timer")
+			}
+
+			clock.Advance(20 * time.Millisecond)
+			mem.FireTimer(d.Handle())
+			clock.Advance(20 * time.Millisecond)
+			mem.FireTimer(d.Handle())
+			if ms := mem.TimerInterval(d.Handle()); ms != 10 {
+				t.Fatal("Expected the engine timer to be set for the one shot timer, got: ", ms)
+			}
+			clock.Advance(10 * time.Millisecond)
+			mem.FireTimer(d.Handle())
+			if len(fired) != 3 || fired[0] != "tick" || fired[1] != "tick" || fired[2] != "once" {
+				t.Fatal("Unexpected timers fired: ", fired)
+			}
+
+			if !tick.Stop() {
+				t.Fatal("Expected the repeating timer to still be running")
+			}
+			if ms := mem.TimerInterval(d.Handle()); ms != 0 {
+				t.Fatal("Expected the engine timer to stop, got: ", ms)
+			}
+			if _, err := d.TryEvery(0, func() {}); err != ErrInvalidParameter {
+				t.Fatal("Expected a zero period to fail, got: ", err)
+			}
+		})
+	})
+}
+
+func TestElementTimersStopOnDetach(t *testing.T) {
+	withFakeClock(func(clock *fakeClock) {
+		testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+			d := RootElement(hwnd).Child(0)
+			fired := 0
+			timer := d.Every(10*time.Millisecond, func() { fired++ })
+			d.AfterFunc(10*time.Millisecond, func() {
+				if timer.Stop() {
+					fired += 100
+				}
+			})
+			handle := d.Handle()
+			clock.Advance(10 * time.Millisecond)
+			if mem.FireTimer(handle) || fired != 101 {
+				t.Fatal("Expected both timers to fire and the engine timer to stop: ", fired)
+			}
+
+			timer = d.Every(10*time.Millisecond, func() { fired++ })
+			d.Delete()
+			if timer.Stop() {
+				t.Fatal("Timer should have been cancelled when the element was detached")
+			}
+			if _, exists := elementTimerSets[handle]; exists {
+				t.Fatal("Timers should be forgotten when the element is detached")
+			}
+		})
+	})
+}
+
+func TestElementTimersOutliveElement(t *testing.T) {
+	withFakeClock(func(clock *fakeClock) {
+		testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+			child := RootElement(hwnd).Child(0)
+			handle := child.Handle()
+			DrainReleaseQueue()
+			before := refCount(handle)
+			fired := 0
+			func() {
+				d := NewElementFromHandle(handle)
+				d.AfterFunc(10*time.Millisecond, func() { fired++ })
+			}()
+			if refCount(handle) != before+2 {
+				t.Fatal("Expected the timers to hold their own reference")
+			}
+
+			// Collect the Element the timer was started from, and release it
+			for i := 0; i < 10 && PendingReleases() == 0; i++ {
+				runtime.GC()
+			}
+			if PendingReleases() == 0 {
+				t.Skip("Element was not collected")
+			}
+			DrainReleaseQueue()
+			if refCount(handle) != before+1 {
+				t.Fatal("Expected only the Element's reference to be released")
+			}
+
+			clock.Advance(10 * time.Millisecond)
+			mem.FireTimer(handle)
+			if fired != 1 {
+				t.Fatal("Pending timer should fire after its Element is released")
+			}
+			if refCount(handle) != before || elementTimerSets[handle] != nil {
+				t.Fatal("Expected the timers to let go of the element once nothing is pending")
+			}
+
+			// Releasing an Element by hand leaves the timers alone as well
+			d := NewElementFromHandle(handle)
+			d.AfterFunc(10*time.Millisecond, func() { fired++ })
+			d.Release()
+			clock.Advance(10 * time.Millisecond)
+			mem.FireTimer(handle)
+			if fired != 2 {
+				t.Fatal("Pending timer should fire after its Element is released")
+			}
+			runtime.KeepAlive(child)
+		})
+	})
+}
@@ -0,0 +1,290 @@
+package gohl
+
+/*
+TypedEventHandler
+
+A higher level alternative to EventHandler with a callback per event code
+rather than per event group, so that handlers don't need to switch on
+params.Cmd.  Only the bubbling phase is delivered, which means each callback
+is called at most once per event, and the SINKING, HANDLED and (for mouse
+events) DRAGGING flags are masked off before the code is looked at.  The
+params are passed through untouched, so those flags can still be inspected.
+
+Attach it with Element.AttachHandler(h.EventHandler()) or pass
+h.EventHandler() wherever an *EventHandler is expected.
+*/
+type TypedEventHandler struct {
+	OnAttached func(he HELEMENT)
+	OnDetached func(he HELEMENT)
+
+	// Mouse events
+	OnMouseEnter  func(he HELEMENT, params *MouseParams) bool
+	OnMouseLeave  func(he HELEMENT, params *MouseParams) bool
+	OnMouseMove   func(he HELEMENT, params *MouseParams) bool
+	OnMouseDown   func(he HELEMENT, params *MouseParams) bool
+	OnMouseUp     func(he HELEMENT, params *MouseParams) bool
+	OnClick       func(he HELEMENT, params *MouseParams) bool
+	OnDoubleClick func(he HELEMENT, params *MouseParams) bool
+	OnMouseWheel  func(he HELEMENT, params *MouseParams) bool
+	OnMouseTick   func(he HELEMENT, params *MouseParams) bool
+	OnMouseIdle   func(he HELEMENT, params *MouseParams) bool
+	OnDrop        func(he HELEMENT, params *MouseParams) bool
+	OnDragEnter   func(he HELEMENT, params *MouseParams) bool
+	OnDragLeave   func(he HELEMENT, params *MouseParams) bool
+	OnDragRequest func(he HELEMENT, params *MouseParams) bool
+
+	// Key events
+	OnKeyDown func(he HELEMENT, params *KeyParams) bool
+	OnKeyUp   func(he HELEMENT, params *KeyParams) bool
+	OnKeyChar func(he HELEMENT, params *KeyParams) bool
+
+	// Focus events
+	OnFocusGot  func(he HELEMENT, params *FocusParams) bool
+	OnFocusLost func(he HELEMENT, params *FocusParams) bool
+
+	// Behavior events
+	OnButtonClick         func(he HELEMENT, params *BehaviorEventParams) bool
+	OnButtonPress         func(he HELEMENT, params *BehaviorEventParams) bool
+	OnButtonStateChanged  func(he HELEMENT, params *BehaviorEventParams) bool
+	OnEditValueChanging   func(he HELEMENT, params *BehaviorEventParams) bool
+	OnEditValueChanged    func(he HELEMENT, params *BehaviorEventParams) bool
+	OnSelectionChanged    func(he HELEMENT, params *BehaviorEventParams) bool
+	OnSelectStateChanged  func(he HELEMENT, params *BehaviorEventParams) bool
+	OnPopupRequest        func(he HELEMENT, params *BehaviorEventParams) bool
+	OnPopupReady          func(he HELEMENT, params *BehaviorEventParams) bool
+	OnPopupDismissed      func(he HELEMENT, params *BehaviorEventParams) bool
+	OnMenuItemActive      func(he HELEMENT, params *BehaviorEventParams) bool
+	OnMenuItemClick       func(he HELEMENT, params *BehaviorEventParams) bool
+	OnContextMenuRequest  func(he HELEMENT, params *BehaviorEventParams) bool
+	OnHyperlinkClick      func(he HELEMENT, params *BehaviorEventParams) bool
+	OnTableHeaderClick    func(he HELEMENT, params *BehaviorEventParams) bool
+	OnTableRowClick       func(he HELEMENT, params *BehaviorEventParams) bool
+	OnTableRowDoubleClick func(he HELEMENT, params *BehaviorEventParams) bool
+	OnElementExpanded     func(he HELEMENT, params *BehaviorEventParams) bool
+	OnElementCollapsed    func(he HELEMENT, params *BehaviorEventParams) bool
+	OnFormSubmit          func(he HELEMENT, params *BehaviorEventParams) bool
+	OnFormReset           func(he HELEMENT, params *BehaviorEventParams) bool
+	OnDocumentComplete    func(he HELEMENT, params *BehaviorEventParams) bool
+
+	// Created on first use by EventHandler and reused after that, since
+	// handlers are attached and detached by pointer
+	handler *EventHandler
+}
+
+func (t *TypedEventHandler) mouseCallback(cmd uint32) func(HELEMENT, *MouseParams) bool {
+	switch cmd &^ (phaseFlags | DRAGGING) {
+	case MOUSE_ENTER:
+		return t.OnMouseEnter
+	case MOUSE_LEAVE:
+		return t.OnMouseLeave
+	case MOUSE_MOVE:
+		return t.OnMouseMove
+	case MOUSE_DOWN:
+		return t.OnMouseDown
+	case MOUSE_UP:
+		return t.OnMouseUp
+	case MOUSE_CLICK:
+		return t.OnClick
+	case MOUSE_DCLICK:
+		return t.OnDoubleClick
+	case MOUSE_WHEEL:
+		return t.OnMouseWheel
+	case MOUSE_TICK:
+		return t.OnMouseTick
+	case MOUSE_IDLE:
+		return t.OnMouseIdle
+	case DROP:
+		return t.OnDrop
+	case DRAG_ENTER:
+		return t.OnDragEnter
+	case DRAG_LEAVE:
+		return t.OnDragLeave
+	case DRAG_REQUEST:
+		return t.OnDragRequest
+	}
+	return nil
+}
+
+func (t *TypedEventHandler) keyCallback(cmd uint32) func(HELEMENT, *KeyParams) bool {
+	switch cmd &^ phaseFlags {
+	case KEY_DOWN:
+		return t.OnKeyDown
+	case KEY_UP:
+		return t.OnKeyUp
+	case KEY_CHAR:
+		return t.OnKeyChar
+	}
+	return nil
+}
+
+func (t *TypedEventHandler) focusCallback(cmd uint32) func(HELEMENT, *FocusParams) bool {
+	switch cmd &^ phaseFlags {
+	case FOCUS_GOT:
+		return t.OnFocusGot
+	case FOCUS_LOST:
+		return t.OnFocusLost
+	}
+	return nil
+}
+
+func (t *TypedEventHandler) behaviorCallback(cmd uint32) func(HELEMENT, *BehaviorEventParams) bool {
+	switch cmd &^ phaseFlags {
+	case BUTTON_CLICK:
+		return t.OnButtonClick
+	case BUTTON_PRESS:
+		return t.OnButtonPress
+	case BUTTON_STATE_CHANGED:
+		return t.OnButtonStateChanged
+	case EDIT_VALUE_CHANGING:
+		return t.OnEditValueChanging
+	case EDIT_VALUE_CHANGED:
+		return t.OnEditValueChanged
+	case SELECT_SELECTION_CHANGED:
+		return t.OnSelectionChanged
+	case SELECT_STATE_CHANGED:
+		return t.OnSelectStateChanged
+	case POPUP_REQUEST:
+		return t.OnPopupRequest
+	case POPUP_READY:
+		return t.OnPopupReady
+	case POPUP_DISMISSED:
+		return t.OnPopupDismissed
+	case MENU_ITEM_ACTIVE:
+		return t.OnMenuItemActive
+	case MENU_ITEM_CLICK:
+		return t.OnMenuItemClick
+	case CONTEXT_MENU_REQUEST:
+		return t.OnContextMenuRequest
+	case HYPERLINK_CLICK:
+		return t.OnHyperlinkClick
+	case TABLE_HEADER_CLICK:
+		return t.OnTableHeaderClick
+	case TABLE_ROW_CLICK:
+		return t.OnTableRowClick
+	case TABLE_ROW_DBL_CLICK:
+		return t.OnTableRowDoubleClick
+	case ELEMENT_EXPANDED:
+		return t.OnElementExpanded
+	case ELEMENT_COLLAPSED:
+		return t.OnElementCollapsed
+	case FORM_SUBMIT:
+		return t.OnFormSubmit
+	case FORM_RESET:
+		return t.OnFormReset
+	case DOCUMENT_COMPLETE:
+		return t.OnDocumentComplete
+	}
+	return nil
+}
+
+// Reports whether any callback in the group is set.  Every code of the group is
+// tried, with the callback lookups above doing the mapping.
+func (t *TypedEventHandler) hasMouse() bool {
+	for _, cmd := range []uint32{MOUSE_ENTER, MOUSE_LEAVE, MOUSE_MOVE, MOUSE_DOWN, MOUSE_UP, MOUSE_CLICK, MOUSE_DCLICK,
+		MOUSE_WHEEL, MOUSE_TICK, MOUSE_IDLE, DROP, DRAG_ENTER, DRAG_LEAVE, DRAG_REQUEST} {
+		if t.mouseCallback(cmd) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *TypedEventHandler) hasKey() bool {
+	return t.OnKeyDown != nil || t.OnKeyUp != nil || t.OnKeyChar != nil
+}
+
+func (t *TypedEventHandler) hasFocus() bool {
+	return t.OnFocusGot != nil || t.OnFocusLost != nil
+}
+
+func (t *TypedEventHandler) hasBehavior() bool {
+	for _, cmd := range []uint32{BUTTON_CLICK, BUTTON_PRESS, BUTTON_STATE_CHANGED, EDIT_VALUE_CHANGING, EDIT_VALUE_CHANGED,
+		SELECT_SELECTION_CHANGED, SELECT_STATE_CHANGED, POPUP_REQUEST, POPUP_READY, POPUP_DISMISSED, MENU_ITEM_ACTIVE,
+		MENU_ITEM_CLICK, CONTEXT_MENU_REQUEST, HYPERLINK_CLICK, TABLE_HEADER_CLICK, TABLE_ROW_CLICK, TABLE_ROW_DBL_CLICK,
+		ELEMENT_EXPANDED, ELEMENT_COLLAPSED, FORM_SUBMIT, FORM_RESET, DOCUMENT_COMPLETE} {
+		if t.behaviorCallback(cmd) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the event groups the handler needs, based on which callbacks are set
+func (t *TypedEventHandler) Subscription() uint32 {
+	return t.EventHandler().Subscription()
+}
+
+// Returns the EventHandler that dispatches to the typed callbacks.  The same
+// pointer is returned every time, so it can be detached again later.  Its event
+// groups are brought up to date with the callbacks that are set on each call,
+// so call this again after setting callbacks on a handler already in use.
+func (t *TypedEventHandler) EventHandler() *EventHandler {
+	if t.handler == nil {
+		t.handler = &EventHandler{
+			OnAttached: func(he HELEMENT) {
+				if t.OnAttached != nil {
+					t.OnAttached(he)
+				}
+			},
+			OnDetached: func(he HELEMENT) {
+				if t.OnDetached != nil {
+					t.OnDetached(he)
+				}
+			},
+		}
+	}
+	h := t.handler
+
+	h.OnMouse = nil
+	if t.hasMouse() {
+		h.OnMouse = func(he HELEMENT, params *MouseParams) bool {
+			if params.Cmd&SINKING != 0 {
+				return false
+			}
+			if f := t.mouseCallback(params.Cmd); f != nil {
+				return f(he, params)
+			}
+			return false
+		}
+	}
+
+	h.OnKey = nil
+	if t.hasKey() {
+		h.OnKey = func(he HELEMENT, params *KeyParams) bool {
+			if params.Cmd&SINKING != 0 {
+				return false
+			}
+			if f := t.keyCallback(params.Cmd); f != nil {
+				return f(he, params)
+			}
+			return false
+		}
+	}
+
+	h.OnFocus = nil
+	if t.hasFocus() {
+		h.OnFocus = func(he HELEMENT, params *FocusParams) bool {
+			if params.Cmd&SINKING != 0 {
+				return false
+			}
+			if f := t.focusCallback(params.Cmd); f != nil {
+				return f(he, params)
+			}
+			return false
+		}
+	}
+
+	h.OnBehaviorEvent = nil
+	if t.hasBehavior() {
+		h.OnBehaviorEvent = func(he HELEMENT, params *BehaviorEventParams) bool {
+			if params.Cmd&SINKING != 0 {
+				return false
+			}
+			if f := t.behaviorCallback(params.Cmd); f != nil {
+				return f(he, params)
+			}
+			return false
+		}
+	}
+	return h
+}
@@ -0,0 +1,95 @@
+package gohl
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestTypedEventHandlerSubscription(t *testing.T) {
+	h := &TypedEventHandler{}
+	if h.Subscription() != 0 {
+		t.Fatal("Handler without callbacks should not subscribe to anything")
+	}
+	h.OnClick = func(he HELEMENT, params *MouseParams) bool { return false }
+	h.OnHyperlinkClick = func(he HELEMENT, params *BehaviorEventParams) bool { return false }
+	if s := h.Subscription(); s != HANDLE_MOUSE|HANDLE_BEHAVIOR_EVENT {
+		t.Fatalf("Unexpected subscription: %#x", s)
+	}
+	h.OnClick = nil
+	h.OnKeyChar = func(he HELEMENT, params *KeyParams) bool { return false }
+	h.OnFocusLost = func(he HELEMENT, params *FocusParams) bool { return false }
+	if s := h.Subscription(); s != HANDLE_KEY|HANDLE_FOCUS|HANDLE_BEHAVIOR_EVENT {
+		t.Fatalf("Unexpected subscription after changing callbacks: %#x", s)
+	}
+	if h.EventHandler() != h.EventHandler() {
+		t.Fatal("EventHandler should return the same pointer every time")
+	}
+}
+
+func TestTypedEventHandlerMouse(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		clicks, moves := 0, 0
+		h := &TypedEventHandler{
+			OnClick: func(he HELEMENT, params *MouseParams) bool {
+				clicks++
+				return true
+			},
+			OnMouseMove: func(he HELEMENT, params *MouseParams) bool {
+				if params.Cmd&DRAGGING == 0 {
+					t.Fatal("Params should be passed through with their flags intact")
+				}
+				moves++
+				return false
+			},
+		}
+		RootElement(hwnd).AttachHandler(h.EventHandler())
+		defer RootElement(hwnd).DetachHandler(h.EventHandler())
+
+		if !mem.FireEvent(d.Handle(), HANDLE_MOUSE, unsafe.Pointer(&MouseParams{Cmd: MOUSE_CLICK, Target: d.Handle()})) {
+			t.Fatal("Click should have been handled")
+		}
+		if clicks != 1 {
+			t.Fatal("Expected a single call in the bubbling phase, got ", clicks)
+		}
+
+		mem.FireEvent(d.Handle(), HANDLE_MOUSE, unsafe.Pointer(&MouseParams{Cmd: MOUSE_MOVE | DRAGGING, Target: d.Handle()}))
+		if moves != 1 {
+			t.Fatal("Dragging move should have reached OnMouseMove")
+		}
+
+		// Codes without a callback fall through unhandled
+		if mem.FireEvent(d.Handle(), HANDLE_MOUSE, unsafe.Pointer(&MouseParams{Cmd: MOUSE_DOWN, Target: d.Handle()})) {
+			t.Fatal("Mouse down should not have been handled")
+		}
+	})
+}
+
+func TestTypedEventHandlerBehaviorEvent(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		d := RootElement(hwnd).Child(0)
+		var sources []HELEMENT
+		h := &TypedEventHandler{
+			OnButtonClick: func(he HELEMENT, params *BehaviorEventParams) bool {
+				sources = append(sources, params.Source)
+				return true
+			},
+			OnEditValueChanged: func(he HELEMENT, params *BehaviorEventParams) bool {
+				t.Fatal("Wrong callback called")
+				return false
+			},
+		}
+		d.AttachHandler(h.EventHandler())
+		defer d.DetachHandler(h.EventHandler())
+
+		if !d.SendEvent(BUTTON_CLICK, d, 0) {
+			t.Fatal("Button click should have been handled")
+		}
+		if len(sources) != 1 {
+			t.Fatal("Expected OnButtonClick to be called once, got ", len(sources))
+		}
+		if d.SendEvent(MENU_ITEM_CLICK, d, 0) {
+			t.Fatal("Event without a callback should not be handled")
+		}
+	})
+}
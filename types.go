@@ -0,0 +1,262 @@
+package gohl
+
+// A handle to a DOM element.  Handles point into memory owned by HTMLayout,
+// never into the Go heap.
+type HELEMENT uintptr
+
+// A window handle.  Handles are pointer sized, so this must not be narrowed
+// to 32 bits on 64-bit builds.
+type HWND uintptr
+
+type HLDOM_RESULT int32
+type VALUE_RESULT uint32
+
+type Point struct {
+	X int32
+	Y int32
+}
+
+type Size struct {
+	Cx int32
+	Cy int32
+}
+
+type Rect struct {
+	Left   int32
+	Top    int32
+	Right  int32
+	Bottom int32
+}
+
+type JsonValue struct {
+	T uint32
+	U uint32
+	D uint64
+}
+
+type InitializationParams struct {
+	Cmd uint32
+}
+
+type MouseParams struct {
+	Cmd         uint32 // MouseEvents
+	Target      HELEMENT
+	Pos         Point
+	DocumentPos Point
+	ButtonState uint32
+	AltState    uint32
+	CursorType  uint32
+	IsOnIcon    int32
+
+	Dragging     HELEMENT
+	DraggingMode uint32
+}
+
+type KeyParams struct {
+	Cmd      uint32 // KeyEvents
+	Target   HELEMENT
+	KeyCode  uint32
+	AltState uint32
+}
+
+type FocusParams struct {
+	Cmd          uint32 // FocusEvents
+	Target       HELEMENT
+	ByMouseClick int32 // boolean
+	Cancel       int32 // boolean
+}
+
+type DrawParams struct {
+	Cmd      uint32 // DrawEvents
+	Hdc      uintptr
+	Area     Rect
+	reserved uint32
+}
+
+type TimerParams struct {
+	TimerId uintptr
+}
+
+type BehaviorEventParams struct {
+	Cmd    uint32 // Behavior events
+	Target HELEMENT
+	Source HELEMENT
+	Reason uint32
+	Data   JsonValue
+}
+
+type MethodParams struct {
+	MethodId uint32
+}
+
+// The structures passed with each of the BehaviorMethodIdentifiers.  Each one
+// starts with the MethodId of MethodParams, so a *MethodParams can be
+// converted to the one its MethodId calls for; see MethodParams.Decode.
+
+// GET_TEXT_VALUE, SET_TEXT_VALUE
+type TextValueParams struct {
+	MethodId uint32
+	Text     *uint16
+	Length   uint32
+}
+
+// TEXT_EDIT_GET_SELECTION, TEXT_EDIT_SET_SELECTION
+type TextEditSelectionParams struct {
+	MethodId       uint32
+	SelectionStart uint32
+	SelectionEnd   uint32
+}
+
+// TEXT_EDIT_REPLACE_SELECTION
+type TextEditReplaceSelectionParams struct {
+	MethodId   uint32
+	Text       *uint16
+	TextLength uint32
+}
+
+// TEXT_EDIT_GET_CARET_POSITION
+type TextCaretPositionParams struct {
+	MethodId uint32
+	Left     int32
+	Top      int32
+	Width    int32
+	Height   int32
+}
+
+// TEXT_EDIT_GET_SELECTION_TEXT, TEXT_EDIT_GET_SELECTION_HTML
+type TextSelectionParams struct {
+	MethodId uint32
+	Outs     uintptr // func pointer: typedef BOOL CALLBACK OutputStreamProc(LPVOID prm, UINT data);
+	Data     uintptr
+}
+
+// TEXT_EDIT_CHAR_POS_AT_XY
+type TextEditCharPosAtXYParams struct {
+	MethodId uint32
+	X        int32
+	Y        int32
+	CharPos  int32
+	He       HELEMENT
+	HePos    int32
+}
+
+// SCROLL_BAR_GET_VALUE, SCROLL_BAR_SET_VALUE
+type ScrollBarValueParams struct {
+	MethodId  uint32
+	Value     int32
+	MinValue  int32
+	MaxValue  int32
+	PageValue int32
+	StepValue int32
+	Changed   int32 // boolean
+}
+
+// IS_EMPTY
+type IsEmptyParams struct {
+	MethodId uint32
+	IsEmpty  uint32 // boolean
+}
+
+// GET_VALUE, SET_VALUE
+type ValueParams struct {
+	MethodId uint32
+	Val      JsonValue
+}
+
+// XCALL
+type XcallParams struct {
+	MethodId   uint32
+	MethodName *byte // Null terminated
+	Argc       uint32
+	Argv       *JsonValue
+	Retval     JsonValue
+}
+
+type DataArrivedParams struct {
+	Initiator HELEMENT
+	Data      *byte
+	DataSize  uint32
+	DataType  uint32
+	Status    uint32
+	Uri       *uint16 // Wide character string
+}
+
+type ScrollParams struct {
+	Cmd      uint32
+	Target   HELEMENT
+	Pos      int32
+	Vertical int32 // bool
+}
+
+type ExchangeParams struct {
+	Cmd       uint32
+	Target    HELEMENT
+	Pos       Point
+	PosView   Point
+	DataTypes uint32
+	DragCmd   uint32
+	FetchData uintptr // func pointer: typedef BOOL CALLBACK FETCH_EXCHANGE_DATA(EXCHANGE_PARAMS* params, UINT data_type, LPCBYTE* ppDataStart, UINT* pDataLength );
+}
+
+type GestureParams struct {
+	Cmd       uint32
+	Target    HELEMENT
+	Pos       Point
+	PosView   Point
+	Flags     uint32
+	DeltaTime uint32
+	DeltaXY   Size
+	DeltaV    float64
+}
+
+// Notify structures
+
+type NMHDR struct {
+	HwndFrom HWND
+	IdFrom   uintptr
+	Code     uint32
+}
+
+type NmhlCreateControl struct {
+	Header         NMHDR
+	Element        HELEMENT
+	InHwndParent   HWND
+	OutHwndControl HWND
+	reserved1      int32
+	reserved2      int32
+}
+
+type NmhlDestroyControl struct {
+	Header           NMHDR
+	Element          HELEMENT
+	InOutHwndControl HWND
+	reserved1        int32
+}
+
+type NmhlLoadData struct {
+	Header      NMHDR
+	Uri         *uint16
+	OutData     uintptr
+	OutDataSize int32
+	DataType    uint32
+	Principal   HELEMENT
+	Initiator   HELEMENT
+}
+
+type NmhlDataLoaded struct {
+	Header   NMHDR
+	Uri      *uint16
+	Data     uintptr
+	DataSize int32
+	DataType uint32
+	Status   uint32
+}
+
+type NmhlAttachBehavior struct {
+	Header        NMHDR
+	Element       HELEMENT
+	BehaviorName  *byte // Null terminated
+	ElementProc   uintptr
+	ElementTag    uintptr
+	ElementEvents uint32
+}
@@ -0,0 +1,505 @@
+package gohl
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unsafe"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// A function registered with a FunctionRegistry
+type xcallFunction struct {
+	fn        reflect.Value
+	hasResult bool
+	hasError  bool
+}
+
+/*
+FunctionRegistry
+
+Go functions exposed by name to the scripts of a document, which call them
+through the XCALL behavior method.  A function takes and returns ordinary Go
+values: bools, numbers, strings, slices, arrays, maps with string keys,
+structs, pointers to these and interface{}, as well as the other types
+JsonValue.Value returns.  It may return nothing, a value,
+an error, or a value and an error.  A returned error fails the call.
+
+Arguments arrive from the document as the values JsonValue.Value returns and
+are converted to the parameter types.  Structs are read from maps and
+written to maps by field name, or by the name in a `json` field tag.  Element
+arguments are released once the call returns; a function that keeps one must
+wrap its handle again with NewElementFromHandle.  Results that refer to
+themselves can't be converted and fail the call.
+
+Attach the registry's EventHandler to the element or window that should
+service the calls.  It must only be used on the UI thread.
+*/
+type FunctionRegistry struct {
+	functions map[string]*xcallFunction
+	handler   *EventHandler
+
+	// Called with the error when a registered function fails, or its
+	// arguments or result can't be converted
+	OnError func(name string, err error)
+}
+
+func NewFunctionRegistry() *FunctionRegistry {
+	return &FunctionRegistry{functions: make(map[string]*xcallFunction, 16)}
+}
+
+// Exposes fn under the name.  Fails if fn isn't a function with a supported
+// signature or the name is already taken.
+func (r *FunctionRegistry) TryRegister(name string, fn interface{}) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("cannot register %q: %T is not a function", name, fn)
+	}
+	if _, exists := r.functions[name]; exists {
+		return fmt.Errorf("cannot register %q: the name is already taken", name)
+	}
+	t := v.Type()
+	f := &xcallFunction{fn: v}
+	switch t.NumOut() {
+	case 0:
+	case 1:
+		f.hasError = t.Out(0) == errorType
+		f.hasResult = !f.hasError
+	case 2:
+		if t.Out(1) != errorType {
+			return fmt.Errorf("cannot register %q: the second result must be an error", name)
+		}
+		f.hasResult, f.hasError = true, true
+	default:
+		return fmt.Errorf("cannot register %q: too many results", name)
+	}
+	r.functions[name] = f
+	return nil
+}
+
+func (r *FunctionRegistry) Register(name string, fn interface{}) {
+	mustSucceed(r.TryRegister(name, fn))
+}
+
+// Removes the function, returning false if there wasn't one
+func (r *FunctionRegistry) Unregister(name string) bool {
+	_, exists := r.functions[name]
+	delete(r.functions, name)
+	return exists
+}
+
+// Returns the registered names, sorted
+func (r *FunctionRegistry) Names() []string {
+	names := make([]string, 0, len(r.functions))
+	for name := range r.functions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Calls the function with arguments of the kinds JsonValue holds, and returns
+// its result in the same form
+func (r *FunctionRegistry) Call(name string, args []interface{}) (interface{}, error) {
+	f := r.functions[name]
+	if f == nil {
+		return nil, fmt.Errorf("no such function: %s", name)
+	}
+	t := f.fn.Type()
+	numIn := t.NumIn()
+	if t.IsVariadic() {
+		if len(args) < numIn-1 {
+			return nil, fmt.Errorf("%s takes at least %d arguments, got %d", name, numIn-1, len(args))
+		}
+	} else if len(args) != numIn {
+		return nil, fmt.Errorf("%s takes %d arguments, got %d", name, numIn, len(args))
+	}
+
+	in := make([]reflect.Value, len(args))
+	for i, arg := range args {
+		var paramType reflect.Type
+		if t.IsVariadic() && i >= numIn-1 {
+			paramType = t.In(numIn - 1).Elem()
+		} else {
+			paramType = t.In(i)
+		}
+		v, err := convertArg(arg, paramType)
+		if err != nil {
+			return nil, fmt.Errorf("%s: argument %d: %s", name, i+1, err)
+		}
+		in[i] = v
+	}
+
+	out := f.fn.Call(in)
+	if f.hasError {
+		if err, _ := out[len(out)-1].Interface().(error); err != nil {
+			return nil, err
+		}
+	}
+	if !f.hasResult {
+		return nil, nil
+	}
+	result, err := exportValue(out[0])
+	if err != nil {
+		return nil, fmt.Errorf("%s: result: %s", name, err)
+	}
+	return result, nil
+}
+
+// The name a struct field is read from and written to, or "" to skip it
+func fieldName(f reflect.StructField) string {
+	if f.PkgPath != "" {
+		// Unexported
+		return ""
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// Converts a value of one of the kinds JsonValue holds to type t
+func convertArg(arg interface{}, t reflect.Type) (reflect.Value, error) {
+	if t.Kind() == reflect.Interface && t.NumMethod() == 0 {
+		if arg == nil {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(arg), nil
+	}
+	if arg == nil {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+			return reflect.Zero(t), nil
+		}
+		return reflect.Value{}, fmt.Errorf("cannot convert null to %s", t)
+	}
+	if reflect.TypeOf(arg) == t {
+		return reflect.ValueOf(arg), nil
+	}
+	fail := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("cannot convert %T to %s", arg, t)
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Ptr:
+		elem, err := convertArg(arg, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Set(reflect.New(t.Elem()))
+		v.Elem().Set(elem)
+	case reflect.Bool:
+		b, ok := arg.(bool)
+		if !ok {
+			return fail()
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		switch n := arg.(type) {
+		case int:
+			i = int64(n)
+		case float64:
+			if n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 {
+				return fail()
+			}
+			i = int64(n)
+		default:
+			return fail()
+		}
+		if v.OverflowInt(i) {
+			return reflect.Value{}, fmt.Errorf("%d overflows %s", i, t)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		var u uint64
+		switch n := arg.(type) {
+		case int:
+			if n < 0 {
+				return reflect.Value{}, fmt.Errorf("%d overflows %s", n, t)
+			}
+			u = uint64(n)
+		case float64:
+			if n != math.Trunc(n) || n < 0 || n >= math.MaxUint64 {
+				return fail()
+			}
+			u = uint64(n)
+		default:
+			return fail()
+		}
+		if v.OverflowUint(u) {
+			return reflect.Value{}, fmt.Errorf("%d overflows %s", u, t)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		switch n := arg.(type) {
+		case int:
+			v.SetFloat(float64(n))
+		case float64:
+			v.SetFloat(n)
+		default:
+			return fail()
+		}
+	case reflect.String:
+		s, ok := arg.(string)
+		if !ok {
+			return fail()
+		}
+		v.SetString(s)
+	case reflect.Slice, reflect.Array:
+		items, ok := arg.([]interface{})
+		if !ok {
+			return fail()
+		}
+		if t.Kind() == reflect.Array {
+			if len(items) != t.Len() {
+				return reflect.Value{}, fmt.Errorf("cannot convert %d items to %s", len(items), t)
+			}
+		} else {
+			v.Set(reflect.MakeSlice(t, len(items), len(items)))
+		}
+		for i, item := range items {
+			elem, err := convertArg(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("item %d: %s", i, err)
+			}
+			v.Index(i).Set(elem)
+		}
+	case reflect.Map:
+		m, ok := arg.(map[string]interface{})
+		if !ok || t.Key().Kind() != reflect.String {
+			return fail()
+		}
+		v.Set(reflect.MakeMapWithSize(t, len(m)))
+		for key, item := range m {
+			elem, err := convertArg(item, t.Elem())
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("key %q: %s", key, err)
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+		}
+	case reflect.Struct:
+		m, ok := arg.(map[string]interface{})
+		if !ok {
+			return fail()
+		}
+		for i := 0; i < t.NumField(); i++ {
+			name := fieldName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			item, exists := m[name]
+			if !exists {
+				// Fall back to a case insensitive match, as encoding/json does
+				for key, value := range m {
+					if strings.EqualFold(key, name) {
+						item, exists = value, true
+						break
+					}
+				}
+			}
+			if !exists {
+				continue
+			}
+			field, err := convertArg(item, t.Field(i).Type)
+			if err != nil {
+				return reflect.Value{}, fmt.Errorf("field %s: %s", name, err)
+			}
+			v.Field(i).Set(field)
+		}
+	default:
+		return fail()
+	}
+	return v, nil
+}
+
+// A pointer, slice or map being exported, along with its type, since a struct
+// and its first field share an address
+type exportVisit struct {
+	ptr uintptr
+	t   reflect.Type
+}
+
+// Converts a Go value to the kinds JsonValue holds
+func exportValue(v reflect.Value) (interface{}, error) {
+	return exportValueVisiting(v, make(map[exportVisit]bool))
+}
+
+// Converts v, failing if it contains one of the values it is nested in.
+// Values are only in visiting while their contents are being converted, so
+// the same value may appear more than once as long as it doesn't contain
+// itself.
+func exportValueVisiting(v reflect.Value, visiting map[exportVisit]bool) (interface{}, error) {
+	if v.IsValid() && v.CanInterface() && isNativeValue(v.Interface()) {
+		return v.Interface(), nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice:
+		if !v.IsNil() && (v.Kind() != reflect.Slice || v.Len() > 0) {
+			visit := exportVisit{v.Pointer(), v.Type()}
+			if visiting[visit] {
+				return nil, fmt.Errorf("cannot convert %s, it refers to itself", v.Type())
+			}
+			visiting[visit] = true
+			defer delete(visiting, visit)
+		}
+	}
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil, nil
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return exportValueVisiting(v.Elem(), visiting)
+	case reflect.Bool:
+		return v.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := v.Int()
+		if i < math.MinInt32 || i > math.MaxInt32 {
+			// JsonValue integers are 32 bit
+			return float64(i), nil
+		}
+		return int(i), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := v.Uint()
+		if u > math.MaxInt32 {
+			return float64(u), nil
+		}
+		return int(u), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			item, err := exportValueVisiting(v.Index(i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = item
+		}
+		return items, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot convert %s, map keys must be strings", v.Type())
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			item, err := exportValueVisiting(v.MapIndex(key), visiting)
+			if err != nil {
+				return nil, err
+			}
+			m[key.String()] = item
+		}
+		return m, nil
+	case reflect.Struct:
+		t := v.Type()
+		m := make(map[string]interface{}, t.NumField())
+		for i := 0; i < t.NumField(); i++ {
+			name := fieldName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			item, err := exportValueVisiting(v.Field(i), visiting)
+			if err != nil {
+				return nil, err
+			}
+			m[name] = item
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s", v.Type())
+}
+
+// Releases the elements among the values JsonValue.Value returned
+func releaseArgElements(arg interface{}) {
+	switch arg := arg.(type) {
+	case *Element:
+		// Release would also detach the element's handlers, which may
+		// include the one servicing this call
+		if arg != nil {
+			arg.releaseReference()
+		}
+	case []interface{}:
+		for _, item := range arg {
+			releaseArgElements(item)
+		}
+	case map[string]interface{}:
+		for _, item := range arg {
+			releaseArgElements(item)
+		}
+	}
+}
+
+func (r *FunctionRegistry) fail(name string, err error) bool {
+	if r.OnError != nil {
+		r.OnError(name, err)
+	}
+	return false
+}
+
+// Services an XCALL, returning false if the function isn't registered here
+// or fails
+func (r *FunctionRegistry) xcall(params *XcallParams) bool {
+	name := cStringToString(params.MethodName)
+	if r.functions[name] == nil {
+		return false
+	}
+	args := make([]interface{}, params.Argc)
+
+	// Released after the result is stored, which takes its own reference to
+	// any element the function returns
+	defer func() {
+		for _, arg := range args {
+			releaseArgElements(arg)
+		}
+	}()
+	for i := range args {
+		argv := (*JsonValue)(unsafe.Pointer(uintptr(unsafe.Pointer(params.Argv)) + uintptr(i)*unsafe.Sizeof(JsonValue{})))
+		arg, err := argv.Value()
+		if err != nil {
+			return r.fail(name, fmt.Errorf("%s: argument %d: %s", name, i+1, err))
+		}
+		args[i] = arg
+	}
+	result, err := r.Call(name, args)
+	if err != nil {
+		return r.fail(name, err)
+	}
+	if err := params.Retval.SetValue(result); err != nil {
+		return r.fail(name, fmt.Errorf("%s: result: %s", name, err))
+	}
+	return true
+}
+
+// Returns the EventHandler that services XCALL for the registered functions.
+// The same pointer is returned every time, so it can be detached again later.
+func (r *FunctionRegistry) EventHandler() *EventHandler {
+	if r.handler == nil {
+		r.handler = &EventHandler{
+			OnMethodCall: func(he HELEMENT, params *MethodParams) bool {
+				if params.MethodId != XCALL {
+					return false
+				}
+				return r.xcall((*XcallParams)(unsafe.Pointer(params)))
+			},
+		}
+	}
+	return r.handler
+}
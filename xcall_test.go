@@ -0,0 +1,239 @@
+package gohl
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+type xcallPoint struct {
+	X, Y  int
+	Label string `json:"label"`
+	Skip  string `json:"-"`
+	note  string
+}
+
+func TestFunctionRegistryCall(t *testing.T) {
+	r := NewFunctionRegistry()
+	r.Register("add", func(a, b int) int { return a + b })
+	r.Register("join", func(sep string, parts ...string) string { return strings.Join(parts, sep) })
+	r.Register("scale", func(f float32, xs []float64) []float64 {
+		out := make([]float64, len(xs))
+		for i, x := range xs {
+			out[i] = x * float64(f)
+		}
+		return out
+	})
+	r.Register("move", func(p *xcallPoint, by map[string]int) xcallPoint {
+		return xcallPoint{X: p.X + by["x"], Y: p.Y + by["y"], Label: p.Label, Skip: "hidden"}
+	})
+	r.Register("not", func(b bool) (bool, error) { return !b, nil })
+	r.Register("echo", func(v interface{}) interface{} { return v })
+	r.Register("big", func() int64 { return 1 << 40 })
+	r.Register("nothing", func() {})
+
+	tests := []struct {
+		name   string
+		args   []interface{}
+		expect interface{}
+	}{
+		{"add", []interface{}{2, 3.0}, 5},
+		{"join", []interface{}{"-", "a", "b", "c"}, "a-b-c"},
+		{"join", []interface{}{"-"}, ""},
+		{"scale", []interface{}{2, []interface{}{1, 1.5}}, []interface{}{2.0, 3.0}},
+		{"scale", []interface{}{2, nil}, []interface{}{}},
+		{"move", []interface{}{map[string]interface{}{"x": 1, "Y": 2, "label": "p"}, map[string]interface{}{"x": 10, "y": 20}},
+			map[string]interface{}{"X": 11, "Y": 22, "label": "p"}},
+		{"not", []interface{}{true}, false},
+		{"echo", []interface{}{[]interface{}{"a", nil}}, []interface{}{"a", nil}},
+		{"big", nil, float64(1 << 40)},
+		{"nothing", nil, nil},
+	}
+	for _, test := range tests {
+		result, err := r.Call(test.name, test.args)
+		if err != nil {
+			t.Errorf("%s%v failed: %s", test.name, test.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, test.expect) {
+			t.Errorf("%s%v returned %#v, expected %#v", test.name, test.args, result, test.expect)
+		}
+	}
+}
+
+func TestFunctionRegistryCallErrors(t *testing.T) {
+	r := NewFunctionRegistry()
+	failure := errors.New("failure")
+	r.Register("add", func(a, b int8) int8 { return a + b })
+	r.Register("fail", func() (string, error) { return "", failure })
+	r.Register("count", func(n uint) uint { return n })
+	r.Register("point", func(p xcallPoint) int { return p.X })
+
+	if _, err := r.Call("fail", nil); err != failure {
+		t.Error("Expected the function's error, got ", err)
+	}
+	bad := []struct {
+		name string
+		args []interface{}
+	}{
+		{"missing", nil},
+		{"add", []interface{}{1}},
+		{"add", []interface{}{1, 2, 3}},
+		{"add", []interface{}{1, "2"}},
+		{"add", []interface{}{1, 2.5}},
+		{"add", []interface{}{1, 200}},
+		{"add", []interface{}{1, nil}},
+		{"count", []interface{}{-1}},
+		{"point", []interface{}{[]interface{}{1}}},
+		{"point", []interface{}{map[string]interface{}{"X": "1"}}},
+	}
+	for _, test := range bad {
+		if _, err := r.Call(test.name, test.args); err == nil {
+			t.Errorf("Expected %s%v to fail", test.name, test.args)
+		}
+	}
+}
+
+func TestFunctionRegistryRegister(t *testing.T) {
+	r := NewFunctionRegistry()
+	if err := r.TryRegister("f", 42); err == nil {
+		t.Error("Registered a non-function")
+	}
+	if err := r.TryRegister("f", func() (int, int) { return 0, 0 }); err == nil {
+		t.Error("Registered a function whose second result is not an error")
+	}
+	if err := r.TryRegister("f", func() (int, int, error) { return 0, 0, nil }); err == nil {
+		t.Error("Registered a function with too many results")
+	}
+	if err := r.TryRegister("f", func() error { return nil }); err != nil {
+		t.Error(err)
+	}
+	if err := r.TryRegister("f", func() {}); err == nil {
+		t.Error("Registered the same name twice")
+	}
+	r.Register("a", func() {})
+	if names := r.Names(); !reflect.DeepEqual(names, []string{"a", "f"}) {
+		t.Error("Unexpected names: ", names)
+	}
+	if !r.Unregister("f") || r.Unregister("f") {
+		t.Error("Unexpected result from Unregister")
+	}
+	if r.EventHandler() != r.EventHandler() {
+		t.Error("Expected the same handler each time")
+	}
+}
+
+func TestCStringToString(t *testing.T) {
+	s := []byte("héllo\x00junk")
+	if got := cStringToString(&s[0]); got != "héllo" {
+		t.Errorf("Got %q", got)
+	}
+	if cStringToString(nil) != "" {
+		t.Error("Expected an empty string for nil")
+	}
+}
+
+type xcallNode struct {
+	Name string
+	Next *xcallNode
+}
+
+func TestFunctionRegistryCycles(t *testing.T) {
+	r := NewFunctionRegistry()
+	loop := &xcallNode{Name: "a"}
+	loop.Next = &xcallNode{Name: "b", Next: loop}
+	selfMap := map[string]interface{}{}
+	selfMap["self"] = selfMap
+	selfSlice := []interface{}{nil}
+	selfSlice[0] = selfSlice
+	r.Register("loop", func() *xcallNode { return loop })
+	r.Register("selfMap", func() interface{} { return selfMap })
+	r.Register("selfSlice", func() interface{} { return selfSlice })
+	for _, name := range []string{"loop", "selfMap", "selfSlice"} {
+		if _, err := r.Call(name, nil); err == nil || !strings.Contains(err.Error(), "refers to itself") {
+			t.Errorf("Expected %s to fail with a cycle, got %v", name, err)
+		}
+	}
+
+	// The same value may appear more than once without containing itself
+	shared := &xcallNode{Name: "shared"}
+	r.Register("shared", func() []*xcallNode { return []*xcallNode{shared, shared} })
+	result, err := r.Call("shared", nil)
+	item := map[string]interface{}{"Name": "shared", "Next": nil}
+	if err != nil || !reflect.DeepEqual(result, []interface{}{item, item}) {
+		t.Errorf("Unexpected result for a shared value: %#v, %v", result, err)
+	}
+}
+
+func TestXcallReleasesElementArgs(t *testing.T) {
+	testWithMemoryHtml(pages["nested-divs"], func(mem *MemoryBackend, hwnd HWND) {
+		a := RootElement(hwnd).Select("#a")[0]
+		h := a.Handle()
+		before := refCount(h)
+
+		r := NewFunctionRegistry()
+		var received []*Element
+		r.Register("first", func(e *Element, more []*Element) *Element {
+			received = append(append(received, e), more...)
+			return e
+		})
+
+		var args [2]JsonValue
+		args[0].SetValue(a)
+		args[1].SetValue([]interface{}{a})
+		name := []byte("first\x00")
+		params := &XcallParams{MethodId: XCALL, MethodName: &name[0], Argc: 2, Argv: &args[0]}
+		if !r.xcall(params) {
+			t.Fatal("Expected the call to succeed")
+		}
+		if len(received) != 2 || received[0].checkReleased() == nil || received[1].checkReleased() == nil {
+			t.Fatal("Expected the element arguments to be released after the call")
+		}
+
+		// The result holds its own reference to the element returned
+		if refCount(h) != before+3 {
+			t.Fatal("Expected only the values to hold references, ref count: ", refCount(h))
+		}
+		if e, err := params.Retval.Value(); err != nil || e.(*Element).Handle() != h {
+			t.Fatal("Unexpected result: ", e, err)
+		} else {
+			e.(*Element).Release()
+		}
+		params.Retval.Clear()
+		args[0].Clear()
+		args[1].Clear()
+		if refCount(h) != before {
+			t.Fatal("Expected every reference to be released, ref count: ", refCount(h))
+		}
+	})
+}
+
+func TestXcallWithHostElementArg(t *testing.T) {
+	testWithMemoryHtml(pages["one-div"], func(mem *MemoryBackend, hwnd HWND) {
+		a := RootElement(hwnd).Child(0)
+		r := NewFunctionRegistry()
+		calls := 0
+		r.Register("host", func(e *Element) bool {
+			calls++
+			return e.Equals(a)
+		})
+		a.AttachHandler(r.EventHandler())
+		defer a.DetachHandler(r.EventHandler())
+
+		// The host element, as HTMLayout passes it
+		arg := JsonValue{T: T_DOM_OBJECT, D: uint64(a.Handle())}
+		name := []byte("host\x00")
+		for i := 0; i < 2; i++ {
+			params := &XcallParams{MethodId: XCALL, MethodName: &name[0], Argc: 1, Argv: &arg}
+			if ret := dom.CallBehaviorMethod(a.Handle(), (*MethodParams)(unsafe.Pointer(params))); ret != HLDOM_OK {
+				t.Fatalf("Expected XCALL %d to be handled, got %s", i+1, domResultAsString(ret))
+			}
+			params.Retval.Clear()
+		}
+		if calls != 2 {
+			t.Fatal("Expected both calls to reach the function, got ", calls)
+		}
+	})
+}